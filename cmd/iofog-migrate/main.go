@@ -0,0 +1,115 @@
+/*
+ *  *******************************************************************************
+ *  * Copyright (c) 2024 Datasance Teknoloji A.S.
+ *  *
+ *  * This program and the accompanying materials are made available under the
+ *  * terms of the Eclipse Public License v. 2.0 which is available at
+ *  * http://www.eclipse.org/legal/epl-2.0
+ *  *
+ *  * SPDX-License-Identifier: EPL-2.0
+ *  *******************************************************************************
+ *
+ */
+
+// Command iofog-migrate copies the configuration of a Controller to another Controller
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/datasance/iofog-go-sdk/v3/pkg/backup"
+	"github.com/datasance/iofog-go-sdk/v3/pkg/client"
+	"github.com/datasance/iofog-go-sdk/v3/pkg/migrate"
+)
+
+// agentMapping collects repeated -agent source=target flags
+type agentMapping map[string]string
+
+func (mapping agentMapping) String() string {
+	pairs := make([]string, 0, len(mapping))
+	for source, target := range mapping {
+		pairs = append(pairs, source+"="+target)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (mapping agentMapping) Set(value string) error {
+	source, target, found := strings.Cut(value, "=")
+	if !found || source == "" || target == "" {
+		return fmt.Errorf("expected source=target, got %s", value)
+	}
+	mapping[source] = target
+	return nil
+}
+
+type controllerFlags struct {
+	endpoint string
+	email    string
+	password string
+	otp      string
+}
+
+func (ctrl *controllerFlags) register(prefix string) {
+	flag.StringVar(&ctrl.endpoint, prefix, "", fmt.Sprintf("URL of the %s Controller", prefix))
+	flag.StringVar(&ctrl.email, prefix+"-email", "", fmt.Sprintf("email of the %s Controller user", prefix))
+	flag.StringVar(&ctrl.password, prefix+"-password", "", fmt.Sprintf("password of the %s Controller user, defaults to $IOFOG_%s_PASSWORD", prefix, strings.ToUpper(prefix)))
+	flag.StringVar(&ctrl.otp, prefix+"-otp", "", fmt.Sprintf("one-time password of the %s Controller user, required if two-factor authentication is enabled", prefix))
+}
+
+func (ctrl *controllerFlags) login(prefix string) (*client.Client, error) {
+	if ctrl.endpoint == "" || ctrl.email == "" {
+		return nil, fmt.Errorf("-%s and -%s-email are required", prefix, prefix)
+	}
+	if ctrl.password == "" {
+		ctrl.password = os.Getenv(fmt.Sprintf("IOFOG_%s_PASSWORD", strings.ToUpper(prefix)))
+	}
+	baseURL, err := url.Parse(ctrl.endpoint)
+	if err != nil {
+		return nil, err
+	}
+	clt := client.New(client.Options{BaseURL: baseURL})
+	if err = clt.Login(client.LoginRequest{Email: ctrl.email, Password: ctrl.password, Totp: ctrl.otp}); err != nil {
+		return nil, fmt.Errorf("%s Controller: %v", prefix, err)
+	}
+	return clt, nil
+}
+
+func main() {
+	var source, target controllerFlags
+	source.register("source")
+	target.register("target")
+	agents := make(agentMapping)
+	flag.Var(agents, "agent", "maps a source Agent to a target Agent as source=target, can be repeated")
+	conflict := flag.String("conflict", string(backup.ConflictFail), "policy for resources that already exist on the target Controller: skip, overwrite or fail")
+	dryRun := flag.Bool("dry-run", false, "only report what would be migrated")
+	flag.Parse()
+
+	if err := run(&source, &target, migrate.Options{
+		AgentNames: agents,
+		Conflict:   backup.ConflictPolicy(*conflict),
+		DryRun:     *dryRun,
+	}); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+}
+
+func run(source, target *controllerFlags, opt migrate.Options) error {
+	sourceClient, err := source.login("source")
+	if err != nil {
+		return err
+	}
+	targetClient, err := target.login("target")
+	if err != nil {
+		return err
+	}
+	report, err := migrate.Migrate(sourceClient, targetClient, opt)
+	if report != nil {
+		fmt.Print(report.String())
+	}
+	return err
+}
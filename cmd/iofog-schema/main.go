@@ -0,0 +1,65 @@
+/*
+ *  *******************************************************************************
+ *  * Copyright (c) 2024 Datasance Teknoloji A.S.
+ *  *
+ *  * This program and the accompanying materials are made available under the
+ *  * terms of the Eclipse Public License v. 2.0 which is available at
+ *  * http://www.eclipse.org/legal/epl-2.0
+ *  *
+ *  * SPDX-License-Identifier: EPL-2.0
+ *  *******************************************************************************
+ *
+ */
+
+// Command iofog-schema writes the JSON Schemas of the manifest kinds, and an OpenAPI v3 fragment, generated from pkg/apps
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/datasance/iofog-go-sdk/v3/pkg/apps"
+)
+
+func main() {
+	out := flag.String("out", "schemas", "directory the schemas are written to")
+	flag.Parse()
+
+	if err := run(*out); err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		os.Exit(1)
+	}
+}
+
+func run(out string) error {
+	if err := os.MkdirAll(out, 0755); err != nil {
+		return err
+	}
+	for _, kind := range apps.DefaultScheme.Kinds(apps.APIVersion) {
+		schema, err := apps.DefaultScheme.JSONSchema(apps.APIVersion, kind)
+		if err != nil {
+			return err
+		}
+		if err = write(filepath.Join(out, strings.ToLower(string(kind))+".schema.json"), schema); err != nil {
+			return err
+		}
+	}
+	openAPI := map[string]interface{}{
+		"components": map[string]interface{}{
+			"schemas": apps.DefaultScheme.OpenAPISchemas(apps.APIVersion),
+		},
+	}
+	return write(filepath.Join(out, "openapi.json"), openAPI)
+}
+
+func write(path string, value interface{}) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
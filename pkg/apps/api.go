@@ -14,7 +14,11 @@
 package apps
 
 import (
+	"context"
+	"io"
 	"net/url"
+
+	"github.com/datasance/iofog-go-sdk/v3/pkg/client"
 )
 
 func DeployApplicationTemplate(controller IofogController, controllerBaseURL *url.URL, template interface{}, name string) error {
@@ -27,7 +31,367 @@ func DeployApplication(controller IofogController, application interface{}, name
 	return exe.execute()
 }
 
+// DeployApplicationWithOptions deploys the application and optionally waits for its microservices to be ready,
+// reporting progress and rolling back to the previous application spec if the rollout fails
+func DeployApplicationWithOptions(controller IofogController, application interface{}, name string, opt DeployOptions) error {
+	exe := newRolloutExecutor(controller, application, name, opt)
+	return exe.execute()
+}
+
 func DeployMicroservice(controller IofogController, microservice interface{}, appName, name string) error {
 	exe := newMicroserviceExecutor(controller, microservice, appName, name)
 	return exe.execute()
 }
+
+// DeployApplicationTemplateWithClient deploys the application template using an existing Controller client
+func DeployApplicationTemplateWithClient(clt *client.Client, template interface{}, name string) error {
+	exe := newApplicationTemplateExecutor(IofogController{}, nil, template, name)
+	exe.client = clt
+	return exe.execute()
+}
+
+// DeployApplicationWithClient deploys the application using an existing Controller client
+func DeployApplicationWithClient(clt *client.Client, application interface{}, name string) error {
+	exe := newApplicationExecutor(IofogController{}, application, name)
+	exe.client = clt
+	return exe.execute()
+}
+
+// DeployMicroserviceWithClient deploys the microservice using an existing Controller client
+func DeployMicroserviceWithClient(clt *client.Client, microservice interface{}, appName, name string) error {
+	exe := newMicroserviceExecutor(IofogController{}, microservice, appName, name)
+	exe.client = clt
+	return exe.execute()
+}
+
+// DeployApplicationWithSession deploys the application using the cached client of the controller
+func DeployApplicationWithSession(sessions *SessionManager, controller IofogController, application interface{}, name string) error {
+	clt, err := sessions.Client(controller)
+	if err != nil {
+		return err
+	}
+	return DeployApplicationWithClient(clt, application, name)
+}
+
+// DeployMicroserviceWithSession deploys the microservice using the cached client of the controller
+func DeployMicroserviceWithSession(sessions *SessionManager, controller IofogController, microservice interface{}, appName, name string) error {
+	clt, err := sessions.Client(controller)
+	if err != nil {
+		return err
+	}
+	return DeployMicroserviceWithClient(clt, microservice, appName, name)
+}
+
+// DeployApplicationTemplateWithSession deploys the application template using the cached client of the controller
+func DeployApplicationTemplateWithSession(sessions *SessionManager, controller IofogController, template interface{}, name string) error {
+	clt, err := sessions.Client(controller)
+	if err != nil {
+		return err
+	}
+	return DeployApplicationTemplateWithClient(clt, template, name)
+}
+
+// BulkDeploy deploys the templates, then the applications, with bounded concurrency and a single Controller session
+// Failed deployments are returned as a *BulkError, cancelling the context stops the deployments that have not started
+func BulkDeploy(ctx context.Context, controller IofogController, deployment BulkDeployment, opt BulkOptions) error {
+	clt, err := newClient(controller)
+	if err != nil {
+		return err
+	}
+	return BulkDeployWithClient(ctx, clt, deployment, opt)
+}
+
+// BulkDeployWithClient is BulkDeploy using an existing Controller client
+func BulkDeployWithClient(ctx context.Context, clt *client.Client, deployment BulkDeployment, opt BulkOptions) error {
+	exe := newBulkExecutor(clt, deployment, opt)
+	return exe.execute(ctx)
+}
+
+// ApplyManifest applies every document of a multi-document YAML manifest, ordered by dependency
+// It stops at the first resource that fails and returns the results of the resources applied so far
+func ApplyManifest(controller IofogController, manifest io.Reader) (*ApplyReport, error) {
+	exe := newManifestExecutor(controller, manifest)
+	return exe.execute()
+}
+
+// PlanPrune previews the resources ApplyManifestWithPrune would delete: resources applied under the same set that the manifest no longer declares
+func PlanPrune(controller IofogController, manifest io.Reader, opt PruneOptions) (*PrunePlan, error) {
+	exe := newPruneExecutor(controller, manifest, opt)
+	return exe.plan()
+}
+
+// ApplyManifestWithPrune applies the manifest, then deletes the resources of the allowed kinds it no longer declares
+// Ownership of the applied resources is recorded in a config map named after the set
+func ApplyManifestWithPrune(controller IofogController, manifest io.Reader, opt PruneOptions) (*ApplyReport, error) {
+	exe := newPruneExecutor(controller, manifest, opt)
+	return exe.execute()
+}
+
+// ApplyDocuments applies documents decoded by a Scheme, ordered by dependency, using an existing Controller client
+func ApplyDocuments(clt *client.Client, documents []Header) (*ApplyReport, error) {
+	exe := newDocumentsExecutor(clt, documents)
+	return exe.run()
+}
+
+// PlanApplication compares an application to its deployed state and returns the changes DeployApplication would make
+// A stopped application is reported as started, and an application deployed from a template reports the template
+// rather than its microservices. It does not change anything on the Controller
+func PlanApplication(controller IofogController, application interface{}, name string) (*ApplicationPlan, error) {
+	app, err := toApplication(application)
+	if err != nil {
+		return nil, err
+	}
+	clt, err := newClient(controller)
+	if err != nil {
+		return nil, err
+	}
+	return planApplication(clt, app, name)
+}
+
+// ExportApplication reads a deployed application back into an application spec that can be deployed again
+// Use DefaultScheme.Encode(ApplicationDocument(app)) to get the kind: Application YAML document
+func ExportApplication(controller IofogController, name string) (*Application, error) {
+	clt, err := newClient(controller)
+	if err != nil {
+		return nil, err
+	}
+	return exportApplication(clt, name)
+}
+
+// ExportApplicationWithClient reads a deployed application back into an application spec using an existing Controller client
+func ExportApplicationWithClient(clt *client.Client, name string) (*Application, error) {
+	return exportApplication(clt, name)
+}
+
+// DeleteApplication deletes a regular or system application, optionally cleaning up the data of its microservices and waiting for the deletion
+func DeleteApplication(controller IofogController, name string, opt LifecycleOptions) error {
+	clt, err := newClient(controller)
+	if err != nil {
+		return err
+	}
+	return DeleteApplicationWithClient(clt, name, opt)
+}
+
+// DeleteApplicationWithClient is DeleteApplication using an existing Controller client
+func DeleteApplicationWithClient(clt *client.Client, name string, opt LifecycleOptions) error {
+	exe := newLifecycleExecutor(clt, name, "", opt)
+	return exe.deleteApplication()
+}
+
+// DeleteMicroservice deletes a microservice of a regular application, optionally cleaning up its data and waiting for the deletion
+func DeleteMicroservice(controller IofogController, appName, name string, opt LifecycleOptions) error {
+	clt, err := newClient(controller)
+	if err != nil {
+		return err
+	}
+	return DeleteMicroserviceWithClient(clt, appName, name, opt)
+}
+
+// DeleteMicroserviceWithClient is DeleteMicroservice using an existing Controller client
+func DeleteMicroserviceWithClient(clt *client.Client, appName, name string, opt LifecycleOptions) error {
+	exe := newLifecycleExecutor(clt, appName, name, opt)
+	return exe.deleteMicroservice()
+}
+
+// StopApplication deactivates an application, optionally waiting for its microservices to be stopped
+func StopApplication(controller IofogController, name string, opt LifecycleOptions) error {
+	clt, err := newClient(controller)
+	if err != nil {
+		return err
+	}
+	return StopApplicationWithClient(clt, name, opt)
+}
+
+// StopApplicationWithClient is StopApplication using an existing Controller client
+func StopApplicationWithClient(clt *client.Client, name string, opt LifecycleOptions) error {
+	exe := newLifecycleExecutor(clt, name, "", opt)
+	return exe.setApplicationActive(false)
+}
+
+// StartApplication activates an application, optionally waiting for its microservices to be running and healthy
+func StartApplication(controller IofogController, name string, opt LifecycleOptions) error {
+	clt, err := newClient(controller)
+	if err != nil {
+		return err
+	}
+	return StartApplicationWithClient(clt, name, opt)
+}
+
+// StartApplicationWithClient is StartApplication using an existing Controller client
+func StartApplicationWithClient(clt *client.Client, name string, opt LifecycleOptions) error {
+	exe := newLifecycleExecutor(clt, name, "", opt)
+	return exe.setApplicationActive(true)
+}
+
+// DeleteApplicationTemplate deletes an application template, applications deployed from it are left untouched
+func DeleteApplicationTemplate(controller IofogController, name string) error {
+	clt, err := newClient(controller)
+	if err != nil {
+		return err
+	}
+	return DeleteApplicationTemplateWithClient(clt, name)
+}
+
+// DeleteApplicationTemplateWithClient is DeleteApplicationTemplate using an existing Controller client
+func DeleteApplicationTemplateWithClient(clt *client.Client, name string) error {
+	return clt.DeleteApplicationTemplate(name)
+}
+
+// RollbackApplication re-applies a revision of the application from its history, and records it as the latest revision
+func RollbackApplication(controller IofogController, store RevisionStore, name string, revision int) (*Revision, error) {
+	clt, err := newClient(controller)
+	if err != nil {
+		return nil, err
+	}
+	return rollbackApplication(clt, store, name, revision)
+}
+
+// RollbackApplicationWithClient is RollbackApplication using an existing Controller client
+func RollbackApplicationWithClient(clt *client.Client, store RevisionStore, name string, revision int) (*Revision, error) {
+	return rollbackApplication(clt, store, name, revision)
+}
+
+// GetApplicationStatus aggregates the state of the microservices of a regular or system application, and of their Agents, into a phase
+func GetApplicationStatus(controller IofogController, name string) (*ApplicationStatus, error) {
+	clt, err := newClient(controller)
+	if err != nil {
+		return nil, err
+	}
+	return applicationStatus(clt, name)
+}
+
+// GetApplicationStatusWithClient is GetApplicationStatus using an existing Controller client
+func GetApplicationStatusWithClient(clt *client.Client, name string) (*ApplicationStatus, error) {
+	return applicationStatus(clt, name)
+}
+
+// GetRouteGraph returns the routes and pub/sub tags between the microservices of a deployed regular or system application
+func GetRouteGraph(controller IofogController, name string) (*RouteGraph, error) {
+	clt, err := newClient(controller)
+	if err != nil {
+		return nil, err
+	}
+	return routeGraph(clt, name)
+}
+
+// GetRouteGraphWithClient is GetRouteGraph using an existing Controller client
+func GetRouteGraphWithClient(clt *client.Client, name string) (*RouteGraph, error) {
+	return routeGraph(clt, name)
+}
+
+// PlanRoutes returns the route creations, patches and deletions ReconcileRoutes would apply, without changing anything
+func PlanRoutes(controller IofogController, appName string, routes []Route) (*RoutePlan, error) {
+	clt, err := newClient(controller)
+	if err != nil {
+		return nil, err
+	}
+	return PlanRoutesWithClient(clt, appName, routes)
+}
+
+// PlanRoutesWithClient is PlanRoutes using an existing Controller client
+func PlanRoutesWithClient(clt *client.Client, appName string, routes []Route) (*RoutePlan, error) {
+	return newRouteExecutor(clt, appName, routes).plan()
+}
+
+// ReconcileRoutes makes the named routes of a deployed application match the desired routes
+// Routes are matched by name: missing routes are created, routes whose endpoints differ are patched and undeclared routes are deleted
+func ReconcileRoutes(controller IofogController, appName string, routes []Route) (*RoutePlan, error) {
+	clt, err := newClient(controller)
+	if err != nil {
+		return nil, err
+	}
+	return ReconcileRoutesWithClient(clt, appName, routes)
+}
+
+// ReconcileRoutesWithClient is ReconcileRoutes using an existing Controller client
+func ReconcileRoutesWithClient(clt *client.Client, appName string, routes []Route) (*RoutePlan, error) {
+	return newRouteExecutor(clt, appName, routes).execute()
+}
+
+// PlanMicroserviceSettings returns the changes ReconcileMicroserviceSettings would apply, without changing anything
+func PlanMicroserviceSettings(controller IofogController, appName, name string, settings MicroserviceSettings) (*SettingsPlan, error) {
+	clt, err := newClient(controller)
+	if err != nil {
+		return nil, err
+	}
+	return PlanMicroserviceSettingsWithClient(clt, appName, name, settings)
+}
+
+// PlanMicroserviceSettingsWithClient is PlanMicroserviceSettings using an existing Controller client
+func PlanMicroserviceSettingsWithClient(clt *client.Client, appName, name string, settings MicroserviceSettings) (*SettingsPlan, error) {
+	return newSettingsExecutor(clt, appName, name, settings).plan()
+}
+
+// ReconcileMicroserviceSettings makes the port mappings, env, volume mappings and extra hosts of a deployed microservice match
+// the settings, applying only the changed items and lists
+func ReconcileMicroserviceSettings(controller IofogController, appName, name string, settings MicroserviceSettings) (*SettingsPlan, error) {
+	clt, err := newClient(controller)
+	if err != nil {
+		return nil, err
+	}
+	return ReconcileMicroserviceSettingsWithClient(clt, appName, name, settings)
+}
+
+// ReconcileMicroserviceSettingsWithClient is ReconcileMicroserviceSettings using an existing Controller client
+func ReconcileMicroserviceSettingsWithClient(clt *client.Client, appName, name string, settings MicroserviceSettings) (*SettingsPlan, error) {
+	return newSettingsExecutor(clt, appName, name, settings).execute()
+}
+
+// ResolveEnvironment returns the env a microservice spec would get once deployed, reading its secrets and config maps from the Controller
+// Secret values are masked, variables whose secret, config map or key is missing are flagged, see EffectiveEnvironment.Validate
+func ResolveEnvironment(controller IofogController, msvc *Microservice) (*EffectiveEnvironment, error) {
+	clt, err := newClient(controller)
+	if err != nil {
+		return nil, err
+	}
+	return resolveEnvironment(clt, msvc)
+}
+
+// ResolveEnvironmentWithClient is ResolveEnvironment using an existing Controller client
+func ResolveEnvironmentWithClient(clt *client.Client, msvc *Microservice) (*EffectiveEnvironment, error) {
+	return resolveEnvironment(clt, msvc)
+}
+
+// GetDependencyGraph returns which deployed microservices, Agents, volume mounts and certificates use secrets, config maps,
+// volume mounts and CAs
+func GetDependencyGraph(controller IofogController) (*DependencyGraph, error) {
+	clt, err := newClient(controller)
+	if err != nil {
+		return nil, err
+	}
+	return dependencyGraph(clt)
+}
+
+// GetDependencyGraphWithClient is GetDependencyGraph using an existing Controller client
+func GetDependencyGraphWithClient(clt *client.Client) (*DependencyGraph, error) {
+	return dependencyGraph(clt)
+}
+
+// RotateSecret updates the data of a secret, then restarts the microservices consuming it through env or volume mounts
+// in batches, waiting for each batch to be running and healthy. The rotation halts with a *RotationError at the first failed batch
+func RotateSecret(controller IofogController, name string, data map[string]string, opt RotationOptions) (*RotationResult, error) {
+	clt, err := newClient(controller)
+	if err != nil {
+		return nil, err
+	}
+	return RotateSecretWithClient(clt, name, data, opt)
+}
+
+// RotateSecretWithClient is RotateSecret using an existing Controller client
+func RotateSecretWithClient(clt *client.Client, name string, data map[string]string, opt RotationOptions) (*RotationResult, error) {
+	return newSecretRotationExecutor(clt, name, data, opt).execute()
+}
+
+// RotateConfigMap updates the data of a config map, then restarts the microservices consuming it like RotateSecret
+func RotateConfigMap(controller IofogController, name string, data map[string]string, opt RotationOptions) (*RotationResult, error) {
+	clt, err := newClient(controller)
+	if err != nil {
+		return nil, err
+	}
+	return RotateConfigMapWithClient(clt, name, data, opt)
+}
+
+// RotateConfigMapWithClient is RotateConfigMap using an existing Controller client
+func RotateConfigMapWithClient(clt *client.Client, name string, data map[string]string, opt RotationOptions) (*RotationResult, error) {
+	return newConfigMapRotationExecutor(clt, name, data, opt).execute()
+}
@@ -15,8 +15,6 @@ package apps
 
 import (
 	"bytes"
-	"fmt"
-	"net/url"
 
 	"github.com/datasance/iofog-go-sdk/v3/pkg/client"
 	"gopkg.in/yaml.v2"
@@ -46,6 +44,10 @@ func (exe *applicationExecutor) execute() (err error) {
 		return err
 	}
 
+	return exe.run()
+}
+
+func (exe *applicationExecutor) run() (err error) {
 	// Try application API
 	// Look for exisiting application
 	exe.applicationInfo, err = exe.client.GetApplicationByName(exe.name)
@@ -63,21 +65,13 @@ func (exe *applicationExecutor) execute() (err error) {
 }
 
 func (exe *applicationExecutor) init() (err error) {
-	baseURL, err := url.Parse(exe.controller.Endpoint)
-	if err != nil {
-		return fmt.Errorf(errParseControllerURL, err.Error())
-	}
-	if exe.controller.Token != "" {
-		exe.client, err = client.NewWithToken(client.Options{BaseURL: baseURL}, exe.controller.Token)
-	} else {
-		exe.client, err = client.SessionLogin(client.Options{BaseURL: baseURL}, exe.controller.RefreshToken, exe.controller.Email, exe.controller.Password)
-	}
+	exe.client, err = clientOrLogin(exe.client, exe.controller)
 	return err
 }
 
 func (exe *applicationExecutor) create() (err error) {
 	file := IofogHeader{
-		APIVersion: "datasance.com/v3",
+		APIVersion: APIVersion,
 		Kind:       ApplicationKind,
 		Metadata: HeaderMetadata{
 			Name: exe.name,
@@ -96,7 +90,7 @@ func (exe *applicationExecutor) create() (err error) {
 
 func (exe *applicationExecutor) update() (err error) {
 	file := IofogHeader{
-		APIVersion: "datasance.com/v3",
+		APIVersion: APIVersion,
 		Kind:       ApplicationKind,
 		Metadata: HeaderMetadata{
 			Name: exe.name,
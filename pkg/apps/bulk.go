@@ -0,0 +1,214 @@
+/*
+ *  *******************************************************************************
+ *  * Copyright (c) 2024 Datasance Teknoloji A.S.
+ *  *
+ *  * This program and the accompanying materials are made available under the
+ *  * terms of the Eclipse Public License v. 2.0 which is available at
+ *  * http://www.eclipse.org/legal/epl-2.0
+ *  *
+ *  * SPDX-License-Identifier: EPL-2.0
+ *  *******************************************************************************
+ *
+ */
+
+package apps
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/datasance/iofog-go-sdk/v3/pkg/client"
+)
+
+// defaultBulkConcurrency is the number of deployments run at the same time when BulkOptions.Concurrency is not set
+const defaultBulkConcurrency = 4
+
+// BulkDeployment is a set of application templates and applications, keyed by name
+type BulkDeployment struct {
+	Templates    map[string]interface{}
+	Applications map[string]interface{}
+}
+
+// BulkOptions controls a bulk deployment
+type BulkOptions struct {
+	// Concurrency is the maximum number of deployments running at the same time, defaults to 4
+	Concurrency int
+}
+
+// DeployError is the failure of a single deployment of a bulk deployment
+type DeployError struct {
+	Kind Kind
+	Name string
+	Err  error
+}
+
+func (err *DeployError) Error() string {
+	return fmt.Sprintf("%s %s: %s", err.Kind, err.Name, err.Err.Error())
+}
+
+func (err *DeployError) Unwrap() error {
+	return err.Err
+}
+
+// BulkError aggregates the failed deployments of a bulk deployment
+type BulkError struct {
+	Errors []*DeployError
+}
+
+func (err *BulkError) Error() string {
+	msgs := make([]string, 0, len(err.Errors))
+	for _, deployErr := range err.Errors {
+		msgs = append(msgs, deployErr.Error())
+	}
+	return fmt.Sprintf("%d deployments failed: %s", len(err.Errors), strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the errors of the failed deployments
+func (err *BulkError) Unwrap() []error {
+	errs := make([]error, 0, len(err.Errors))
+	for _, deployErr := range err.Errors {
+		errs = append(errs, deployErr)
+	}
+	return errs
+}
+
+type bulkTask struct {
+	kind Kind
+	name string
+	run  func() error
+}
+
+// runBulk runs the tasks with at most concurrency of them at the same time
+// Tasks that have not started when the context is cancelled fail with the context error
+func runBulk(ctx context.Context, tasks []bulkTask, concurrency int) (errs []*DeployError) {
+	var (
+		mutex sync.Mutex
+		wg    sync.WaitGroup
+	)
+	fail := func(task bulkTask, err error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		errs = append(errs, &DeployError{Kind: task.kind, Name: task.name, Err: err})
+	}
+
+	slots := make(chan struct{}, concurrency)
+	for _, task := range tasks {
+		select {
+		case <-ctx.Done():
+			fail(task, ctx.Err())
+			continue
+		case slots <- struct{}{}:
+		}
+		// The slot may have been acquired while the context was cancelled
+		if ctx.Err() != nil {
+			<-slots
+			fail(task, ctx.Err())
+			continue
+		}
+		wg.Add(1)
+		go func(task bulkTask) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			if err := task.run(); err != nil {
+				fail(task, err)
+			}
+		}(task)
+	}
+	wg.Wait()
+	return errs
+}
+
+type bulkExecutor struct {
+	deployment     BulkDeployment
+	opt            BulkOptions
+	deployTemplate func(name string, template interface{}) error
+	deployApp      func(name string, app interface{}) error
+}
+
+func newBulkExecutor(clt *client.Client, deployment BulkDeployment, opt BulkOptions) *bulkExecutor {
+	exe := &bulkExecutor{
+		deployment: deployment,
+		opt:        opt,
+		deployTemplate: func(name string, template interface{}) error {
+			return DeployApplicationTemplateWithClient(clt, template, name)
+		},
+		deployApp: func(name string, app interface{}) error {
+			return DeployApplicationWithClient(clt, app, name)
+		},
+	}
+	if exe.opt.Concurrency <= 0 {
+		exe.opt.Concurrency = defaultBulkConcurrency
+	}
+
+	return exe
+}
+
+// execute deploys the templates, then the applications
+// Applications referencing a template that failed to deploy are not deployed
+func (exe *bulkExecutor) execute(ctx context.Context) error {
+	var templateTasks []bulkTask
+	for _, name := range sortedKeys(exe.deployment.Templates) {
+		name, template := name, exe.deployment.Templates[name]
+		templateTasks = append(templateTasks, bulkTask{
+			kind: ApplicationTemplateKind,
+			name: name,
+			run:  func() error { return exe.deployTemplate(name, template) },
+		})
+	}
+	errs := runBulk(ctx, templateTasks, exe.opt.Concurrency)
+	failedTemplates := make(map[string]bool)
+	for _, err := range errs {
+		failedTemplates[err.Name] = true
+	}
+
+	var appTasks []bulkTask
+	for _, name := range sortedKeys(exe.deployment.Applications) {
+		name, app := name, exe.deployment.Applications[name]
+		if template := templateName(app); failedTemplates[template] {
+			errs = append(errs, &DeployError{
+				Kind: ApplicationKind,
+				Name: name,
+				Err:  NewError(fmt.Sprintf("Application template %s failed to deploy", template)),
+			})
+			continue
+		}
+		appTasks = append(appTasks, bulkTask{
+			kind: ApplicationKind,
+			name: name,
+			run:  func() error { return exe.deployApp(name, app) },
+		})
+	}
+	errs = append(errs, runBulk(ctx, appTasks, exe.opt.Concurrency)...)
+
+	if len(errs) == 0 {
+		return nil
+	}
+	sort.SliceStable(errs, func(i, j int) bool {
+		if errs[i].Kind != errs[j].Kind {
+			return kindOrder(errs[i].Kind) < kindOrder(errs[j].Kind)
+		}
+		return errs[i].Name < errs[j].Name
+	})
+	return &BulkError{Errors: errs}
+}
+
+// templateName returns the name of the template an application is deployed from, if any
+func templateName(app interface{}) string {
+	spec, err := toApplication(app)
+	if err != nil || spec.Template == nil {
+		return ""
+	}
+	return spec.Template.Name
+}
+
+func sortedKeys(in map[string]interface{}) []string {
+	keys := make([]string, 0, len(in))
+	for key := range in {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,102 @@
+/*
+ *  *******************************************************************************
+ *  * Copyright (c) 2024 Datasance Teknoloji A.S.
+ *  *
+ *  * This program and the accompanying materials are made available under the
+ *  * terms of the Eclipse Public License v. 2.0 which is available at
+ *  * http://www.eclipse.org/legal/epl-2.0
+ *  *
+ *  * SPDX-License-Identifier: EPL-2.0
+ *  *******************************************************************************
+ *
+ */
+
+package apps
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBulkDeploy(t *testing.T) {
+	var (
+		running, maxRunning int32
+		mutex               sync.Mutex
+		deployed            []string
+	)
+	track := func(name string, fail bool) error {
+		current := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			max := atomic.LoadInt32(&maxRunning)
+			if current <= max || atomic.CompareAndSwapInt32(&maxRunning, max, current) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		mutex.Lock()
+		deployed = append(deployed, name)
+		mutex.Unlock()
+		if fail {
+			return errors.New("controller error")
+		}
+		return nil
+	}
+
+	deployment := BulkDeployment{
+		Templates: map[string]interface{}{"good": nil, "bad": nil},
+		Applications: map[string]interface{}{
+			"from-bad":  &Application{Name: "from-bad", Template: &ApplicationTemplate{Name: "bad"}},
+			"from-good": &Application{Name: "from-good", Template: &ApplicationTemplate{Name: "good"}},
+		},
+	}
+	for i := 0; i < 10; i++ {
+		name := fmt.Sprintf("app-%d", i)
+		deployment.Applications[name] = &Application{Name: name}
+	}
+	exe := &bulkExecutor{
+		deployment:     deployment,
+		opt:            BulkOptions{Concurrency: 3},
+		deployTemplate: func(name string, _ interface{}) error { return track(name, name == "bad") },
+		deployApp:      func(name string, _ interface{}) error { return track(name, name == "app-3") },
+	}
+	err := exe.execute(context.Background())
+
+	var bulkErr *BulkError
+	if !errors.As(err, &bulkErr) {
+		t.Fatalf("Expected a bulk error, got %v", err)
+	}
+	expected := []string{"ApplicationTemplate bad", "Application app-3", "Application from-bad"}
+	if len(bulkErr.Errors) != len(expected) {
+		t.Fatalf("Expected %d errors, got %v", len(expected), bulkErr)
+	}
+	for idx, deployErr := range bulkErr.Errors {
+		if got := fmt.Sprintf("%s %s", deployErr.Kind, deployErr.Name); got != expected[idx] {
+			t.Errorf("Expected error %d to be for %s, got %s", idx, expected[idx], got)
+		}
+	}
+	if len(deployed) != 13 {
+		t.Errorf("Expected 13 deployments, got %d", len(deployed))
+	}
+	if maxRunning > 3 {
+		t.Errorf("Expected at most 3 concurrent deployments, got %d", maxRunning)
+	}
+}
+
+func TestBulkDeployCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	tasks := []bulkTask{
+		{kind: ApplicationKind, name: "first", run: func() error { return nil }},
+		{kind: ApplicationKind, name: "second", run: func() error { return nil }},
+	}
+	errs := runBulk(ctx, tasks, 1)
+	if len(errs) != 2 || !errors.Is(errs[0], context.Canceled) {
+		t.Errorf("Expected cancelled deployments, got %v", errs)
+	}
+}
@@ -0,0 +1,74 @@
+/*
+ *  *******************************************************************************
+ *  * Copyright (c) 2024 Datasance Teknoloji A.S.
+ *  *
+ *  * This program and the accompanying materials are made available under the
+ *  * terms of the Eclipse Public License v. 2.0 which is available at
+ *  * http://www.eclipse.org/legal/epl-2.0
+ *  *
+ *  * SPDX-License-Identifier: EPL-2.0
+ *  *******************************************************************************
+ *
+ */
+
+package apps
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/datasance/iofog-go-sdk/v3/pkg/client"
+)
+
+// Defaults of RetryOptions
+const (
+	defaultRetryAttempts = 5
+	defaultRetryInterval = 100 * time.Millisecond
+)
+
+// RetryOptions controls RetryOnConflict
+type RetryOptions struct {
+	// Attempts is the maximum number of calls, defaults to 5
+	Attempts int
+	// Interval before the first retry, doubled after every conflict, defaults to 100 milliseconds
+	Interval time.Duration
+}
+
+// IsConflict returns whether the error reports a conflicting update
+func IsConflict(err error) bool {
+	var clientConflict *client.ConflictError
+	var conflict *ConflictError
+	var httpErr *client.HTTPError
+	switch {
+	case errors.As(err, &clientConflict), errors.As(err, &conflict):
+		return true
+	case errors.As(err, &httpErr):
+		return httpErr.Code == http.StatusConflict
+	}
+	return false
+}
+
+// RetryOnConflict calls update until it does not fail with a conflict, or the attempts are exhausted
+// update must read the resource again on every call, and update it with a precondition, e.g. UpdateApplicationFromYAMLWithPrecondition
+// The last error is returned when every attempt conflicted
+func RetryOnConflict(opt RetryOptions, update func() error) error {
+	if opt.Attempts <= 0 {
+		opt.Attempts = defaultRetryAttempts
+	}
+	if opt.Interval <= 0 {
+		opt.Interval = defaultRetryInterval
+	}
+	var err error
+	interval := opt.Interval
+	for attempt := 0; attempt < opt.Attempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(interval)
+			interval *= 2
+		}
+		if err = update(); !IsConflict(err) {
+			return err
+		}
+	}
+	return err
+}
@@ -0,0 +1,70 @@
+/*
+ *  *******************************************************************************
+ *  * Copyright (c) 2024 Datasance Teknoloji A.S.
+ *  *
+ *  * This program and the accompanying materials are made available under the
+ *  * terms of the Eclipse Public License v. 2.0 which is available at
+ *  * http://www.eclipse.org/legal/epl-2.0
+ *  *
+ *  * SPDX-License-Identifier: EPL-2.0
+ *  *******************************************************************************
+ *
+ */
+
+package apps
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/datasance/iofog-go-sdk/v3/pkg/client"
+)
+
+func TestIsConflict(t *testing.T) {
+	cases := map[error]bool{
+		client.NewConflictError("changed"):                          true,
+		NewConflictError("changed"):                                 true,
+		fmt.Errorf("wrapped: %w", client.NewConflictError("x")):     true,
+		client.NewHTTPError("Conflict", 409):                        true,
+		client.NewHTTPError("Bad request", 400):                     false,
+		client.NewNotFoundError("missing"):                          false,
+		errors.New("Resource conflict error, but only in the text"): false,
+	}
+	for err, expected := range cases {
+		if IsConflict(err) != expected {
+			t.Errorf("Expected IsConflict(%q) to be %v", err.Error(), expected)
+		}
+	}
+}
+
+func TestRetryOnConflict(t *testing.T) {
+	opt := RetryOptions{Attempts: 3, Interval: time.Millisecond}
+	calls := 0
+	err := RetryOnConflict(opt, func() error {
+		calls++
+		if calls < 3 {
+			return client.NewConflictError("changed")
+		}
+		return nil
+	})
+	if err != nil || calls != 3 {
+		t.Errorf("Expected success on the third call, got %d calls and %v", calls, err)
+	}
+
+	calls = 0
+	err = RetryOnConflict(opt, func() error {
+		calls++
+		return client.NewConflictError("changed")
+	})
+	if !IsConflict(err) || calls != 3 {
+		t.Errorf("Expected the conflict after 3 calls, got %d calls and %v", calls, err)
+	}
+
+	calls = 0
+	failure := errors.New("failure")
+	if err = RetryOnConflict(opt, func() error { calls++; return failure }); err != failure || calls != 1 {
+		t.Errorf("Expected other errors not to be retried, got %d calls and %v", calls, err)
+	}
+}
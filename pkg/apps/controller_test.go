@@ -0,0 +1,73 @@
+/*
+ *  *******************************************************************************
+ *  * Copyright (c) 2024 Datasance Teknoloji A.S.
+ *  *
+ *  * This program and the accompanying materials are made available under the
+ *  * terms of the Eclipse Public License v. 2.0 which is available at
+ *  * http://www.eclipse.org/legal/epl-2.0
+ *  *
+ *  * SPDX-License-Identifier: EPL-2.0
+ *  *******************************************************************************
+ *
+ */
+
+package apps
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/datasance/iofog-go-sdk/v3/pkg/client"
+)
+
+// fakeController answers GET requests with its resources, keyed by path and query, and records every other request
+// Unknown resources are not found, other requests answer the resource keyed by "<METHOD> <path>" or an empty object
+type fakeController struct {
+	resources map[string]interface{}
+	mutex     sync.Mutex
+	requests  []string
+}
+
+func (ctrl *fakeController) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	key := strings.TrimPrefix(request.URL.Path, "/api/v3")
+	if request.URL.RawQuery != "" {
+		key += "?" + request.URL.RawQuery
+	}
+	if request.Method != http.MethodGet {
+		key = request.Method + " " + key
+		if request.Method != http.MethodHead {
+			ctrl.mutex.Lock()
+			ctrl.requests = append(ctrl.requests, key)
+			ctrl.mutex.Unlock()
+		}
+	}
+	ctrl.mutex.Lock()
+	resource, found := ctrl.resources[key]
+	ctrl.mutex.Unlock()
+	switch {
+	case found:
+		_ = json.NewEncoder(writer).Encode(resource)
+	case request.Method == http.MethodGet:
+		writer.WriteHeader(http.StatusNotFound)
+	default:
+		_, _ = writer.Write([]byte("{}"))
+	}
+}
+
+func newFakeController(t *testing.T, resources map[string]interface{}) (*client.Client, *fakeController) {
+	ctrl := &fakeController{resources: resources}
+	server := httptest.NewServer(ctrl)
+	t.Cleanup(server.Close)
+	baseURL, err := url.Parse(server.URL + "/api/v3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clt := client.New(client.Options{BaseURL: baseURL})
+	clt.SetAccessToken("token")
+	return clt, ctrl
+}
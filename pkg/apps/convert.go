@@ -0,0 +1,189 @@
+/*
+ *  *******************************************************************************
+ *  * Copyright (c) 2024 Datasance Teknoloji A.S.
+ *  *
+ *  * This program and the accompanying materials are made available under the
+ *  * terms of the Eclipse Public License v. 2.0 which is available at
+ *  * http://www.eclipse.org/legal/epl-2.0
+ *  *
+ *  * SPDX-License-Identifier: EPL-2.0
+ *  *******************************************************************************
+ *
+ */
+
+package apps
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/datasance/iofog-go-sdk/v3/pkg/client"
+	"gopkg.in/yaml.v2"
+)
+
+// microserviceFromInfo converts the microservice information returned by the Controller into a microservice spec
+// Server managed fields (UUID, status, creation time) are not copied
+func microserviceFromInfo(info *client.MicroserviceInfo, agentNameByUUID map[string]string) (msvc Microservice, err error) {
+	msvc = Microservice{
+		Name:     info.Name,
+		Agent:    MicroserviceAgent{Name: agentNameByUUID[info.AgentUUID]},
+		Schedule: info.Schedule,
+		MsRoutes: MsRoutes{
+			PubTags: info.PubTags,
+			SubTags: info.SubTags,
+		},
+		Container: MicroserviceContainer{
+			Commands:       info.Commands,
+			RootHostAccess: info.RootHostAccess,
+			PidMode:        info.PidMode,
+			IpcMode:        info.IpcMode,
+			Runtime:        info.Runtime,
+			Platform:       info.Platform,
+			RunAsUser:      info.RunAsUser,
+			CdiDevices:     info.CdiDevices,
+			CapAdd:         info.CapAdd,
+			CapDrop:        info.CapDrop,
+			CpuSetCpus:     info.CpuSetCpus,
+		},
+	}
+
+	msvc.Images = imagesFromInfo(info)
+
+	for _, port := range info.Ports {
+		msvc.Container.Ports = append(msvc.Container.Ports, MicroservicePortMapping{
+			Internal: port.Internal,
+			External: port.External,
+			Protocol: port.Protocol,
+		})
+	}
+	if len(info.Volumes) > 0 {
+		volumes := make([]MicroserviceVolumeMapping, 0, len(info.Volumes))
+		for _, volume := range info.Volumes {
+			volumes = append(volumes, MicroserviceVolumeMapping{
+				HostDestination:      volume.HostDestination,
+				ContainerDestination: volume.ContainerDestination,
+				AccessMode:           volume.AccessMode,
+				Type:                 volume.Type,
+			})
+		}
+		msvc.Container.Volumes = &volumes
+	}
+	if len(info.Env) > 0 {
+		env := make([]MicroserviceEnvironment, 0, len(info.Env))
+		for _, variable := range info.Env {
+			env = append(env, MicroserviceEnvironment{
+				Key:                variable.Key,
+				Value:              variable.Value,
+				ValueFromSecret:    variable.ValueFromSecret,
+				ValueFromConfigMap: variable.ValueFromConfigMap,
+			})
+		}
+		msvc.Container.Env = &env
+	}
+	if len(info.ExtraHosts) > 0 {
+		extraHosts := make([]MicroserviceExtraHost, 0, len(info.ExtraHosts))
+		for _, host := range info.ExtraHosts {
+			extraHosts = append(extraHosts, MicroserviceExtraHost{
+				Name:    host.Name,
+				Address: host.Address,
+				Value:   host.Value,
+			})
+		}
+		msvc.Container.ExtraHosts = &extraHosts
+	}
+	if info.MemoryLimit != 0 {
+		memoryLimit := info.MemoryLimit
+		msvc.Container.MemoryLimit = &memoryLimit
+	}
+	if len(info.HealthCheck.Test) > 0 {
+		msvc.Container.HealthCheck = &MicroserviceHealthCheck{
+			Test:          info.HealthCheck.Test,
+			Interval:      info.HealthCheck.Interval,
+			Timeout:       info.HealthCheck.Timeout,
+			Retries:       info.HealthCheck.Retries,
+			StartPeriod:   info.HealthCheck.StartPeriod,
+			StartInterval: info.HealthCheck.StartInterval,
+		}
+	}
+	if msvc.Container.Annotations, err = nestedMapFromJSON(info.Annotations); err != nil {
+		return msvc, NewInternalError(fmt.Sprintf("Could not parse annotations of microservice %s: %s", info.Name, err.Error()))
+	}
+	if msvc.Config, err = nestedMapFromJSON(info.Config); err != nil {
+		return msvc, NewInternalError(fmt.Sprintf("Could not parse config of microservice %s: %s", info.Name, err.Error()))
+	}
+	return msvc, nil
+}
+
+func imagesFromInfo(info *client.MicroserviceInfo) *MicroserviceImages {
+	images := &MicroserviceImages{
+		Registry: registryName(info.RegistryID),
+	}
+	// Microservices created from a catalog item get their images from the catalog
+	if info.CatalogItemID != 0 {
+		images.CatalogID = info.CatalogItemID
+		return images
+	}
+	for _, image := range info.Images {
+		switch client.AgentTypeIDAgentTypeDict[image.AgentTypeID] {
+		case "x86":
+			images.X86 = image.ContainerImage
+		case "arm":
+			images.ARM = image.ContainerImage
+		}
+	}
+	return images
+}
+
+// registryName returns the name used in microservice specs for a registry ID
+func registryName(registryID int) string {
+	if registryID == 0 {
+		return ""
+	}
+	if name, found := client.RegistryTypeIDRegistryTypeDict[registryID]; found {
+		return name
+	}
+	return strconv.Itoa(registryID)
+}
+
+func nestedMapFromJSON(in string) (NestedMap, error) {
+	if in == "" {
+		return nil, nil
+	}
+	out := NestedMap{}
+	if err := json.Unmarshal([]byte(in), &out); err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, nil
+	}
+	return out, nil
+}
+
+// routeFromInfo converts a route returned by the Controller into a route spec
+func routeFromInfo(info *client.Route) Route {
+	return Route{
+		Name: info.Name,
+		From: info.From,
+		To:   info.To,
+	}
+}
+
+// toApplication returns the application spec of any value accepted by DeployApplication
+func toApplication(application interface{}) (*Application, error) {
+	switch app := application.(type) {
+	case *Application:
+		return app, nil
+	case Application:
+		return &app, nil
+	}
+	data, err := yaml.Marshal(application)
+	if err != nil {
+		return nil, err
+	}
+	app := new(Application)
+	if err = yaml.Unmarshal(data, app); err != nil {
+		return nil, NewInputError(fmt.Sprintf("Could not read application spec: %s", err.Error()))
+	}
+	return app, nil
+}
@@ -0,0 +1,266 @@
+/*
+ *  *******************************************************************************
+ *  * Copyright (c) 2024 Datasance Teknoloji A.S.
+ *  *
+ *  * This program and the accompanying materials are made available under the
+ *  * terms of the Eclipse Public License v. 2.0 which is available at
+ *  * http://www.eclipse.org/legal/epl-2.0
+ *  *
+ *  * SPDX-License-Identifier: EPL-2.0
+ *  *******************************************************************************
+ *
+ */
+
+package apps
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/datasance/iofog-go-sdk/v3/pkg/client"
+)
+
+// AgentKind identifies Agents in dependency graphs, Agents are configured with AgentConfig documents
+const AgentKind Kind = "Agent"
+
+// volumeMountType is the type of the microservice volumes backed by a volume mount, whose host destination is the volume mount name
+const volumeMountType = "volumeMount"
+
+// ResourceRef identifies a resource, microservices are named <application>/<microservice>
+type ResourceRef struct {
+	Kind Kind
+	Name string
+}
+
+func (ref ResourceRef) String() string {
+	return fmt.Sprintf("%s/%s", ref.Kind, ref.Name)
+}
+
+// Dependency is a resource using another one
+type Dependency struct {
+	User ResourceRef
+	Used ResourceRef
+	// Via describes how the resource is used, e.g. env PASSWORD or volume /data
+	Via string
+}
+
+func (dependency Dependency) String() string {
+	return fmt.Sprintf("%s uses %s through %s", dependency.User, dependency.Used, dependency.Via)
+}
+
+// DependencyGraph lists which microservices, Agents, volume mounts and certificates use secrets, config maps,
+// volume mounts and CAs
+type DependencyGraph struct {
+	Dependencies []Dependency
+}
+
+func (graph *DependencyGraph) add(user, used ResourceRef, via string) {
+	if used.Name == "" {
+		return
+	}
+	dependency := Dependency{User: user, Used: used, Via: via}
+	for _, existing := range graph.Dependencies {
+		if existing == dependency {
+			return
+		}
+	}
+	graph.Dependencies = append(graph.Dependencies, dependency)
+}
+
+// addEnv records the secrets and config maps an env reads, references are <name>/<key>
+func (graph *DependencyGraph) addEnv(user ResourceRef, key, valueFromSecret, valueFromConfigMap string) {
+	if name, _, ok := splitReference(valueFromSecret); ok {
+		graph.add(user, ResourceRef{Kind: SecretKind, Name: name}, "env "+key)
+	}
+	if name, _, ok := splitReference(valueFromConfigMap); ok {
+		graph.add(user, ResourceRef{Kind: ConfigMapKind, Name: name}, "env "+key)
+	}
+}
+
+func (graph *DependencyGraph) addVolume(user ResourceRef, volumeType, hostDestination, containerDestination string) {
+	if volumeType == volumeMountType {
+		graph.add(user, ResourceRef{Kind: VolumeMountKind, Name: hostDestination}, "volume "+containerDestination)
+	}
+}
+
+// addMicroservice records the env and volumes of a microservice spec
+func (graph *DependencyGraph) addMicroservice(appName string, msvc *Microservice) {
+	user := ResourceRef{Kind: MicroserviceKind, Name: appName + "/" + msvc.Name}
+	if msvc.Container.Env != nil {
+		for _, env := range *msvc.Container.Env {
+			graph.addEnv(user, env.Key, env.ValueFromSecret, env.ValueFromConfigMap)
+		}
+	}
+	if msvc.Container.Volumes != nil {
+		for _, volume := range *msvc.Container.Volumes {
+			graph.addVolume(user, volume.Type, volume.HostDestination, volume.ContainerDestination)
+		}
+	}
+}
+
+// addMicroserviceInfo records the env and volumes of a deployed microservice
+func (graph *DependencyGraph) addMicroserviceInfo(msvc *client.MicroserviceInfo) {
+	user := ResourceRef{Kind: MicroserviceKind, Name: msvc.Application + "/" + msvc.Name}
+	for _, env := range msvc.Env {
+		graph.addEnv(user, env.Key, env.ValueFromSecret, env.ValueFromConfigMap)
+	}
+	for _, volume := range msvc.Volumes {
+		graph.addVolume(user, volume.Type, volume.HostDestination, volume.ContainerDestination)
+	}
+}
+
+func (graph *DependencyGraph) addVolumeMount(name, secretName, configMapName string) {
+	user := ResourceRef{Kind: VolumeMountKind, Name: name}
+	graph.add(user, ResourceRef{Kind: SecretKind, Name: secretName}, "secretName")
+	graph.add(user, ResourceRef{Kind: ConfigMapKind, Name: configMapName}, "configMapName")
+}
+
+func (graph *DependencyGraph) sort() {
+	sort.SliceStable(graph.Dependencies, func(i, j int) bool {
+		lhs, rhs := graph.Dependencies[i], graph.Dependencies[j]
+		if lhs.Used != rhs.Used {
+			return lhs.Used.String() < rhs.Used.String()
+		}
+		if lhs.User != rhs.User {
+			return lhs.User.String() < rhs.User.String()
+		}
+		return lhs.Via < rhs.Via
+	})
+}
+
+// NewDependencyGraph returns the dependencies declared by the documents of a manifest
+// Microservice documents are named <application>/<microservice>, certificates and CAs use the secret named by their secretName
+func NewDependencyGraph(headers []Header) *DependencyGraph {
+	graph := new(DependencyGraph)
+	for idx := range headers {
+		header := &headers[idx]
+		name := header.Metadata.Name
+		switch spec := header.Spec.(type) {
+		case *Application:
+			for msvcIdx := range spec.Microservices {
+				graph.addMicroservice(name, &spec.Microservices[msvcIdx])
+			}
+		case *Microservice:
+			appName, msvcName, err := ParseFQMsvcName(name)
+			if err != nil {
+				continue
+			}
+			msvc := *spec
+			msvc.Name = msvcName
+			graph.addMicroservice(appName, &msvc)
+		case *VolumeMount:
+			graph.addVolumeMount(name, spec.SecretName, spec.ConfigMapName)
+		case *CertificateAuthority:
+			graph.add(ResourceRef{Kind: CertificateAuthorityKind, Name: name}, ResourceRef{Kind: SecretKind, Name: spec.SecretName}, "secretName")
+		case *Certificate:
+			graph.add(ResourceRef{Kind: CertificateKind, Name: name}, ResourceRef{Kind: SecretKind, Name: spec.CA.SecretName}, "ca.secretName")
+		}
+	}
+	graph.sort()
+	return graph
+}
+
+// dependencyGraph returns the dependencies of the resources deployed on the Controller: microservices, including system
+// microservices, volume mounts, the volume mounts linked to Agents and the CAs signing certificates
+// The Controller does not report the secret names of certificates and CAs, use NewDependencyGraph on their manifests
+func dependencyGraph(clt *client.Client) (*DependencyGraph, error) {
+	graph := new(DependencyGraph)
+	msvcs, err := clt.GetAllMicroservices()
+	if err != nil {
+		return nil, err
+	}
+	systemMsvcs, err := clt.GetAllSystemMicroservices()
+	if err != nil {
+		return nil, err
+	}
+	for _, list := range []*client.MicroserviceListResponse{msvcs, systemMsvcs} {
+		for idx := range list.Microservices {
+			graph.addMicroserviceInfo(&list.Microservices[idx])
+		}
+	}
+
+	volumeMounts, err := clt.ListVolumeMounts()
+	if err != nil {
+		return nil, err
+	}
+	for _, volumeMount := range volumeMounts.VolumeMounts {
+		graph.addVolumeMount(volumeMount.Name, volumeMount.SecretName, volumeMount.ConfigMapName)
+	}
+
+	agents, err := clt.ListAgents(client.ListAgentsRequest{})
+	if err != nil {
+		return nil, err
+	}
+	for idx := range agents.Agents {
+		agent := &agents.Agents[idx]
+		for _, volumeMount := range agent.VolumeMounts {
+			graph.add(ResourceRef{Kind: AgentKind, Name: agent.Name}, ResourceRef{Kind: VolumeMountKind, Name: volumeMount.Name}, "link")
+		}
+	}
+
+	certificates, err := clt.ListCertificates()
+	if err != nil {
+		return nil, err
+	}
+	for _, certificate := range certificates.Certificates {
+		if certificate.CAName != nil {
+			graph.add(ResourceRef{Kind: CertificateKind, Name: certificate.Name}, ResourceRef{Kind: CertificateAuthorityKind, Name: *certificate.CAName}, "ca")
+		}
+	}
+	graph.sort()
+	return graph, nil
+}
+
+// Users returns the dependencies on the resource
+func (graph *DependencyGraph) Users(kind Kind, name string) (dependencies []Dependency) {
+	used := ResourceRef{Kind: kind, Name: name}
+	for _, dependency := range graph.Dependencies {
+		if dependency.Used == used {
+			dependencies = append(dependencies, dependency)
+		}
+	}
+	return dependencies
+}
+
+// WhoUses returns the dependencies on the resource and, transitively, on its users
+// e.g. the volume mounts of a secret, then the microservices and Agents using these volume mounts
+func (graph *DependencyGraph) WhoUses(kind Kind, name string) (dependencies []Dependency) {
+	visited := make(map[ResourceRef]bool)
+	queue := []ResourceRef{{Kind: kind, Name: name}}
+	for len(queue) > 0 {
+		used := queue[0]
+		queue = queue[1:]
+		if visited[used] {
+			continue
+		}
+		visited[used] = true
+		for _, dependency := range graph.Users(used.Kind, used.Name) {
+			dependencies = append(dependencies, dependency)
+			queue = append(queue, dependency.User)
+		}
+	}
+	return dependencies
+}
+
+// Microservices returns the microservices using the resource directly or transitively, sorted by name
+func (graph *DependencyGraph) Microservices(kind Kind, name string) (microservices []string) {
+	found := make(map[string]bool)
+	for _, dependency := range graph.WhoUses(kind, name) {
+		if dependency.User.Kind == MicroserviceKind && !found[dependency.User.Name] {
+			found[dependency.User.Name] = true
+			microservices = append(microservices, dependency.User.Name)
+		}
+	}
+	sort.Strings(microservices)
+	return microservices
+}
+
+// String returns one line per dependency
+func (graph *DependencyGraph) String() string {
+	var builder strings.Builder
+	for _, dependency := range graph.Dependencies {
+		builder.WriteString(dependency.String() + "\n")
+	}
+	return builder.String()
+}
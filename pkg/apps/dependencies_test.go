@@ -0,0 +1,101 @@
+/*
+ *  *******************************************************************************
+ *  * Copyright (c) 2024 Datasance Teknoloji A.S.
+ *  *
+ *  * This program and the accompanying materials are made available under the
+ *  * terms of the Eclipse Public License v. 2.0 which is available at
+ *  * http://www.eclipse.org/legal/epl-2.0
+ *  *
+ *  * SPDX-License-Identifier: EPL-2.0
+ *  *******************************************************************************
+ *
+ */
+
+package apps
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const dependencyManifest = `apiVersion: datasance.com/v3
+kind: Application
+metadata:
+  name: app
+spec:
+  microservices:
+    - name: api
+      container:
+        env:
+          - key: PASSWORD
+            valueFromSecret: db/password
+          - key: LEVEL
+            valueFromConfigMap: settings/level
+        volumes:
+          - hostDestination: certs
+            containerDestination: /certs
+            accessMode: ro
+            type: volumeMount
+          - hostDestination: /var/data
+            containerDestination: /data
+            accessMode: rw
+---
+apiVersion: datasance.com/v3
+kind: Microservice
+metadata:
+  name: app/worker
+spec:
+  container:
+    env:
+      - key: PASSWORD
+        valueFromSecret: db/password
+---
+apiVersion: datasance.com/v3
+kind: VolumeMount
+metadata:
+  name: certs
+spec:
+  secretName: tls
+---
+apiVersion: datasance.com/v3
+kind: Certificate
+metadata:
+  name: server
+spec:
+  subject: server
+  hosts: localhost
+  ca:
+    type: direct
+    secretName: root-ca
+`
+
+func TestDependencyGraph(t *testing.T) {
+	headers, err := DefaultScheme.DecodeAll(strings.NewReader(dependencyManifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	graph := NewDependencyGraph(headers)
+	if len(graph.Dependencies) != 6 {
+		t.Fatalf("expected 6 dependencies, got\n%s", graph)
+	}
+
+	users := graph.WhoUses(SecretKind, "tls")
+	expected := []Dependency{
+		{User: ResourceRef{Kind: VolumeMountKind, Name: "certs"}, Used: ResourceRef{Kind: SecretKind, Name: "tls"}, Via: "secretName"},
+		{User: ResourceRef{Kind: MicroserviceKind, Name: "app/api"}, Used: ResourceRef{Kind: VolumeMountKind, Name: "certs"}, Via: "volume /certs"},
+	}
+	if !reflect.DeepEqual(users, expected) {
+		t.Errorf("expected %v, got %v", expected, users)
+	}
+
+	if msvcs := graph.Microservices(SecretKind, "db"); !reflect.DeepEqual(msvcs, []string{"app/api", "app/worker"}) {
+		t.Errorf("unexpected users of secret db: %v", msvcs)
+	}
+	if users := graph.Users(SecretKind, "root-ca"); len(users) != 1 || users[0].User.Kind != CertificateKind {
+		t.Errorf("unexpected users of secret root-ca: %v", users)
+	}
+	if users := graph.WhoUses(ConfigMapKind, "unused"); len(users) != 0 {
+		t.Errorf("expected no users, got %v", users)
+	}
+}
@@ -0,0 +1,209 @@
+/*
+ *  *******************************************************************************
+ *  * Copyright (c) 2024 Datasance Teknoloji A.S.
+ *  *
+ *  * This program and the accompanying materials are made available under the
+ *  * terms of the Eclipse Public License v. 2.0 which is available at
+ *  * http://www.eclipse.org/legal/epl-2.0
+ *  *
+ *  * SPDX-License-Identifier: EPL-2.0
+ *  *******************************************************************************
+ *
+ */
+
+package apps
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/datasance/iofog-go-sdk/v3/pkg/client"
+)
+
+// maskedValue replaces the values read from secrets
+const maskedValue = "********"
+
+// EnvSource is where the value of an env variable comes from
+type EnvSource string
+
+const (
+	EnvFromValue     EnvSource = "value"
+	EnvFromSecret    EnvSource = "secret"
+	EnvFromConfigMap EnvSource = "configMap"
+)
+
+// EffectiveEnv is the value an env variable of a microservice resolves to
+type EffectiveEnv struct {
+	Key    string
+	Value  string
+	Source EnvSource
+	// Reference is the <name>/<key> the value is read from, for secrets and config maps
+	Reference string
+	// Masked is true when Value hides a secret value
+	Masked bool
+	// Missing explains why the value could not be resolved, e.g. an unknown secret or key
+	Missing string
+}
+
+// EffectiveEnvironment is the resolved env of a microservice, in spec order
+type EffectiveEnvironment struct {
+	Microservice string
+	Env          []EffectiveEnv
+}
+
+// Missing returns the variables that could not be resolved
+func (environment *EffectiveEnvironment) Missing() (missing []EffectiveEnv) {
+	for _, env := range environment.Env {
+		if env.Missing != "" {
+			missing = append(missing, env)
+		}
+	}
+	return missing
+}
+
+// Validate returns a *ValidationError listing the variables that could not be resolved, with the path of their reference
+func (environment *EffectiveEnvironment) Validate() error {
+	v := new(validator)
+	for idx, env := range environment.Env {
+		if env.Missing == "" {
+			continue
+		}
+		field := "valueFromSecret"
+		if env.Source == EnvFromConfigMap {
+			field = "valueFromConfigMap"
+		}
+		v.add(fmt.Sprintf("container.env[%d].%s", idx, field), "%s", env.Missing)
+	}
+	return v.result()
+}
+
+// String returns one KEY=value line per variable, unresolved variables are flagged
+func (environment *EffectiveEnvironment) String() string {
+	var builder strings.Builder
+	for _, env := range environment.Env {
+		switch {
+		case env.Missing != "":
+			fmt.Fprintf(&builder, "%s: <missing: %s>\n", env.Key, env.Missing)
+		case env.Reference != "":
+			fmt.Fprintf(&builder, "%s=%s (from %s %s)\n", env.Key, env.Value, env.Source, env.Reference)
+		default:
+			fmt.Fprintf(&builder, "%s=%s\n", env.Key, env.Value)
+		}
+	}
+	return builder.String()
+}
+
+// envResolver reads secrets and config maps once, a nil map is missing
+type envResolver struct {
+	getSecret    func(name string) (map[string]string, error)
+	getConfigMap func(name string) (map[string]string, error)
+	secrets      map[string]map[string]string
+	configMaps   map[string]map[string]string
+}
+
+func newEnvResolver(clt *client.Client) *envResolver {
+	return &envResolver{
+		getSecret: func(name string) (map[string]string, error) {
+			secret, err := clt.GetSecret(name)
+			if err != nil {
+				return nil, err
+			}
+			return secret.Data, nil
+		},
+		getConfigMap: func(name string) (map[string]string, error) {
+			configMap, err := clt.GetConfigMap(name)
+			if err != nil {
+				return nil, err
+			}
+			return configMap.Data, nil
+		},
+	}
+}
+
+// lookupData returns the data of a secret or config map from the cache, fetching it on first use
+func lookupData(cache *map[string]map[string]string, get func(string) (map[string]string, error), name string) (data map[string]string, found bool, err error) {
+	if *cache == nil {
+		*cache = make(map[string]map[string]string)
+	}
+	if data, cached := (*cache)[name]; cached {
+		return data, data != nil, nil
+	}
+	data, err = get(name)
+	if err != nil {
+		if _, notFound := err.(*client.NotFoundError); !notFound {
+			return nil, false, err
+		}
+		data = nil
+	} else if data == nil {
+		data = map[string]string{}
+	}
+	(*cache)[name] = data
+	return data, data != nil, nil
+}
+
+// splitReference splits a <name>/<key> reference
+func splitReference(reference string) (name, key string, ok bool) {
+	idx := strings.Index(reference, "/")
+	if idx <= 0 || idx == len(reference)-1 {
+		return "", "", false
+	}
+	return reference[:idx], reference[idx+1:], true
+}
+
+// resolve returns the effective env of a microservice spec
+func (resolver *envResolver) resolve(msvc *Microservice) (*EffectiveEnvironment, error) {
+	environment := &EffectiveEnvironment{Microservice: msvc.Name}
+	if msvc.Container.Env == nil {
+		return environment, nil
+	}
+	for _, variable := range *msvc.Container.Env {
+		env := EffectiveEnv{Key: variable.Key, Value: variable.Value, Source: EnvFromValue}
+		var (
+			kind  = "secret"
+			cache = &resolver.secrets
+			get   = resolver.getSecret
+		)
+		switch {
+		case variable.ValueFromSecret != "":
+			env.Source = EnvFromSecret
+			env.Reference = variable.ValueFromSecret
+		case variable.ValueFromConfigMap != "":
+			env.Source = EnvFromConfigMap
+			env.Reference = variable.ValueFromConfigMap
+			kind, cache, get = "config map", &resolver.configMaps, resolver.getConfigMap
+		default:
+			environment.Env = append(environment.Env, env)
+			continue
+		}
+
+		name, key, ok := splitReference(env.Reference)
+		if !ok {
+			env.Missing = fmt.Sprintf("invalid %s reference %s, expected <name>/<key>", kind, env.Reference)
+			environment.Env = append(environment.Env, env)
+			continue
+		}
+		data, found, err := lookupData(cache, get, name)
+		if err != nil {
+			return nil, err
+		}
+		value, hasKey := data[key]
+		switch {
+		case !found:
+			env.Missing = fmt.Sprintf("%s %s does not exist", kind, name)
+		case !hasKey:
+			env.Missing = fmt.Sprintf("%s %s has no key %s", kind, name, key)
+		case env.Source == EnvFromSecret:
+			env.Value = maskedValue
+			env.Masked = true
+		default:
+			env.Value = value
+		}
+		environment.Env = append(environment.Env, env)
+	}
+	return environment, nil
+}
+
+// resolveEnvironment returns the effective env of a microservice spec, reading its secrets and config maps from the Controller
+func resolveEnvironment(clt *client.Client, msvc *Microservice) (*EffectiveEnvironment, error) {
+	return newEnvResolver(clt).resolve(msvc)
+}
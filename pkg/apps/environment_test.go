@@ -0,0 +1,82 @@
+/*
+ *  *******************************************************************************
+ *  * Copyright (c) 2024 Datasance Teknoloji A.S.
+ *  *
+ *  * This program and the accompanying materials are made available under the
+ *  * terms of the Eclipse Public License v. 2.0 which is available at
+ *  * http://www.eclipse.org/legal/epl-2.0
+ *  *
+ *  * SPDX-License-Identifier: EPL-2.0
+ *  *******************************************************************************
+ *
+ */
+
+package apps
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/datasance/iofog-go-sdk/v3/pkg/client"
+)
+
+func fakeEnvResolver(secrets, configMaps map[string]map[string]string, calls *int) *envResolver {
+	get := func(store map[string]map[string]string) func(string) (map[string]string, error) {
+		return func(name string) (map[string]string, error) {
+			*calls++
+			data, found := store[name]
+			if !found {
+				return nil, client.NewNotFoundError(name)
+			}
+			return data, nil
+		}
+	}
+	return &envResolver{getSecret: get(secrets), getConfigMap: get(configMaps)}
+}
+
+func TestResolveEnvironment(t *testing.T) {
+	calls := 0
+	resolver := fakeEnvResolver(
+		map[string]map[string]string{"db": {"password": "s3cret"}},
+		map[string]map[string]string{"settings": {"level": "debug"}},
+		&calls,
+	)
+	msvc := &Microservice{Name: "filter"}
+	msvc.Container.Env = &[]MicroserviceEnvironment{
+		{Key: "PLAIN", Value: "1"},
+		{Key: "PASSWORD", ValueFromSecret: "db/password"},
+		{Key: "USER", ValueFromSecret: "db/user"},
+		{Key: "LEVEL", ValueFromConfigMap: "settings/level"},
+		{Key: "TOKEN", ValueFromSecret: "missing/token"},
+		{Key: "OTHER", ValueFromSecret: "missing/other"},
+		{Key: "BAD", ValueFromConfigMap: "settings"},
+	}
+	environment, err := resolver.resolve(msvc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []EffectiveEnv{
+		{Key: "PLAIN", Value: "1", Source: EnvFromValue},
+		{Key: "PASSWORD", Value: maskedValue, Source: EnvFromSecret, Reference: "db/password", Masked: true},
+		{Key: "USER", Source: EnvFromSecret, Reference: "db/user", Missing: "secret db has no key user"},
+		{Key: "LEVEL", Value: "debug", Source: EnvFromConfigMap, Reference: "settings/level"},
+		{Key: "TOKEN", Source: EnvFromSecret, Reference: "missing/token", Missing: "secret missing does not exist"},
+		{Key: "OTHER", Source: EnvFromSecret, Reference: "missing/other", Missing: "secret missing does not exist"},
+		{Key: "BAD", Source: EnvFromConfigMap, Reference: "settings", Missing: "invalid config map reference settings, expected <name>/<key>"},
+	}
+	if !reflect.DeepEqual(environment.Env, expected) {
+		t.Errorf("expected %+v, got %+v", expected, environment.Env)
+	}
+	// Secrets and config maps are fetched once
+	if calls != 3 {
+		t.Errorf("expected 3 lookups, got %d", calls)
+	}
+	if len(environment.Missing()) != 4 {
+		t.Errorf("expected 4 missing variables, got %+v", environment.Missing())
+	}
+
+	validationErr, ok := environment.Validate().(*ValidationError)
+	if !ok || len(validationErr.Errors) != 4 || validationErr.Errors[3].Path != "container.env[6].valueFromConfigMap" {
+		t.Errorf("unexpected validation error %v", environment.Validate())
+	}
+}
@@ -0,0 +1,65 @@
+/*
+ *  *******************************************************************************
+ *  * Copyright (c) 2024 Datasance Teknoloji A.S.
+ *  *
+ *  * This program and the accompanying materials are made available under the
+ *  * terms of the Eclipse Public License v. 2.0 which is available at
+ *  * http://www.eclipse.org/legal/epl-2.0
+ *  *
+ *  * SPDX-License-Identifier: EPL-2.0
+ *  *******************************************************************************
+ *
+ */
+
+package apps
+
+import (
+	"github.com/datasance/iofog-go-sdk/v3/pkg/client"
+)
+
+// exportApplication reads a deployed application and its microservices back into an application spec
+func exportApplication(clt *client.Client, name string) (*Application, error) {
+	info, err := clt.GetApplicationByName(name)
+	if err != nil {
+		return nil, err
+	}
+	msvcs, err := clt.GetMicroservicesByApplication(name)
+	if err != nil {
+		return nil, err
+	}
+	agentNames, err := agentNamesByUUID(clt)
+	if err != nil {
+		return nil, err
+	}
+	return applicationFromInfo(info, msvcs.Microservices, agentNames)
+}
+
+// applicationFromInfo converts a deployed application into an application spec without server managed fields
+func applicationFromInfo(info *client.ApplicationInfo, msvcs []client.MicroserviceInfo, agentNames map[string]string) (*Application, error) {
+	app := &Application{
+		Name: info.Name,
+	}
+	for idx := range msvcs {
+		msvc, err := microserviceFromInfo(&msvcs[idx], agentNames)
+		if err != nil {
+			return nil, err
+		}
+		app.Microservices = append(app.Microservices, msvc)
+	}
+	for idx := range info.Routes {
+		app.Routes = append(app.Routes, routeFromInfo(&info.Routes[idx]))
+	}
+	return app, nil
+}
+
+// ApplicationDocument wraps an application spec in a kind: Application document, ready to be encoded by a Scheme
+func ApplicationDocument(app *Application) *Header {
+	return &Header{
+		APIVersion: APIVersion,
+		Kind:       ApplicationKind,
+		Metadata: HeaderMetadata{
+			Name: app.Name,
+		},
+		Spec: app,
+	}
+}
@@ -0,0 +1,76 @@
+/*
+ *  *******************************************************************************
+ *  * Copyright (c) 2024 Datasance Teknoloji A.S.
+ *  *
+ *  * This program and the accompanying materials are made available under the
+ *  * terms of the Eclipse Public License v. 2.0 which is available at
+ *  * http://www.eclipse.org/legal/epl-2.0
+ *  *
+ *  * SPDX-License-Identifier: EPL-2.0
+ *  *******************************************************************************
+ *
+ */
+
+package apps
+
+import (
+	"testing"
+
+	"github.com/datasance/iofog-go-sdk/v3/pkg/client"
+)
+
+func TestExportRoundTrip(t *testing.T) {
+	interval := int64(30)
+	info := &client.ApplicationInfo{
+		Name:        "app",
+		ID:          4,
+		IsActivated: true,
+		Routes:      []client.Route{{Name: "route", Application: "app", From: "sensor", To: "viewer"}},
+	}
+	msvcs := []client.MicroserviceInfo{
+		{
+			UUID:        "uuid-1",
+			Name:        "sensor",
+			AgentUUID:   "agent-uuid",
+			Config:      `{"interval":{"seconds":5}}`,
+			Annotations: `{}`,
+			Images:      []client.CatalogImage{{ContainerImage: "sensor:x86", AgentTypeID: 1}, {ContainerImage: "sensor:arm", AgentTypeID: 2}},
+			RegistryID:  1,
+			Ports:       []client.MicroservicePortMappingInfo{{Internal: 80, External: 8080, Protocol: "tcp"}},
+			Volumes:     []client.MicroserviceVolumeMappingInfo{{HostDestination: "/data", ContainerDestination: "/data", AccessMode: "rw"}},
+			Env:         []client.MicroserviceEnvironmentInfo{{Key: "TOKEN", ValueFromSecret: "creds/token"}},
+			PubTags:     []string{"readings"},
+			HealthCheck: client.MicroserviceHealthCheck{Test: []string{"CMD", "true"}, Interval: &interval},
+			Status:      client.MicroserviceStatusInfo{Status: "RUNNING"},
+		},
+		{UUID: "uuid-2", Name: "viewer", AgentUUID: "agent-uuid", CatalogItemID: 12, SubTags: []string{"readings"}},
+	}
+	agentNames := map[string]string{"agent-uuid": "agent"}
+
+	app, err := applicationFromInfo(info, msvcs, agentNames)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if app.ID != 0 || app.Microservices[0].UUID != "" || app.Microservices[0].Status.Status != "" {
+		t.Error("Expected server managed fields to be stripped")
+	}
+	if app.Microservices[1].Images.CatalogID != 12 {
+		t.Errorf("Expected catalog item to be exported, got %v", app.Microservices[1].Images)
+	}
+
+	data, err := DefaultScheme.Encode(ApplicationDocument(app))
+	if err != nil {
+		t.Fatal(err)
+	}
+	header, err := DefaultScheme.Decode(data)
+	if err != nil {
+		t.Fatalf("%s\n%s", err.Error(), string(data))
+	}
+	plan, err := diffApplication(header.Spec.(*Application), "app", info, msvcs, agentNames)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.HasChanges() {
+		t.Errorf("Expected exported application to match the deployed one:\n%s", plan.String())
+	}
+}
@@ -0,0 +1,248 @@
+/*
+ *  *******************************************************************************
+ *  * Copyright (c) 2024 Datasance Teknoloji A.S.
+ *  *
+ *  * This program and the accompanying materials are made available under the
+ *  * terms of the Eclipse Public License v. 2.0 which is available at
+ *  * http://www.eclipse.org/legal/epl-2.0
+ *  *
+ *  * SPDX-License-Identifier: EPL-2.0
+ *  *******************************************************************************
+ *
+ */
+
+package apps
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// JSONSchemaDraft is the JSON Schema dialect of the generated schemas
+const JSONSchemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// Prefixes of the references between generated schemas
+const (
+	jsonSchemaRefPrefix = "#/$defs/"
+	openAPIRefPrefix    = "#/components/schemas/"
+)
+
+// JSONSchema is the subset of JSON Schema, also valid as an OpenAPI v3 schema object, used to describe manifests
+type JSONSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Ref                  string                 `json:"$ref,omitempty"`
+	Type                 string                 `json:"type,omitempty"`
+	Format               string                 `json:"format,omitempty"`
+	Pattern              string                 `json:"pattern,omitempty"`
+	Enum                 []interface{}          `json:"enum,omitempty"`
+	Minimum              *float64               `json:"minimum,omitempty"`
+	Maximum              *float64               `json:"maximum,omitempty"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AdditionalProperties interface{}            `json:"additionalProperties,omitempty"`
+	Items                *JSONSchema            `json:"items,omitempty"`
+	Defs                 map[string]*JSONSchema `json:"$defs,omitempty"`
+}
+
+// schemaEnums lists the values of enum-like fields, keyed by schema name and property
+// They accept what Validate accepts, e.g. an empty access mode defaulting to rw
+var schemaEnums = map[string][]interface{}{
+	"AgentConfiguration.routerMode":        {"edge", "interior", "none"},
+	"client.RouterConfig.routerMode":       {"edge", "interior", "none"},
+	"MicroserviceVolumeMapping.accessMode": {"", "rw", "ro"},
+}
+
+// schemaPatterns lists the patterns of fields Validate matches case-insensitively, keyed by schema name and property
+// JSON Schema patterns have no case-insensitive flag, hence the character classes
+var schemaPatterns = map[string]string{
+	"MicroservicePortMapping.protocol": "^([tT][cC][pP]|[uU][dD][pP])?$",
+}
+
+// schemaRanges lists the bounds of numeric fields, keyed by schema name and property
+var schemaRanges = map[string][2]float64{
+	"MicroservicePortMapping.internal": {1, 65535},
+	"MicroservicePortMapping.external": {1, 65535},
+	"Service.targetPort":               {1, 65535},
+	"Service.servicePort":              {1, 65535},
+}
+
+// Kinds returns the sorted kinds registered for the API version
+func (scheme *Scheme) Kinds(apiVersion string) (kinds []Kind) {
+	for key := range scheme.types {
+		if key.apiVersion == apiVersion {
+			kinds = append(kinds, key.kind)
+		}
+	}
+	sort.Slice(kinds, func(i, j int) bool { return kinds[i] < kinds[j] })
+	return kinds
+}
+
+// JSONSchema returns the JSON Schema of documents of the kind, generated from the Go type of their spec
+func (scheme *Scheme) JSONSchema(apiVersion string, kind Kind) (*JSONSchema, error) {
+	specType, found := scheme.types[schemeKey{apiVersion: apiVersion, kind: kind}]
+	if !found {
+		return nil, NewNotFoundError(fmt.Sprintf("No type registered for kind %s in %s", kind, apiVersion))
+	}
+	gen := newSchemaGenerator(jsonSchemaRefPrefix)
+	document := gen.document(apiVersion, kind, specType)
+	document.Schema = JSONSchemaDraft
+	document.Defs = gen.defs
+	return document, nil
+}
+
+// OpenAPISchemas returns the schemas of every kind of the API version, ready to be used as the components.schemas of an OpenAPI v3 document
+// Documents are named after their kind with a Document suffix
+func (scheme *Scheme) OpenAPISchemas(apiVersion string) map[string]*JSONSchema {
+	gen := newSchemaGenerator(openAPIRefPrefix)
+	schemas := make(map[string]*JSONSchema)
+	for _, kind := range scheme.Kinds(apiVersion) {
+		specType := scheme.types[schemeKey{apiVersion: apiVersion, kind: kind}]
+		schemas[string(kind)+"Document"] = gen.document(apiVersion, kind, specType)
+	}
+	for name, def := range gen.defs {
+		schemas[name] = def
+	}
+	return schemas
+}
+
+type schemaGenerator struct {
+	refPrefix string
+	defs      map[string]*JSONSchema
+}
+
+func newSchemaGenerator(refPrefix string) *schemaGenerator {
+	return &schemaGenerator{
+		refPrefix: refPrefix,
+		defs:      make(map[string]*JSONSchema),
+	}
+}
+
+// document returns the schema of a document, its spec referencing the schema of the spec type
+func (gen *schemaGenerator) document(apiVersion string, kind Kind, specType reflect.Type) *JSONSchema {
+	return &JSONSchema{
+		Title: string(kind),
+		Type:  "object",
+		Properties: map[string]*JSONSchema{
+			"apiVersion": {Type: "string", Enum: []interface{}{apiVersion}},
+			"kind":       {Type: "string", Enum: []interface{}{string(kind)}},
+			"metadata": {
+				Type: "object",
+				Properties: map[string]*JSONSchema{
+					"name":      {Type: "string"},
+					"namespace": {Type: "string"},
+				},
+				Required:             []string{"name"},
+				AdditionalProperties: false,
+			},
+			"spec": gen.schema(specType),
+		},
+		Required:             []string{"apiVersion", "kind", "metadata", "spec"},
+		AdditionalProperties: false,
+	}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schema returns the schema of a Go type, structs are added to the definitions and referenced
+func (gen *schemaGenerator) schema(t reflect.Type) *JSONSchema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return &JSONSchema{Type: "string", Format: "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return &JSONSchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &JSONSchema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &JSONSchema{Type: "number"}
+	case reflect.String:
+		return &JSONSchema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &JSONSchema{Type: "array", Items: gen.schema(t.Elem())}
+	case reflect.Map:
+		// NestedMap and other free form maps accept any value
+		if t.Elem().Kind() == reflect.Interface {
+			return &JSONSchema{Type: "object", AdditionalProperties: true}
+		}
+		return &JSONSchema{Type: "object", AdditionalProperties: gen.schema(t.Elem())}
+	case reflect.Struct:
+		name := schemaName(t)
+		if _, found := gen.defs[name]; !found {
+			// Register before generating the properties to stop recursive types
+			def := &JSONSchema{Type: "object", Properties: make(map[string]*JSONSchema), AdditionalProperties: false}
+			gen.defs[name] = def
+			gen.properties(def, name, t)
+		}
+		return &JSONSchema{Ref: gen.refPrefix + name}
+	}
+	// Interfaces accept any value
+	return &JSONSchema{}
+}
+
+// properties adds the fields of a struct to its schema, following the YAML encoding of the field
+func (gen *schemaGenerator) properties(def *JSONSchema, name string, t reflect.Type) {
+	for idx := 0; idx < t.NumField(); idx++ {
+		field := t.Field(idx)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+		property, inline := yamlFieldName(field)
+		if property == "-" {
+			continue
+		}
+		if inline {
+			fieldType := field.Type
+			for fieldType.Kind() == reflect.Ptr {
+				fieldType = fieldType.Elem()
+			}
+			gen.properties(def, name, fieldType)
+			continue
+		}
+		schema := gen.schema(field.Type)
+		if values, found := schemaEnums[name+"."+property]; found {
+			schema.Enum = values
+		}
+		if pattern, found := schemaPatterns[name+"."+property]; found {
+			schema.Pattern = pattern
+		}
+		if bounds, found := schemaRanges[name+"."+property]; found {
+			schema.Minimum, schema.Maximum = &bounds[0], &bounds[1]
+		}
+		def.Properties[property] = schema
+	}
+}
+
+// yamlFieldName returns the name of a field in YAML documents, as encoded by gopkg.in/yaml.v2
+func yamlFieldName(field reflect.StructField) (name string, inline bool) {
+	tag := field.Tag.Get("yaml")
+	parts := strings.Split(tag, ",")
+	for _, option := range parts[1:] {
+		if option == "inline" {
+			return "", true
+		}
+	}
+	if parts[0] != "" {
+		return parts[0], false
+	}
+	return strings.ToLower(field.Name), false
+}
+
+// schemaName returns the name of the definition of a struct, qualified with its package outside of pkg/apps
+func schemaName(t reflect.Type) string {
+	if t.PkgPath() == reflect.TypeOf(Header{}).PkgPath() {
+		return t.Name()
+	}
+	pkg := t.PkgPath()
+	if idx := strings.LastIndex(pkg, "/"); idx >= 0 {
+		pkg = pkg[idx+1:]
+	}
+	return pkg + "." + t.Name()
+}
@@ -0,0 +1,93 @@
+/*
+ *  *******************************************************************************
+ *  * Copyright (c) 2024 Datasance Teknoloji A.S.
+ *  *
+ *  * This program and the accompanying materials are made available under the
+ *  * terms of the Eclipse Public License v. 2.0 which is available at
+ *  * http://www.eclipse.org/legal/epl-2.0
+ *  *
+ *  * SPDX-License-Identifier: EPL-2.0
+ *  *******************************************************************************
+ *
+ */
+
+package apps
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"testing"
+)
+
+func TestJSONSchemaReproducible(t *testing.T) {
+	var outputs [][]byte
+	for i := 0; i < 2; i++ {
+		schema, err := DefaultScheme.JSONSchema(APIVersion, ApplicationKind)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := json.Marshal(schema)
+		if err != nil {
+			t.Fatal(err)
+		}
+		outputs = append(outputs, data)
+	}
+	if !bytes.Equal(outputs[0], outputs[1]) {
+		t.Error("Expected schema generation to be reproducible")
+	}
+}
+
+func TestJSONSchemaFields(t *testing.T) {
+	schema, err := DefaultScheme.JSONSchema(APIVersion, AgentConfigKind)
+	if err != nil {
+		t.Fatal(err)
+	}
+	agentConfig := schema.Defs["AgentConfig"]
+	// Fields of the inlined client.AgentConfiguration are properties of AgentConfig
+	for _, property := range []string{"location", "tags", "networkInterface", "routerConfig"} {
+		if _, found := agentConfig.Properties[property]; !found {
+			t.Errorf("Expected AgentConfig to have property %s", property)
+		}
+	}
+	routerMode := schema.Defs["client.RouterConfig"].Properties["routerMode"]
+	if len(routerMode.Enum) != 3 {
+		t.Errorf("Expected routerMode enum, got %v", routerMode.Enum)
+	}
+
+	msvc, err := DefaultScheme.JSONSchema(APIVersion, MicroserviceKind)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msvc.Properties["spec"].Ref != "#/$defs/Microservice" {
+		t.Errorf("Unexpected spec reference %s", msvc.Properties["spec"].Ref)
+	}
+	external := msvc.Defs["MicroservicePortMapping"].Properties["external"]
+	if external.Type != "integer" || external.Maximum == nil || *external.Maximum != 65535 {
+		t.Errorf("Unexpected external port schema: %+v", external)
+	}
+	protocol := regexp.MustCompile(msvc.Defs["MicroservicePortMapping"].Properties["protocol"].Pattern)
+	for value, valid := range map[string]bool{"": true, "tcp": true, "UDP": true, "Tcp": true, "sctp": false} {
+		if protocol.MatchString(value) != valid {
+			t.Errorf("Expected protocol %q to match the schema: %v", value, valid)
+		}
+	}
+	accessMode := msvc.Defs["MicroserviceVolumeMapping"].Properties["accessMode"]
+	for _, value := range []string{"", "rw", "ro"} {
+		found := false
+		for _, allowed := range accessMode.Enum {
+			found = found || allowed == value
+		}
+		if !found {
+			t.Errorf("Expected access mode %q to be allowed by the schema, got %v", value, accessMode.Enum)
+		}
+	}
+	if msvc.Defs["AgentConfiguration"].Properties["routerMode"].Enum == nil {
+		t.Error("Expected routerMode enum on microservice agent configuration")
+	}
+
+	schemas := DefaultScheme.OpenAPISchemas(APIVersion)
+	if schemas["ApplicationDocument"].Properties["spec"].Ref != "#/components/schemas/Application" {
+		t.Errorf("Unexpected OpenAPI reference %s", schemas["ApplicationDocument"].Properties["spec"].Ref)
+	}
+}
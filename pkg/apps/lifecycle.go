@@ -0,0 +1,248 @@
+/*
+ *  *******************************************************************************
+ *  * Copyright (c) 2024 Datasance Teknoloji A.S.
+ *  *
+ *  * This program and the accompanying materials are made available under the
+ *  * terms of the Eclipse Public License v. 2.0 which is available at
+ *  * http://www.eclipse.org/legal/epl-2.0
+ *  *
+ *  * SPDX-License-Identifier: EPL-2.0
+ *  *******************************************************************************
+ *
+ */
+
+package apps
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/datasance/iofog-go-sdk/v3/pkg/client"
+)
+
+// microserviceStopped is the state reported by the Controller for a stopped microservice
+const microserviceStopped = "STOPPED"
+
+// LifecycleOptions controls the deletion, stop and start of applications and microservices
+type LifecycleOptions struct {
+	// Cleanup makes the Agents remove the data of the deleted microservices
+	Cleanup bool
+	// Wait for the operation to complete: deleted resources are gone, started microservices are running and healthy,
+	// stopped microservices are stopped
+	Wait bool
+	// Timeout of the wait, defaults to 5 minutes
+	Timeout time.Duration
+	// Interval between two polls, defaults to 2 seconds
+	Interval time.Duration
+}
+
+type lifecycleExecutor struct {
+	client   *client.Client
+	appName  string
+	name     string
+	opt      LifecycleOptions
+	isSystem bool
+}
+
+func newLifecycleExecutor(clt *client.Client, appName, name string, opt LifecycleOptions) *lifecycleExecutor {
+	if opt.Timeout == 0 {
+		opt.Timeout = defaultRolloutTimeout
+	}
+	if opt.Interval == 0 {
+		opt.Interval = defaultRolloutInterval
+	}
+	exe := &lifecycleExecutor{
+		client:  clt,
+		appName: appName,
+		name:    name,
+		opt:     opt,
+	}
+
+	return exe
+}
+
+// isApplicationNotFound returns whether the Controller reported a missing application
+func isApplicationNotFound(err error) bool {
+	if _, ok := err.(*client.NotFoundError); ok {
+		return true
+	}
+	return err != nil && strings.Contains(err.Error(), "Invalid application id")
+}
+
+// lookupApplication finds whether the application is a regular or a system application
+func (exe *lifecycleExecutor) lookupApplication() error {
+	_, err := exe.client.GetApplicationByName(exe.appName)
+	if err == nil {
+		exe.isSystem = false
+		return nil
+	}
+	if !isApplicationNotFound(err) {
+		return err
+	}
+	// Try system application
+	if _, err = exe.client.GetSystemApplicationByName(exe.appName); err != nil {
+		if isApplicationNotFound(err) {
+			return NewNotFoundError(fmt.Sprintf("Could not find application %s", exe.appName))
+		}
+		return err
+	}
+	exe.isSystem = true
+	return nil
+}
+
+func (exe *lifecycleExecutor) microservices() (*client.MicroserviceListResponse, error) {
+	if exe.isSystem {
+		return exe.client.GetSystemMicroservicesByApplication(exe.appName)
+	}
+	return exe.client.GetMicroservicesByApplication(exe.appName)
+}
+
+func (exe *lifecycleExecutor) deleteApplication() error {
+	if err := exe.lookupApplication(); err != nil {
+		return err
+	}
+	if exe.opt.Cleanup {
+		if exe.isSystem {
+			return NewInputError(fmt.Sprintf("Cannot clean up the microservices of system application %s", exe.appName))
+		}
+		// The Controller only cleans up microservices deleted one by one
+		msvcs, err := exe.microservices()
+		if err != nil {
+			return err
+		}
+		for idx := range msvcs.Microservices {
+			err := exe.client.DeleteMicroserviceWithCleanup(msvcs.Microservices[idx].UUID)
+			if _, notFound := err.(*client.NotFoundError); err != nil && !notFound {
+				return err
+			}
+		}
+	}
+
+	var err error
+	if exe.isSystem {
+		err = exe.client.DeleteSystemApplication(exe.appName)
+	} else {
+		err = exe.client.DeleteApplication(exe.appName)
+	}
+	if err != nil {
+		return err
+	}
+	if !exe.opt.Wait {
+		return nil
+	}
+	return exe.wait(fmt.Sprintf("application %s to be deleted", exe.appName), func() (bool, error) {
+		var err error
+		if exe.isSystem {
+			_, err = exe.client.GetSystemApplicationByName(exe.appName)
+		} else {
+			_, err = exe.client.GetApplicationByName(exe.appName)
+		}
+		if isApplicationNotFound(err) {
+			return true, nil
+		}
+		return false, err
+	})
+}
+
+func (exe *lifecycleExecutor) deleteMicroservice() error {
+	msvc := newMicroserviceExecutor(IofogController{}, nil, exe.appName, exe.name)
+	msvc.client = exe.client
+	if err := msvc.lookup(); err != nil {
+		return err
+	}
+	if msvc.uuid == "" {
+		return NewNotFoundError(fmt.Sprintf("Could not find microservice %s/%s", exe.appName, exe.name))
+	}
+	if msvc.isSystem {
+		return NewInputError(fmt.Sprintf("Microservice %s/%s belongs to a system application, delete the system application instead", exe.appName, exe.name))
+	}
+
+	var err error
+	if exe.opt.Cleanup {
+		err = exe.client.DeleteMicroserviceWithCleanup(msvc.uuid)
+	} else {
+		err = exe.client.DeleteMicroservice(msvc.uuid)
+	}
+	if err != nil {
+		return err
+	}
+	if !exe.opt.Wait {
+		return nil
+	}
+	return exe.wait(fmt.Sprintf("microservice %s/%s to be deleted", exe.appName, exe.name), func() (bool, error) {
+		_, err := exe.client.GetMicroserviceByID(msvc.uuid)
+		if _, notFound := err.(*client.NotFoundError); notFound {
+			return true, nil
+		}
+		return false, err
+	})
+}
+
+func (exe *lifecycleExecutor) setApplicationActive(active bool) error {
+	if err := exe.lookupApplication(); err != nil {
+		return err
+	}
+	action := "stopped"
+	if active {
+		action = "started"
+	}
+	if exe.isSystem {
+		return NewInputError(fmt.Sprintf("System application %s cannot be %s", exe.appName, action))
+	}
+
+	var err error
+	if active {
+		_, err = exe.client.StartApplication(exe.appName)
+	} else {
+		_, err = exe.client.StopApplication(exe.appName)
+	}
+	if err != nil {
+		return err
+	}
+	if !exe.opt.Wait {
+		return nil
+	}
+	return exe.wait(fmt.Sprintf("application %s to be %s", exe.appName, action), func() (bool, error) {
+		msvcs, err := exe.microservices()
+		if err != nil {
+			return false, err
+		}
+		if !active {
+			return stoppedState(msvcs.Microservices), nil
+		}
+		_, ready, failures := rolloutState(msvcs.Microservices)
+		if len(failures) > 0 {
+			return false, NewError(fmt.Sprintf("Application %s failed to start: %s", exe.appName, strings.Join(failures, ", ")))
+		}
+		return ready, nil
+	})
+}
+
+// stoppedState returns whether every microservice is stopped
+func stoppedState(msvcs []client.MicroserviceInfo) bool {
+	for idx := range msvcs {
+		if msvcs[idx].Status.Status != microserviceStopped {
+			return false
+		}
+	}
+	return true
+}
+
+// wait polls until done returns true or an error, or the timeout expires
+func (exe *lifecycleExecutor) wait(what string, done func() (bool, error)) error {
+	deadline := time.Now().Add(exe.opt.Timeout)
+	for {
+		ok, err := done()
+		if err != nil {
+			return err
+		}
+		if ok {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return NewError(fmt.Sprintf("Timed out after %s waiting for %s", exe.opt.Timeout, what))
+		}
+		time.Sleep(exe.opt.Interval)
+	}
+}
@@ -0,0 +1,67 @@
+/*
+ *  *******************************************************************************
+ *  * Copyright (c) 2024 Datasance Teknoloji A.S.
+ *  *
+ *  * This program and the accompanying materials are made available under the
+ *  * terms of the Eclipse Public License v. 2.0 which is available at
+ *  * http://www.eclipse.org/legal/epl-2.0
+ *  *
+ *  * SPDX-License-Identifier: EPL-2.0
+ *  *******************************************************************************
+ *
+ */
+
+package apps
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/datasance/iofog-go-sdk/v3/pkg/client"
+)
+
+func TestStoppedState(t *testing.T) {
+	msvcs := []client.MicroserviceInfo{
+		{Name: "a", Status: client.MicroserviceStatusInfo{Status: microserviceStopped}},
+		{Name: "b", Status: client.MicroserviceStatusInfo{Status: "STOPPING"}},
+	}
+	if stoppedState(msvcs) {
+		t.Error("Expected a stopping microservice to block the stop")
+	}
+	msvcs[1].Status.Status = microserviceStopped
+	if !stoppedState(msvcs) {
+		t.Error("Expected every microservice to be stopped")
+	}
+}
+
+func TestIsApplicationNotFound(t *testing.T) {
+	cases := map[error]bool{
+		client.NewNotFoundError("missing"):                       true,
+		client.NewHTTPError("Invalid application id 'app'", 400): true,
+		client.NewHTTPError("Internal error", 500):               false,
+	}
+	for err, expected := range cases {
+		if isApplicationNotFound(err) != expected {
+			t.Errorf("Expected isApplicationNotFound(%q) to be %v", err.Error(), expected)
+		}
+	}
+	if isApplicationNotFound(nil) {
+		t.Error("Expected no error not to be a missing application")
+	}
+}
+
+func TestLifecycleWait(t *testing.T) {
+	exe := newLifecycleExecutor(nil, "app", "", LifecycleOptions{Timeout: 20 * time.Millisecond, Interval: time.Millisecond})
+	polls := 0
+	if err := exe.wait("done", func() (bool, error) { polls++; return polls == 3, nil }); err != nil || polls != 3 {
+		t.Errorf("Expected the wait to end on the third poll, got %d polls and %v", polls, err)
+	}
+	if err := exe.wait("never", func() (bool, error) { return false, nil }); err == nil {
+		t.Error("Expected the wait to time out")
+	}
+	failure := errors.New("failure")
+	if err := exe.wait("failure", func() (bool, error) { return false, failure }); err != failure {
+		t.Errorf("Expected the poll error, got %v", err)
+	}
+}
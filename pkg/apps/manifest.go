@@ -0,0 +1,467 @@
+/*
+ *  *******************************************************************************
+ *  * Copyright (c) 2024 Datasance Teknoloji A.S.
+ *  *
+ *  * This program and the accompanying materials are made available under the
+ *  * terms of the Eclipse Public License v. 2.0 which is available at
+ *  * http://www.eclipse.org/legal/epl-2.0
+ *  *
+ *  * SPDX-License-Identifier: EPL-2.0
+ *  *******************************************************************************
+ *
+ */
+
+package apps
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/datasance/iofog-go-sdk/v3/pkg/client"
+)
+
+// applyOrder defines the order in which kinds are applied, so that every resource is applied after the resources it depends on
+var applyOrder = map[Kind]int{
+	RegistryKind:             0,
+	CatalogItemKind:          1,
+	SecretKind:               2,
+	ConfigMapKind:            3,
+	CertificateAuthorityKind: 4,
+	CertificateKind:          5,
+	VolumeMountKind:          6,
+	EdgeResourceKind:         7,
+	AgentConfigKind:          8,
+	ApplicationTemplateKind:  9,
+	ApplicationKind:          10,
+	MicroserviceKind:         11,
+	RouteKind:                12,
+	ServiceKind:              13,
+}
+
+// ApplyResult is the outcome of applying a single resource of a manifest
+type ApplyResult struct {
+	Kind   Kind
+	Name   string
+	Action client.ApplyAction
+	Err    error
+}
+
+// ApplyReport contains the outcome of every resource of a manifest, in the order they were applied
+type ApplyReport struct {
+	Results []ApplyResult
+}
+
+// Failed returns the results of the resources that could not be applied
+func (report *ApplyReport) Failed() (failed []ApplyResult) {
+	for _, result := range report.Results {
+		if result.Err != nil {
+			failed = append(failed, result)
+		}
+	}
+	return
+}
+
+// String returns one line per applied resource
+func (report *ApplyReport) String() string {
+	var builder strings.Builder
+	for _, result := range report.Results {
+		if result.Err != nil {
+			fmt.Fprintf(&builder, "%s/%s: Failed: %s\n", result.Kind, result.Name, result.Err.Error())
+			continue
+		}
+		fmt.Fprintf(&builder, "%s/%s: %s\n", result.Kind, result.Name, result.Action)
+	}
+	return builder.String()
+}
+
+type manifestExecutor struct {
+	controller  IofogController
+	manifest    io.Reader
+	headers     []Header
+	client      *client.Client
+	registryIDs map[string]int
+}
+
+func newManifestExecutor(controller IofogController, manifest io.Reader) *manifestExecutor {
+	exe := &manifestExecutor{
+		controller:  controller,
+		manifest:    manifest,
+		registryIDs: make(map[string]int),
+	}
+
+	return exe
+}
+
+func newDocumentsExecutor(clt *client.Client, documents []Header) *manifestExecutor {
+	exe := &manifestExecutor{
+		client:      clt,
+		headers:     append([]Header{}, documents...),
+		registryIDs: make(map[string]int),
+	}
+
+	return exe
+}
+
+func (exe *manifestExecutor) execute() (report *ApplyReport, err error) {
+	report = new(ApplyReport)
+
+	// Read every document before touching the Controller
+	if exe.headers, err = DefaultScheme.DecodeAll(exe.manifest); err != nil {
+		return report, err
+	}
+
+	// Init remote resources
+	if err = exe.init(); err != nil {
+		return report, err
+	}
+
+	return exe.run()
+}
+
+// run applies the decoded documents in dependency order
+func (exe *manifestExecutor) run() (report *ApplyReport, err error) {
+	report = new(ApplyReport)
+	sortHeaders(exe.headers)
+
+	for idx := range exe.headers {
+		header := &exe.headers[idx]
+		action, err := exe.apply(header)
+		report.Results = append(report.Results, ApplyResult{
+			Kind:   header.Kind,
+			Name:   header.Metadata.Name,
+			Action: action,
+			Err:    err,
+		})
+		// Stop at the first failure, later resources may depend on this one
+		if err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+func (exe *manifestExecutor) init() (err error) {
+	exe.client, err = clientOrLogin(exe.client, exe.controller)
+	return err
+}
+
+func (exe *manifestExecutor) apply(header *Header) (client.ApplyAction, error) {
+	name := header.Metadata.Name
+	if name == "" {
+		return "", NewInputError(fmt.Sprintf("Missing metadata name for resource of kind %s", header.Kind))
+	}
+	switch spec := header.Spec.(type) {
+	case *Secret:
+		return exe.client.ApplySecret(&client.SecretCreateRequest{
+			Name: name,
+			Type: spec.Type,
+			Data: spec.Data,
+		})
+	case *ConfigMap:
+		return exe.client.ApplyConfigMap(&client.ConfigMapCreateRequest{
+			Name:      name,
+			Data:      spec.Data,
+			Immutable: spec.Immutable,
+		})
+	case *VolumeMount:
+		return exe.client.ApplyVolumeMount(&client.VolumeMountCreateRequest{
+			Name:          name,
+			SecretName:    spec.SecretName,
+			ConfigMapName: spec.ConfigMapName,
+		})
+	case *CertificateAuthority:
+		return exe.client.ApplyCA(&client.CACreateRequest{
+			Name:       name,
+			Subject:    spec.Subject,
+			Expiration: spec.Expiration,
+			Type:       spec.Type,
+			SecretName: spec.SecretName,
+		})
+	case *Certificate:
+		return exe.client.ApplyCertificate(&client.CertificateCreateRequest{
+			Name:       name,
+			Subject:    spec.Subject,
+			Hosts:      spec.Hosts,
+			Expiration: spec.Expiration,
+			CA: client.CertificateCreateCA{
+				Type:       spec.CA.Type,
+				SecretName: spec.CA.SecretName,
+			},
+		})
+	case *Service:
+		return exe.client.ApplyService(&client.ServiceCreateRequest{
+			Name:          name,
+			Type:          spec.Type,
+			Resource:      spec.Resource,
+			TargetPort:    spec.TargetPort,
+			ServicePort:   spec.ServicePort,
+			K8sType:       spec.K8sType,
+			DefaultBridge: spec.DefaultBridge,
+			Tags:          spec.Tags,
+		})
+	case *Registry:
+		return exe.applyRegistry(name, spec)
+	case *CatalogItem:
+		return exe.applyCatalogItem(name, spec)
+	case *EdgeResource:
+		return exe.applyEdgeResource(name, spec)
+	case *AgentConfig:
+		return exe.applyAgentConfig(name, spec)
+	case *Route:
+		return exe.applyRoute(name, spec)
+	case *ApplicationTemplate:
+		return exe.applyApplicationTemplate(name, spec)
+	case *Application:
+		return exe.applyApplication(name, spec)
+	case *Microservice:
+		return exe.applyMicroservice(name, spec)
+	default:
+		return "", NewInputError(fmt.Sprintf("Unsupported kind %s for resource %s", header.Kind, name))
+	}
+}
+
+func (exe *manifestExecutor) applyRegistry(name string, spec *Registry) (client.ApplyAction, error) {
+	id, action, err := exe.client.ApplyRegistry(&client.RegistryCreateRequest{
+		URL:          spec.URL,
+		IsPublic:     spec.IsPublic,
+		Certificate:  spec.Certificate,
+		RequiresCert: spec.RequiresCert,
+		Username:     spec.Username,
+		Email:        spec.Email,
+		Password:     spec.Password,
+	})
+	if err != nil {
+		return "", err
+	}
+	// Catalog items of the same manifest can reference the registry by name or URL
+	exe.registryIDs[name] = id
+	exe.registryIDs[spec.URL] = id
+	return action, nil
+}
+
+func (exe *manifestExecutor) applyCatalogItem(name string, spec *CatalogItem) (client.ApplyAction, error) {
+	registryID, err := exe.resolveRegistryID(spec.Registry)
+	if err != nil {
+		return "", err
+	}
+	request := &client.CatalogItemCreateRequest{
+		Name:        name,
+		Description: spec.Description,
+		RegistryID:  registryID,
+	}
+	if spec.X86 != "" {
+		request.Images = append(request.Images, client.CatalogImage{ContainerImage: spec.X86, AgentTypeID: client.AgentTypeAgentTypeIDDict["x86"]})
+	}
+	if spec.ARM != "" {
+		request.Images = append(request.Images, client.CatalogImage{ContainerImage: spec.ARM, AgentTypeID: client.AgentTypeAgentTypeIDDict["arm"]})
+	}
+	_, action, err := exe.client.ApplyCatalogItem(request)
+	return action, err
+}
+
+func (exe *manifestExecutor) resolveRegistryID(registry string) (int, error) {
+	if registry == "" {
+		return client.RegistryTypeRegistryTypeIDDict["remote"], nil
+	}
+	if id, found := client.RegistryTypeRegistryTypeIDDict[registry]; found {
+		return id, nil
+	}
+	if id, found := exe.registryIDs[registry]; found {
+		return id, nil
+	}
+	id, err := strconv.Atoi(registry)
+	if err != nil {
+		return 0, NewInputError(fmt.Sprintf("Unknown registry %s", registry))
+	}
+	return id, nil
+}
+
+func (exe *manifestExecutor) applyEdgeResource(name string, spec *EdgeResource) (client.ApplyAction, error) {
+	request := &client.EdgeResourceMetadata{
+		Name:              name,
+		Description:       spec.Description,
+		Version:           spec.Version,
+		InterfaceProtocol: spec.InterfaceProtocol,
+		OrchestrationTags: spec.OrchestrationTags,
+	}
+	if spec.Display != nil {
+		request.Display = &client.EdgeResourceDisplay{
+			Name:  spec.Display.Name,
+			Icon:  spec.Display.Icon,
+			Color: spec.Display.Color,
+		}
+	}
+	for _, endpoint := range spec.Interface.Endpoints {
+		request.Interface.Endpoints = append(request.Interface.Endpoints, client.HTTPEndpoint{
+			Name:   endpoint.Name,
+			Method: endpoint.Method,
+			URL:    endpoint.URL,
+		})
+	}
+	if spec.Custom != nil {
+		request.Custom = toJSONCompatible(map[string]interface{}(spec.Custom)).(map[string]interface{})
+	}
+	return exe.client.ApplyEdgeResource(request)
+}
+
+func (exe *manifestExecutor) applyAgentConfig(name string, spec *AgentConfig) (client.ApplyAction, error) {
+	agent, err := exe.client.GetAgentByName(name)
+	if err != nil {
+		return "", err
+	}
+	request := &client.AgentUpdateRequest{
+		UUID:               agent.UUID,
+		Name:               agent.Name,
+		Location:           spec.Location,
+		Latitude:           spec.Latitude,
+		Longitude:          spec.Longitude,
+		Description:        spec.Description,
+		Tags:               spec.Tags,
+		AgentConfiguration: spec.AgentConfiguration,
+	}
+	unchanged, err := jsonSubsetEqual(request, agent)
+	if err != nil {
+		return "", err
+	}
+	if unchanged {
+		return client.ApplyUnchanged, nil
+	}
+	if _, err = exe.client.UpdateAgent(request); err != nil {
+		return "", err
+	}
+	return client.ApplyUpdated, nil
+}
+
+func (exe *manifestExecutor) applyRoute(fqName string, spec *Route) (client.ApplyAction, error) {
+	appName, name, err := parseFQName(fqName)
+	if err != nil {
+		return "", err
+	}
+	if appName == "" {
+		return "", NewInputError(fmt.Sprintf("Route %s must be named <application>/<route>", fqName))
+	}
+	return exe.client.ApplyRoute(&client.Route{
+		Name:        name,
+		Application: appName,
+		From:        spec.From,
+		To:          spec.To,
+	})
+}
+
+func (exe *manifestExecutor) applyApplicationTemplate(name string, spec *ApplicationTemplate) (client.ApplyAction, error) {
+	templateExe := newApplicationTemplateExecutor(exe.controller, nil, spec, name)
+	templateExe.client = exe.client
+	existing, err := exe.client.GetApplicationTemplate(name)
+	if _, ok := err.(*client.NotFoundError); err != nil && !ok {
+		return "", err
+	}
+	if existing != nil {
+		changed, err := templateChanged(name, spec, existing)
+		if err != nil {
+			return "", err
+		}
+		if !changed {
+			return client.ApplyUnchanged, nil
+		}
+	}
+	if err = templateExe.deploy(); err != nil {
+		return "", err
+	}
+	if existing == nil {
+		return client.ApplyCreated, nil
+	}
+	return client.ApplyUpdated, nil
+}
+
+func (exe *manifestExecutor) applyApplication(name string, spec *Application) (client.ApplyAction, error) {
+	plan, err := planApplication(exe.client, spec, name)
+	if err != nil {
+		return "", err
+	}
+	if !plan.HasChanges() {
+		return client.ApplyUnchanged, nil
+	}
+	appExe := newApplicationExecutor(exe.controller, spec, name)
+	appExe.client = exe.client
+	if err := appExe.run(); err != nil {
+		return "", err
+	}
+	if plan.Create {
+		return client.ApplyCreated, nil
+	}
+	return client.ApplyUpdated, nil
+}
+
+func (exe *manifestExecutor) applyMicroservice(name string, spec *Microservice) (client.ApplyAction, error) {
+	appName, name, err := ParseFQMsvcName(name)
+	if err != nil {
+		return "", err
+	}
+	msvcExe := newMicroserviceExecutor(exe.controller, spec, appName, name)
+	msvcExe.client = exe.client
+	if err = msvcExe.lookup(); err != nil {
+		return "", err
+	}
+	existing := msvcExe.uuid != ""
+	if existing {
+		deployed, err := exe.deployedMicroservice(msvcExe)
+		if err != nil {
+			return "", err
+		}
+		agentNames, err := agentNamesByUUID(exe.client)
+		if err != nil {
+			return "", err
+		}
+		changed, err := microserviceChanged(deployed, spec, agentNames)
+		if err != nil {
+			return "", err
+		}
+		if !changed {
+			return client.ApplyUnchanged, nil
+		}
+	}
+	if _, err = msvcExe.deploy(); err != nil {
+		return "", err
+	}
+	if existing {
+		return client.ApplyUpdated, nil
+	}
+	return client.ApplyCreated, nil
+}
+
+// deployedMicroservice returns the microservice found by the lookup of the executor
+func (exe *manifestExecutor) deployedMicroservice(msvcExe *microserviceExecutor) (*client.MicroserviceInfo, error) {
+	if msvcExe.isSystem {
+		return exe.client.GetSystemMicroserviceByID(msvcExe.uuid)
+	}
+	return exe.client.GetMicroserviceByID(msvcExe.uuid)
+}
+
+func sortHeaders(headers []Header) {
+	sort.SliceStable(headers, func(i, j int) bool {
+		return kindOrder(headers[i].Kind) < kindOrder(headers[j].Kind)
+	})
+}
+
+func kindOrder(kind Kind) int {
+	if order, found := applyOrder[kind]; found {
+		return order
+	}
+	// Unknown kinds go last, they will be reported as unsupported
+	return len(applyOrder)
+}
+
+func parseFQName(fqName string) (appName, name string, err error) {
+	splittedName := strings.Split(fqName, "/")
+	switch len(splittedName) {
+	case 1:
+		return "", splittedName[0], nil
+	case 2:
+		return splittedName[0], splittedName[1], nil
+	default:
+		return "", "", NewInputError(fmt.Sprintf("Invalid name %s", fqName))
+	}
+}
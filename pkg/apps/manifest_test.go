@@ -0,0 +1,211 @@
+/*
+ *  *******************************************************************************
+ *  * Copyright (c) 2024 Datasance Teknoloji A.S.
+ *  *
+ *  * This program and the accompanying materials are made available under the
+ *  * terms of the Eclipse Public License v. 2.0 which is available at
+ *  * http://www.eclipse.org/legal/epl-2.0
+ *  *
+ *  * SPDX-License-Identifier: EPL-2.0
+ *  *******************************************************************************
+ *
+ */
+
+package apps
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/datasance/iofog-go-sdk/v3/pkg/client"
+)
+
+const testManifest = `apiVersion: datasance.com/v3
+kind: Application
+metadata:
+  name: app
+spec:
+  microservices: []
+---
+apiVersion: datasance.com/v3
+kind: Secret
+metadata:
+  name: creds
+spec:
+  type: Opaque
+  data:
+    user: admin
+---
+---
+apiVersion: datasance.com/v3
+kind: Route
+metadata:
+  name: app/route
+spec:
+  from: first
+  to: second
+---
+apiVersion: datasance.com/v3
+kind: Registry
+metadata:
+  name: private
+spec:
+  url: registry.local
+  isPublic: false
+`
+
+func TestDecodeManifestOrder(t *testing.T) {
+	headers, err := DefaultScheme.DecodeAll(strings.NewReader(testManifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sortHeaders(headers)
+	expected := []Kind{RegistryKind, SecretKind, ApplicationKind, RouteKind}
+	if len(headers) != len(expected) {
+		t.Fatalf("Expected %d documents, got %d", len(expected), len(headers))
+	}
+	for idx, kind := range expected {
+		if headers[idx].Kind != kind {
+			t.Errorf("Expected document %d to be %s, got %s", idx, kind, headers[idx].Kind)
+		}
+	}
+
+	secret, ok := headers[1].Spec.(*Secret)
+	if !ok {
+		t.Fatalf("Expected secret spec, got %T", headers[1].Spec)
+	}
+	if secret.Type != "Opaque" || secret.Data["user"] != "admin" {
+		t.Errorf("Unexpected secret spec: %v", secret)
+	}
+}
+
+func TestApplyRouteRequiresApplication(t *testing.T) {
+	exe := newManifestExecutor(IofogController{}, nil)
+	_, err := exe.applyRoute("/sensor-to-filter", &Route{From: "sensor", To: "filter"})
+	if err == nil || !strings.Contains(err.Error(), "Route /sensor-to-filter must be named") {
+		t.Errorf("expected the route name as written in the error, got %v", err)
+	}
+}
+
+func TestApplyDocuments(t *testing.T) {
+	agents := client.ListAgentsResponse{Agents: []client.AgentInfo{{UUID: "agent-uuid", Name: "edge"}}}
+	web := client.MicroserviceInfo{
+		UUID:      "web-uuid",
+		Name:      "web",
+		AgentUUID: "agent-uuid",
+		Ports:     []client.MicroservicePortMappingInfo{{Internal: 80, External: 8080}},
+	}
+	webSpec := func(external int64) *Microservice {
+		return &Microservice{
+			Name:      "web",
+			Agent:     MicroserviceAgent{Name: "edge"},
+			Container: MicroserviceContainer{Ports: []MicroservicePortMapping{{Internal: 80, External: external}}},
+		}
+	}
+	application := func(external int64) *Application {
+		return &Application{Name: "app", Microservices: []Microservice{*webSpec(external)}}
+	}
+	deployedApplication := map[string]interface{}{
+		"/application/app":               client.ApplicationInfo{Name: "app", IsActivated: true},
+		"/microservices?application=app": client.MicroserviceListResponse{Microservices: []client.MicroserviceInfo{web}},
+		"/microservices/web-uuid":        web,
+		"/iofog-list":                    agents,
+	}
+	template := func(description string) *ApplicationTemplate {
+		return &ApplicationTemplate{
+			Description: description,
+			Application: &ApplicationTemplateInfo{Microservices: []Microservice{*webSpec(8080)}},
+		}
+	}
+	deployedTemplate := map[string]interface{}{
+		"/applicationTemplate/tmpl": client.ApplicationTemplate{
+			Name:        "tmpl",
+			Description: "web server",
+			Application: &client.ApplicationTemplateInfo{
+				Microservices: []interface{}{map[string]interface{}{
+					"name":      "web",
+					"agent":     map[string]interface{}{"name": "edge"},
+					"container": map[string]interface{}{"ports": []interface{}{map[string]interface{}{"internal": 80, "external": 8080}}},
+				}},
+			},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		resources map[string]interface{}
+		document  Header
+		expected  client.ApplyAction
+		requests  []string
+	}{
+		{
+			name:      "application unchanged",
+			resources: deployedApplication,
+			document:  Header{Kind: ApplicationKind, Metadata: HeaderMetadata{Name: "app"}, Spec: application(8080)},
+			expected:  client.ApplyUnchanged,
+		},
+		{
+			name:      "application updated",
+			resources: deployedApplication,
+			document:  Header{Kind: ApplicationKind, Metadata: HeaderMetadata{Name: "app"}, Spec: application(8081)},
+			expected:  client.ApplyUpdated,
+			requests:  []string{"PUT /application/yaml/app", "PATCH /application/app"},
+		},
+		{
+			name:      "microservice unchanged",
+			resources: deployedApplication,
+			document:  Header{Kind: MicroserviceKind, Metadata: HeaderMetadata{Name: "app/web"}, Spec: webSpec(8080)},
+			expected:  client.ApplyUnchanged,
+		},
+		{
+			name:      "microservice updated",
+			resources: deployedApplication,
+			document:  Header{Kind: MicroserviceKind, Metadata: HeaderMetadata{Name: "app/web"}, Spec: webSpec(8081)},
+			expected:  client.ApplyUpdated,
+			requests:  []string{"PATCH /microservices/yaml/web-uuid"},
+		},
+		{
+			name: "microservice created",
+			resources: map[string]interface{}{
+				"/microservices?application=app": client.MicroserviceListResponse{Microservices: []client.MicroserviceInfo{{UUID: "db-uuid", Name: "db"}}},
+				"POST /microservices/yaml":       map[string]string{"uuid": "web-uuid"},
+				"/microservices/web-uuid":        web,
+			},
+			document: Header{Kind: MicroserviceKind, Metadata: HeaderMetadata{Name: "app/web"}, Spec: webSpec(8080)},
+			expected: client.ApplyCreated,
+			requests: []string{"POST /microservices/yaml"},
+		},
+		{
+			name:      "template unchanged",
+			resources: deployedTemplate,
+			document:  Header{Kind: ApplicationTemplateKind, Metadata: HeaderMetadata{Name: "tmpl"}, Spec: template("web server")},
+			expected:  client.ApplyUnchanged,
+		},
+		{
+			name:      "template updated",
+			resources: deployedTemplate,
+			document:  Header{Kind: ApplicationTemplateKind, Metadata: HeaderMetadata{Name: "tmpl"}, Spec: template("static web server")},
+			expected:  client.ApplyUpdated,
+			requests:  []string{"PUT /applicationTemplate/yaml/tmpl"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			clt, ctrl := newFakeController(t, test.resources)
+			report, err := ApplyDocuments(clt, []Header{test.document})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(report.Results) != 1 {
+				t.Fatalf("Expected 1 result, got %v", report.Results)
+			}
+			if result := report.Results[0]; result.Err != nil || result.Action != test.expected {
+				t.Errorf("Expected %s, got %s (%v)", test.expected, result.Action, result.Err)
+			}
+			if !reflect.DeepEqual(ctrl.requests, test.requests) {
+				t.Errorf("Expected requests %v, got %v", test.requests, ctrl.requests)
+			}
+		})
+	}
+}
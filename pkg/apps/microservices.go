@@ -16,7 +16,6 @@ package apps
 import (
 	"bytes"
 	"fmt"
-	"net/url"
 	"strings"
 
 	"github.com/datasance/iofog-go-sdk/v3/pkg/client"
@@ -87,18 +86,14 @@ func (exe *microserviceExecutor) execute() error {
 }
 
 func (exe *microserviceExecutor) init() (err error) {
-	baseURL, err := url.Parse(exe.controller.Endpoint)
-	if err != nil {
-		return fmt.Errorf(errParseControllerURL, err.Error())
-	}
-	if exe.controller.Token != "" {
-		exe.client, err = client.NewWithToken(client.Options{BaseURL: baseURL}, exe.controller.Token)
-	} else {
-		exe.client, err = client.SessionLogin(client.Options{BaseURL: baseURL}, exe.controller.RefreshToken, exe.controller.Email, exe.controller.Password)
-	}
-	if err != nil {
+	if exe.client, err = clientOrLogin(exe.client, exe.controller); err != nil {
 		return err
 	}
+	return exe.lookup()
+}
+
+// lookup finds the application of the microservice and the UUID of the microservice if it already exists
+func (exe *microserviceExecutor) lookup() (err error) {
 	if exe.appName == "" {
 		return NewInputError(fmt.Sprintf("Application name missing for microservice %s", exe.name))
 	}
@@ -156,7 +151,7 @@ func (exe *microserviceExecutor) create() (newMsvc *client.MicroserviceInfo, err
 		return nil, fmt.Errorf("cannot create system microservice")
 	}
 	file := IofogHeader{
-		APIVersion: "datasance.com/v3",
+		APIVersion: APIVersion,
 		Kind:       MicroserviceKind,
 		Metadata: HeaderMetadata{
 			Name: strings.Join([]string{exe.appName, exe.name}, "/"),
@@ -172,7 +167,7 @@ func (exe *microserviceExecutor) create() (newMsvc *client.MicroserviceInfo, err
 
 func (exe *microserviceExecutor) update() (newMsvc *client.MicroserviceInfo, err error) {
 	file := IofogHeader{
-		APIVersion: "datasance.com/v3",
+		APIVersion: APIVersion,
 		Kind:       MicroserviceKind,
 		Metadata: HeaderMetadata{
 			Name: strings.Join([]string{exe.appName, exe.name}, "/"),
@@ -0,0 +1,284 @@
+/*
+ *  *******************************************************************************
+ *  * Copyright (c) 2024 Datasance Teknoloji A.S.
+ *  *
+ *  * This program and the accompanying materials are made available under the
+ *  * terms of the Eclipse Public License v. 2.0 which is available at
+ *  * http://www.eclipse.org/legal/epl-2.0
+ *  *
+ *  * SPDX-License-Identifier: EPL-2.0
+ *  *******************************************************************************
+ *
+ */
+
+package apps
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// PatchDirective is the key of a list element of a patch carrying a directive, e.g. $patch: delete
+const PatchDirective = "$patch"
+
+// PatchDelete removes the element of the base list with the same merge key
+const PatchDelete = "delete"
+
+// mergeKeys lists the fields identifying the elements of the lists of an application, keyed by list name
+// Lists without merge keys are replaced by the patch
+var mergeKeys = map[string][]string{
+	"microservices": {"name"},
+	"routes":        {"name"},
+	"env":           {"key"},
+	"ports":         {"internal", "protocol"},
+	"volumes":       {"containerDestination"},
+	"extraHosts":    {"name"},
+}
+
+// defaultMergeKeyValues are the values assumed for merge key fields a list element does not set
+var defaultMergeKeyValues = map[string]string{
+	"protocol": "tcp",
+}
+
+// MergeApplication overlays patches on a base application with strategic merge semantics, in order
+// Maps, including NestedMap configs, are merged recursively and a null value removes a key. Microservices and routes are merged by name,
+// env variables by key, ports by internal port and protocol, volumes by container destination and extra hosts by name.
+// Other lists are replaced. A list element with $patch: delete removes the element with the same merge key.
+// Patches are usually partial specs decoded from YAML: Application and Microservice patches only set their non-zero fields.
+// The merged application can be deployed with DeployApplication or compared with PlanApplication
+func MergeApplication(base interface{}, patches ...interface{}) (*Application, error) {
+	merged, err := mergeOverlay(base, patches)
+	if err != nil {
+		return nil, err
+	}
+	app := new(Application)
+	if err := decodeOverlay(merged, app); err != nil {
+		return nil, err
+	}
+	return app, nil
+}
+
+// MergeMicroservice overlays patches on a base microservice, with the semantics of MergeApplication
+func MergeMicroservice(base interface{}, patches ...interface{}) (*Microservice, error) {
+	merged, err := mergeOverlay(base, patches)
+	if err != nil {
+		return nil, err
+	}
+	msvc := new(Microservice)
+	if err := decodeOverlay(merged, msvc); err != nil {
+		return nil, err
+	}
+	return msvc, nil
+}
+
+func mergeOverlay(base interface{}, patches []interface{}) (interface{}, error) {
+	merged, err := overlayTree(base, false)
+	if err != nil {
+		return nil, err
+	}
+	for idx, patch := range patches {
+		tree, err := overlayTree(patch, true)
+		if err != nil {
+			return nil, err
+		}
+		if merged, err = mergeTree(merged, tree, ""); err != nil {
+			return nil, NewInputError(fmt.Sprintf("Could not apply patch %d: %s", idx, err.Error()))
+		}
+	}
+	return merged, nil
+}
+
+// overlayTree returns the generic YAML tree of a spec, without the zero fields of typed patches
+func overlayTree(spec interface{}, patch bool) (interface{}, error) {
+	data, err := yaml.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+	var tree interface{}
+	if err = yaml.Unmarshal(data, &tree); err != nil {
+		return nil, err
+	}
+	value := reflect.ValueOf(spec)
+	for value.Kind() == reflect.Ptr {
+		value = value.Elem()
+	}
+	if patch && value.Kind() == reflect.Struct {
+		tree = pruneZero(tree)
+	}
+	return tree, nil
+}
+
+func decodeOverlay(tree interface{}, out interface{}) error {
+	data, err := yaml.Marshal(tree)
+	if err != nil {
+		return err
+	}
+	if err = yaml.UnmarshalStrict(data, out); err != nil {
+		return NewInputError(fmt.Sprintf("Could not read merged spec: %s", err.Error()))
+	}
+	return nil
+}
+
+// mergeTree merges a patch into a base tree, name is the field holding them
+func mergeTree(base, patch interface{}, name string) (interface{}, error) {
+	switch patchValue := patch.(type) {
+	case map[interface{}]interface{}:
+		// Maps missing from the base are merged into an empty map, to check the lists they hold
+		baseMap, _ := base.(map[interface{}]interface{})
+		out := make(map[interface{}]interface{}, len(baseMap))
+		for key, value := range baseMap {
+			out[key] = value
+		}
+		for key, value := range patchValue {
+			if key == PatchDirective {
+				continue
+			}
+			if value == nil {
+				delete(out, key)
+				continue
+			}
+			merged, err := mergeTree(out[key], value, fmt.Sprint(key))
+			if err != nil {
+				return nil, err
+			}
+			out[key] = merged
+		}
+		return out, nil
+	case []interface{}:
+		keys, keyed := mergeKeys[name]
+		if !keyed {
+			return stripDirectives(patchValue), nil
+		}
+		baseList, _ := base.([]interface{})
+		return mergeList(baseList, patchValue, name, keys)
+	}
+	return patch, nil
+}
+
+// mergeList merges the elements of a list by merge key, patch elements without a match are appended
+func mergeList(base, patch []interface{}, name string, keys []string) (interface{}, error) {
+	out := make([]interface{}, len(base))
+	copy(out, base)
+	index := make(map[string]int)
+	for idx, elem := range out {
+		if key, ok := mergeKeyOf(elem, keys); ok {
+			index[key] = idx
+		}
+	}
+	deleted := make(map[int]bool)
+	for _, elem := range patch {
+		key, ok := mergeKeyOf(elem, keys)
+		if !ok {
+			return nil, NewInputError(fmt.Sprintf("Element of %s has no %s", name, strings.Join(keys, " and ")))
+		}
+		idx, found := index[key]
+		if directive, _ := elem.(map[interface{}]interface{})[PatchDirective].(string); directive != "" {
+			if directive != PatchDelete {
+				return nil, NewInputError(fmt.Sprintf("Unsupported %s directive %s on %s %s", PatchDirective, directive, name, key))
+			}
+			if found {
+				deleted[idx] = true
+			}
+			continue
+		}
+		if !found {
+			index[key] = len(out)
+			out = append(out, stripDirectives(elem))
+			continue
+		}
+		merged, err := mergeTree(out[idx], elem, "")
+		if err != nil {
+			return nil, err
+		}
+		out[idx] = merged
+		delete(deleted, idx)
+	}
+
+	result := make([]interface{}, 0, len(out))
+	for idx, elem := range out {
+		if !deleted[idx] {
+			result = append(result, elem)
+		}
+	}
+	return result, nil
+}
+
+// mergeKeyOf returns the merge key of a list element
+func mergeKeyOf(elem interface{}, keys []string) (string, bool) {
+	fields, ok := elem.(map[interface{}]interface{})
+	if !ok {
+		return "", false
+	}
+	values := make([]string, 0, len(keys))
+	for _, key := range keys {
+		value, found := fields[key]
+		if !found || value == nil || value == "" {
+			def, hasDefault := defaultMergeKeyValues[key]
+			if !hasDefault {
+				return "", false
+			}
+			value = def
+		}
+		values = append(values, strings.ToLower(fmt.Sprint(value)))
+	}
+	return strings.Join(values, "/"), true
+}
+
+// stripDirectives returns a copy of the tree without patch directives
+func stripDirectives(tree interface{}) interface{} {
+	switch value := tree.(type) {
+	case map[interface{}]interface{}:
+		out := make(map[interface{}]interface{}, len(value))
+		for key, child := range value {
+			if key == PatchDirective {
+				continue
+			}
+			out[key] = stripDirectives(child)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, 0, len(value))
+		for _, child := range value {
+			out = append(out, stripDirectives(child))
+		}
+		return out
+	}
+	return tree
+}
+
+// pruneZero removes the zero values of a tree, they are the unset fields of a typed patch
+func pruneZero(tree interface{}) interface{} {
+	switch value := tree.(type) {
+	case map[interface{}]interface{}:
+		out := make(map[interface{}]interface{})
+		for key, child := range value {
+			if pruned := pruneZero(child); pruned != nil {
+				out[key] = pruned
+			}
+		}
+		if len(out) == 0 {
+			return nil
+		}
+		return out
+	case []interface{}:
+		if len(value) == 0 {
+			return nil
+		}
+		out := make([]interface{}, 0, len(value))
+		for _, child := range value {
+			if pruned := pruneZero(child); pruned != nil {
+				out = append(out, pruned)
+			}
+		}
+		return out
+	case nil:
+		return nil
+	}
+	if reflect.ValueOf(tree).IsZero() {
+		return nil
+	}
+	return tree
+}
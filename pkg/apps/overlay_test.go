@@ -0,0 +1,117 @@
+/*
+ *  *******************************************************************************
+ *  * Copyright (c) 2024 Datasance Teknoloji A.S.
+ *  *
+ *  * This program and the accompanying materials are made available under the
+ *  * terms of the Eclipse Public License v. 2.0 which is available at
+ *  * http://www.eclipse.org/legal/epl-2.0
+ *  *
+ *  * SPDX-License-Identifier: EPL-2.0
+ *  *******************************************************************************
+ *
+ */
+
+package apps
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+const overlayPatchYAML = `
+microservices:
+- name: web
+  agent:
+    name: customer-edge
+  container:
+    env:
+    - key: LOG_LEVEL
+      value: debug
+    - key: TOKEN
+      $patch: delete
+    ports:
+    - internal: 80
+      external: 9090
+    - internal: 53
+      protocol: udp
+      external: 5353
+  config:
+    db:
+      host: db.customer
+    cache: null
+- name: metrics
+  agent:
+    name: customer-edge
+  images:
+    x86: metrics:latest
+`
+
+func TestMergeApplication(t *testing.T) {
+	memory := int64(256)
+	base := &Application{
+		Name: "my-app",
+		Microservices: []Microservice{
+			{
+				Name:  "web",
+				Agent: MicroserviceAgent{Name: "dev-edge"},
+				Container: MicroserviceContainer{
+					Env:         &[]MicroserviceEnvironment{{Key: "LOG_LEVEL", Value: "info"}, {Key: "TOKEN", ValueFromSecret: "creds/token"}},
+					Ports:       []MicroservicePortMapping{{Internal: 80, External: 8080}},
+					MemoryLimit: &memory,
+				},
+				Config: NestedMap{"db": map[string]interface{}{"host": "db.dev", "port": 5432}, "cache": true},
+			},
+		},
+		Routes: []Route{{Name: "web-to-db", From: "web", To: "db"}},
+	}
+	patch := make(map[string]interface{})
+	if err := yaml.Unmarshal([]byte(overlayPatchYAML), &patch); err != nil {
+		t.Fatal(err)
+	}
+
+	app, err := MergeApplication(base, patch, &Application{Name: "customer-app"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if app.Name != "customer-app" || len(app.Microservices) != 2 || len(app.Routes) != 1 {
+		t.Fatalf("Unexpected merged application %+v", app)
+	}
+	web := app.Microservices[0]
+	if web.Agent.Name != "customer-edge" || web.Container.MemoryLimit == nil || *web.Container.MemoryLimit != 256 {
+		t.Errorf("Expected the agent to be patched and the memory limit kept, got %+v", web)
+	}
+	if env := *web.Container.Env; len(env) != 1 || env[0].Value != "debug" {
+		t.Errorf("Expected LOG_LEVEL to be patched and TOKEN deleted, got %v", env)
+	}
+	if ports := web.Container.Ports; len(ports) != 2 || ports[0].External != 9090 || ports[1].Protocol != "udp" {
+		t.Errorf("Expected ports to be merged by internal port and protocol, got %v", ports)
+	}
+	db, _ := web.Config["db"].(map[interface{}]interface{})
+	if db["host"] != "db.customer" || db["port"] != 5432 {
+		t.Errorf("Expected the config to be deep merged, got %v", web.Config)
+	}
+	if _, found := web.Config["cache"]; found {
+		t.Error("Expected a null value to remove the config key")
+	}
+	if app.Microservices[1].Name != "metrics" || app.Microservices[1].Images.X86 != "metrics:latest" {
+		t.Errorf("Expected the new microservice to be appended, got %+v", app.Microservices[1])
+	}
+	if base.Microservices[0].Agent.Name != "dev-edge" {
+		t.Error("Expected the base to be left untouched")
+	}
+}
+
+func TestMergeMicroserviceErrors(t *testing.T) {
+	base := &Microservice{Name: "web"}
+	patches := []map[string]interface{}{
+		{"container": map[string]interface{}{"env": []interface{}{map[string]interface{}{"value": "no key"}}}},
+		{"container": map[string]interface{}{"env": []interface{}{map[string]interface{}{"key": "A", PatchDirective: "replace"}}}},
+		{"contianer": map[string]interface{}{}},
+	}
+	for idx, patch := range patches {
+		if _, err := MergeMicroservice(base, patch); err == nil {
+			t.Errorf("Expected patch %d to fail", idx)
+		}
+	}
+}
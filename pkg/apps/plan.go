@@ -0,0 +1,454 @@
+/*
+ *  *******************************************************************************
+ *  * Copyright (c) 2024 Datasance Teknoloji A.S.
+ *  *
+ *  * This program and the accompanying materials are made available under the
+ *  * terms of the Eclipse Public License v. 2.0 which is available at
+ *  * http://www.eclipse.org/legal/epl-2.0
+ *  *
+ *  * SPDX-License-Identifier: EPL-2.0
+ *  *******************************************************************************
+ *
+ */
+
+package apps
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/datasance/iofog-go-sdk/v3/pkg/client"
+)
+
+// ChangeAction describes how a field of an application changes
+type ChangeAction string
+
+const (
+	ChangeAdd    ChangeAction = "Add"
+	ChangeUpdate ChangeAction = "Update"
+	ChangeRemove ChangeAction = "Remove"
+)
+
+var changeSymbols = map[ChangeAction]string{
+	ChangeAdd:    "+",
+	ChangeUpdate: "~",
+	ChangeRemove: "-",
+}
+
+// Change is a difference between the deployed and the desired value of a field
+// Path uses the YAML field names, list items are identified by their key, e.g. microservices[msvc].container.ports[80]
+type Change struct {
+	Path   string
+	Action ChangeAction
+	Old    interface{}
+	New    interface{}
+}
+
+// ApplicationPlan describes what deploying an application would change on the Controller
+type ApplicationPlan struct {
+	Name    string
+	Create  bool
+	Changes []Change
+}
+
+// HasChanges returns true if deploying the application would change anything
+func (plan *ApplicationPlan) HasChanges() bool {
+	return plan.Create || len(plan.Changes) > 0
+}
+
+// String returns a human readable description of the plan
+func (plan *ApplicationPlan) String() string {
+	var builder strings.Builder
+	switch {
+	case plan.Create:
+		fmt.Fprintf(&builder, "Application %s will be created\n", plan.Name)
+	case len(plan.Changes) > 0:
+		fmt.Fprintf(&builder, "Application %s will be updated\n", plan.Name)
+	default:
+		fmt.Fprintf(&builder, "Application %s is up to date\n", plan.Name)
+	}
+	for _, change := range plan.Changes {
+		builder.WriteString("  " + change.String() + "\n")
+	}
+	return builder.String()
+}
+
+// String returns a one line description of the change
+func (change Change) String() string {
+	symbol := changeSymbols[change.Action]
+	switch change.Action {
+	case ChangeAdd:
+		if isLeaf(change.New) {
+			return fmt.Sprintf("%s %s: %s", symbol, change.Path, formatValue(change.New))
+		}
+	case ChangeRemove:
+		if isLeaf(change.Old) {
+			return fmt.Sprintf("%s %s: %s", symbol, change.Path, formatValue(change.Old))
+		}
+	case ChangeUpdate:
+		return fmt.Sprintf("%s %s: %s -> %s", symbol, change.Path, formatValue(change.Old), formatValue(change.New))
+	}
+	return fmt.Sprintf("%s %s", symbol, change.Path)
+}
+
+// planApplication fetches the deployed state of an application and compares it to the desired spec
+func planApplication(clt *client.Client, desired *Application, name string) (*ApplicationPlan, error) {
+	current, err := clt.GetApplicationByName(name)
+	if err != nil {
+		if _, ok := err.(*client.NotFoundError); !ok {
+			return nil, err
+		}
+		return diffApplication(desired, name, nil, nil, nil)
+	}
+	msvcs, err := clt.GetMicroservicesByApplication(name)
+	if err != nil {
+		return nil, err
+	}
+	agentNames, err := agentNamesByUUID(clt)
+	if err != nil {
+		return nil, err
+	}
+	return diffApplication(desired, name, current, msvcs.Microservices, agentNames)
+}
+
+func agentNamesByUUID(clt *client.Client) (map[string]string, error) {
+	agents, err := clt.ListAgents(client.ListAgentsRequest{})
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]string, len(agents.Agents))
+	for idx := range agents.Agents {
+		names[agents.Agents[idx].UUID] = agents.Agents[idx].Name
+	}
+	return names, nil
+}
+
+// diffApplication compares a desired application to the deployed one, current is nil if the application does not exist
+func diffApplication(desired *Application, name string, current *client.ApplicationInfo, msvcs []client.MicroserviceInfo, agentNames map[string]string) (*ApplicationPlan, error) {
+	plan := &ApplicationPlan{Name: name}
+	if current == nil {
+		plan.Create = true
+	}
+
+	deployed := &Application{Name: name}
+	for idx := range msvcs {
+		msvc, err := microserviceFromInfo(&msvcs[idx], agentNames)
+		if err != nil {
+			return nil, err
+		}
+		deployed.Microservices = append(deployed.Microservices, msvc)
+	}
+	if current != nil {
+		for idx := range current.Routes {
+			deployed.Routes = append(deployed.Routes, routeFromInfo(&current.Routes[idx]))
+		}
+		// Deploying starts the application
+		if !current.IsActivated {
+			plan.Changes = append(plan.Changes, Change{Path: "isActivated", Action: ChangeUpdate, Old: false, New: true})
+		}
+	}
+	// The microservices of an application deployed from a template are rendered by the Controller, the template is
+	// reported as changed rather than compared
+	if desired.Template != nil {
+		action := ChangeUpdate
+		if current == nil {
+			action = ChangeAdd
+		}
+		plan.Changes = append(plan.Changes, Change{Path: "template", Action: action, Old: "deployed microservices", New: desired.Template.Name})
+		return plan, nil
+	}
+	changes, err := diffSpecs(deployed, desired)
+	if err != nil {
+		return nil, err
+	}
+	plan.Changes = append(plan.Changes, changes...)
+	return plan, nil
+}
+
+// diffSpecs compares two application specs, microservices and routes are matched by name
+func diffSpecs(current, desired *Application) ([]Change, error) {
+	var changes []Change
+
+	// Microservices
+	desiredMsvcs := make(map[string]*Microservice)
+	for idx := range desired.Microservices {
+		desiredMsvcs[desired.Microservices[idx].Name] = &desired.Microservices[idx]
+	}
+	currentMsvcs := make(map[string]*Microservice)
+	for idx := range current.Microservices {
+		currentMsvcs[current.Microservices[idx].Name] = &current.Microservices[idx]
+	}
+	for _, msvcName := range unionKeys(desiredMsvcs, currentMsvcs) {
+		path := fmt.Sprintf("microservices[%s]", msvcName)
+		desiredMsvc, inDesired := desiredMsvcs[msvcName]
+		currentMsvc, inCurrent := currentMsvcs[msvcName]
+		switch {
+		case !inCurrent:
+			changes = append(changes, Change{Path: path, Action: ChangeAdd, New: msvcName})
+		case !inDesired:
+			changes = append(changes, Change{Path: path, Action: ChangeRemove, Old: msvcName})
+		default:
+			desiredValue, err := normalizeMicroservice(desiredMsvc)
+			if err != nil {
+				return nil, err
+			}
+			currentValue, err := normalizeMicroservice(currentMsvc)
+			if err != nil {
+				return nil, err
+			}
+			normalizeImages(desiredValue, currentValue)
+			diffGeneric(path, currentValue, desiredValue, &changes)
+		}
+	}
+
+	// Routes
+	desiredRoutes := make(map[string]*Route)
+	for idx := range desired.Routes {
+		desiredRoutes[desired.Routes[idx].Name] = &desired.Routes[idx]
+	}
+	currentRoutes := make(map[string]*Route)
+	for idx := range current.Routes {
+		currentRoutes[current.Routes[idx].Name] = &current.Routes[idx]
+	}
+	for _, routeName := range unionKeys(desiredRoutes, currentRoutes) {
+		path := fmt.Sprintf("routes[%s]", routeName)
+		desiredRoute, inDesired := desiredRoutes[routeName]
+		currentRoute, inCurrent := currentRoutes[routeName]
+		switch {
+		case !inCurrent:
+			changes = append(changes, Change{Path: path, Action: ChangeAdd, New: fmt.Sprintf("%s -> %s", desiredRoute.From, desiredRoute.To)})
+		case !inDesired:
+			changes = append(changes, Change{Path: path, Action: ChangeRemove, Old: fmt.Sprintf("%s -> %s", currentRoute.From, currentRoute.To)})
+		default:
+			if currentRoute.From != desiredRoute.From {
+				changes = append(changes, Change{Path: path + ".from", Action: ChangeUpdate, Old: currentRoute.From, New: desiredRoute.From})
+			}
+			if currentRoute.To != desiredRoute.To {
+				changes = append(changes, Change{Path: path + ".to", Action: ChangeUpdate, Old: currentRoute.To, New: desiredRoute.To})
+			}
+		}
+	}
+	return changes, nil
+}
+
+// listKeys identifies the items of the container lists that are compared item by item
+var listKeys = map[string]func(item map[string]interface{}) string{
+	"ports": func(item map[string]interface{}) string {
+		protocol, _ := item["protocol"].(string)
+		if protocol == "" || strings.EqualFold(protocol, "tcp") {
+			return fmt.Sprint(item["internal"])
+		}
+		return fmt.Sprintf("%v/%s", item["internal"], strings.ToLower(protocol))
+	},
+	"env": func(item map[string]interface{}) string {
+		return fmt.Sprint(item["key"])
+	},
+	"volumes": func(item map[string]interface{}) string {
+		return fmt.Sprint(item["containerDestination"])
+	},
+	"extraHosts": func(item map[string]interface{}) string {
+		return fmt.Sprint(item["name"])
+	},
+}
+
+// microserviceChanged returns whether deploying the spec would change the deployed microservice, the spec may be unnamed
+func microserviceChanged(deployed *client.MicroserviceInfo, spec *Microservice, agentNames map[string]string) (bool, error) {
+	current, err := microserviceFromInfo(deployed, agentNames)
+	if err != nil {
+		return false, err
+	}
+	desired := *spec
+	desired.Name = current.Name
+	changes, err := diffSpecs(&Application{Microservices: []Microservice{current}}, &Application{Microservices: []Microservice{desired}})
+	if err != nil {
+		return false, err
+	}
+	return len(changes) > 0, nil
+}
+
+// normalizeMicroservice returns the comparable fields of a microservice spec as generic values
+func normalizeMicroservice(msvc *Microservice) (map[string]interface{}, error) {
+	copied := *msvc
+	copied.Config = nil
+	copied.Container.Annotations = nil
+	value, err := toJSONMap(copied)
+	if err != nil {
+		return nil, err
+	}
+	if msvc.Config != nil {
+		value["config"] = toJSONCompatible(msvc.Config)
+	}
+	if msvc.Container.Annotations != nil {
+		if container, ok := value["container"].(map[string]interface{}); ok {
+			container["annotations"] = toJSONCompatible(msvc.Container.Annotations)
+		}
+	}
+	// JSON round trip so that numbers of config maps have the same type on both sides
+	if value, err = toJSONMap(value); err != nil {
+		return nil, err
+	}
+
+	// Server managed fields and Agent configuration are not part of the microservice
+	for _, field := range []string{"uuid", "created", "status", "execStatus", "flow", "application", "rebuild"} {
+		delete(value, field)
+	}
+	if agent, ok := value["agent"].(map[string]interface{}); ok {
+		delete(agent, "config")
+	}
+	if msRoutes, ok := value["msRoutes"].(map[string]interface{}); ok {
+		sortStrings(msRoutes, "pubTags")
+		sortStrings(msRoutes, "subTags")
+	}
+	if container, ok := value["container"].(map[string]interface{}); ok {
+		for field, key := range listKeys {
+			items, ok := container[field].([]interface{})
+			if !ok {
+				continue
+			}
+			keyed := make(map[string]interface{}, len(items))
+			for _, item := range items {
+				if itemMap, ok := item.(map[string]interface{}); ok {
+					keyed[fmt.Sprintf("[%s]", key(itemMap))] = itemMap
+				}
+			}
+			container[field] = keyed
+		}
+	}
+	return prune(value).(map[string]interface{}), nil
+}
+
+// normalizeImages ignores image fields the desired spec does not manage
+func normalizeImages(desired, current map[string]interface{}) {
+	desiredImages, _ := desired["images"].(map[string]interface{})
+	currentImages, _ := current["images"].(map[string]interface{})
+	if currentImages == nil {
+		return
+	}
+	if desiredImages == nil {
+		delete(current, "images")
+		return
+	}
+	// Images of a catalog item are managed by the catalog
+	if _, found := desiredImages["catalogId"]; found {
+		delete(currentImages, "x86")
+		delete(currentImages, "arm")
+	}
+	// The Controller sets the default registry
+	if _, found := desiredImages["registry"]; !found {
+		delete(currentImages, "registry")
+	}
+}
+
+func sortStrings(value map[string]interface{}, field string) {
+	items, ok := value[field].([]interface{})
+	if !ok {
+		return
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return fmt.Sprint(items[i]) < fmt.Sprint(items[j])
+	})
+}
+
+// prune removes zero values so that unset and empty fields compare equal
+func prune(in interface{}) interface{} {
+	switch value := in.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{})
+		for key, val := range value {
+			if pruned := prune(val); pruned != nil {
+				out[key] = pruned
+			}
+		}
+		if len(out) == 0 {
+			return nil
+		}
+		return out
+	case []interface{}:
+		if len(value) == 0 {
+			return nil
+		}
+		out := make([]interface{}, 0, len(value))
+		for _, val := range value {
+			out = append(out, prune(val))
+		}
+		return out
+	case string:
+		if value == "" {
+			return nil
+		}
+	case float64:
+		if value == 0 {
+			return nil
+		}
+	case bool:
+		if !value {
+			return nil
+		}
+	}
+	return in
+}
+
+// diffGeneric appends the differences between two generic values to changes
+func diffGeneric(path string, current, desired interface{}, changes *[]Change) {
+	currentMap, currentIsMap := current.(map[string]interface{})
+	desiredMap, desiredIsMap := desired.(map[string]interface{})
+	if currentIsMap && desiredIsMap {
+		for _, key := range unionKeys(currentMap, desiredMap) {
+			diffGeneric(joinPath(path, key), currentMap[key], desiredMap[key], changes)
+		}
+		return
+	}
+	switch {
+	case current == nil && desired == nil:
+	case current == nil:
+		*changes = append(*changes, Change{Path: path, Action: ChangeAdd, New: desired})
+	case desired == nil:
+		*changes = append(*changes, Change{Path: path, Action: ChangeRemove, Old: current})
+	case !reflect.DeepEqual(current, desired):
+		*changes = append(*changes, Change{Path: path, Action: ChangeUpdate, Old: current, New: desired})
+	}
+}
+
+func joinPath(path, key string) string {
+	if path == "" || strings.HasPrefix(key, "[") {
+		return path + key
+	}
+	return path + "." + key
+}
+
+// unionKeys returns the sorted keys of two maps with string keys
+func unionKeys(lhs, rhs interface{}) (keys []string) {
+	found := make(map[string]bool)
+	for _, in := range []interface{}{lhs, rhs} {
+		for _, key := range reflect.ValueOf(in).MapKeys() {
+			if !found[key.String()] {
+				found[key.String()] = true
+				keys = append(keys, key.String())
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func isLeaf(value interface{}) bool {
+	switch value.(type) {
+	case map[string]interface{}, []interface{}:
+		return false
+	}
+	return true
+}
+
+func formatValue(value interface{}) string {
+	switch value.(type) {
+	case map[string]interface{}, []interface{}:
+		data, err := json.Marshal(value)
+		if err == nil {
+			return string(data)
+		}
+	}
+	return fmt.Sprint(value)
+}
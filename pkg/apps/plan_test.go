@@ -0,0 +1,122 @@
+/*
+ *  *******************************************************************************
+ *  * Copyright (c) 2024 Datasance Teknoloji A.S.
+ *  *
+ *  * This program and the accompanying materials are made available under the
+ *  * terms of the Eclipse Public License v. 2.0 which is available at
+ *  * http://www.eclipse.org/legal/epl-2.0
+ *  *
+ *  * SPDX-License-Identifier: EPL-2.0
+ *  *******************************************************************************
+ *
+ */
+
+package apps
+
+import (
+	"testing"
+
+	"github.com/datasance/iofog-go-sdk/v3/pkg/client"
+)
+
+func TestDiffApplication(t *testing.T) {
+	current := &client.ApplicationInfo{
+		Name:        "app",
+		IsActivated: true,
+		Routes: []client.Route{
+			{Name: "old", Application: "app", From: "msvc", To: "gone"},
+			{Name: "kept", Application: "app", From: "msvc", To: "gone"},
+		},
+	}
+	msvcs := []client.MicroserviceInfo{
+		{
+			UUID:       "uuid-1",
+			Name:       "msvc",
+			AgentUUID:  "agent-uuid",
+			Config:     `{"level":"info","retries":3}`,
+			Images:     []client.CatalogImage{{ContainerImage: "nginx:1", AgentTypeID: 1}},
+			RegistryID: 1,
+			Ports:      []client.MicroservicePortMappingInfo{{Internal: 80, External: 8080}},
+			Env:        []client.MicroserviceEnvironmentInfo{{Key: "A", Value: "1"}, {Key: "B", Value: "2"}},
+			Status:     client.MicroserviceStatusInfo{Status: "RUNNING"},
+		},
+		{UUID: "uuid-2", Name: "gone", AgentUUID: "agent-uuid"},
+	}
+	agentNames := map[string]string{"agent-uuid": "agent"}
+
+	desired := &Application{
+		Name: "app",
+		Microservices: []Microservice{
+			{
+				Name:   "msvc",
+				Agent:  MicroserviceAgent{Name: "agent"},
+				Images: &MicroserviceImages{X86: "nginx:2"},
+				Config: NestedMap{"level": "info", "retries": 3},
+				Container: MicroserviceContainer{
+					Ports: []MicroservicePortMapping{{Internal: 80, External: 9090}},
+					Env:   &[]MicroserviceEnvironment{{Key: "A", Value: "1"}, {Key: "C", Value: "3"}},
+				},
+			},
+			{Name: "new", Agent: MicroserviceAgent{Name: "agent"}},
+		},
+		Routes: []Route{{Name: "kept", From: "msvc", To: "new"}},
+	}
+
+	plan, err := diffApplication(desired, "app", current, msvcs, agentNames)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Create {
+		t.Error("Expected an update plan")
+	}
+	expected := map[string]ChangeAction{
+		"microservices[gone]":                              ChangeRemove,
+		"microservices[msvc].container.env[B]":             ChangeRemove,
+		"microservices[msvc].container.env[C]":             ChangeAdd,
+		"microservices[msvc].container.ports[80].external": ChangeUpdate,
+		"microservices[msvc].images.x86":                   ChangeUpdate,
+		"microservices[new]":                               ChangeAdd,
+		"routes[kept].to":                                  ChangeUpdate,
+		"routes[old]":                                      ChangeRemove,
+	}
+	if len(plan.Changes) != len(expected) {
+		t.Errorf("Expected %d changes, got %d:\n%s", len(expected), len(plan.Changes), plan.String())
+	}
+	for _, change := range plan.Changes {
+		if action, found := expected[change.Path]; !found || action != change.Action {
+			t.Errorf("Unexpected change %s", change.String())
+		}
+	}
+
+	plan, err = diffApplication(desired, "app", nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !plan.Create || !plan.HasChanges() {
+		t.Error("Expected a create plan")
+	}
+}
+
+func TestDiffApplicationFields(t *testing.T) {
+	msvcs := []client.MicroserviceInfo{{UUID: "uuid-1", Name: "msvc", AgentUUID: "agent-uuid", Config: "{}"}}
+	agentNames := map[string]string{"agent-uuid": "agent"}
+	desired := &Application{Name: "app", Microservices: []Microservice{{Name: "msvc", Agent: MicroserviceAgent{Name: "agent"}}}}
+
+	stopped := &client.ApplicationInfo{Name: "app"}
+	plan, err := diffApplication(desired, "app", stopped, msvcs, agentNames)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Changes) != 1 || plan.Changes[0].String() != "~ isActivated: false -> true" {
+		t.Errorf("Expected the stopped application to be started:\n%s", plan.String())
+	}
+
+	templated := &Application{Name: "app", Template: &ApplicationTemplate{Name: "template"}}
+	plan, err = diffApplication(templated, "app", &client.ApplicationInfo{Name: "app", IsActivated: true}, msvcs, agentNames)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Changes) != 1 || plan.Changes[0].Path != "template" || plan.Changes[0].Action != ChangeUpdate {
+		t.Errorf("Expected the template to be reported instead of removing its microservices:\n%s", plan.String())
+	}
+}
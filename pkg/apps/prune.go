@@ -0,0 +1,328 @@
+/*
+ *  *******************************************************************************
+ *  * Copyright (c) 2024 Datasance Teknoloji A.S.
+ *  *
+ *  * This program and the accompanying materials are made available under the
+ *  * terms of the Eclipse Public License v. 2.0 which is available at
+ *  * http://www.eclipse.org/legal/epl-2.0
+ *  *
+ *  * SPDX-License-Identifier: EPL-2.0
+ *  *******************************************************************************
+ *
+ */
+
+package apps
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/datasance/iofog-go-sdk/v3/pkg/client"
+)
+
+// PruneDeleted is reported for the resources deleted by a prune
+const PruneDeleted client.ApplyAction = "Deleted"
+
+// Name prefix and data key of the config maps tracking the resources owned by a manifest set
+const (
+	pruneTrackingPrefix = "iofog-prune-"
+	pruneTrackingKey    = "resources"
+)
+
+// PruneOptions controls which resources a prune may delete
+type PruneOptions struct {
+	// Set names the group of manifests owning the resources, resources applied under another set are never pruned
+	// It must be a DNS label, the tracking config map is named iofog-prune-<Set>
+	Set string
+	// Kinds allowed to be deleted, owned resources of other kinds are kept and reported as protected
+	Kinds []Kind
+}
+
+// OwnedResource identifies a resource applied by a manifest set
+type OwnedResource struct {
+	Kind Kind   `json:"kind"`
+	Name string `json:"name"`
+	// Version of an Edge Resource
+	Version string `json:"version,omitempty"`
+	// URL of a Registry
+	URL string `json:"url,omitempty"`
+}
+
+func (resource OwnedResource) String() string {
+	if resource.Version != "" {
+		return fmt.Sprintf("%s/%s/%s", resource.Kind, resource.Name, resource.Version)
+	}
+	return fmt.Sprintf("%s/%s", resource.Kind, resource.Name)
+}
+
+// PrunePlan lists the owned resources that are no longer declared
+type PrunePlan struct {
+	// Delete are the resources a prune deletes, in deletion order
+	Delete []OwnedResource
+	// Protected are the resources that are no longer declared but whose kind is not allowed to be pruned
+	Protected []OwnedResource
+}
+
+// String returns one line per resource of the plan
+func (plan *PrunePlan) String() string {
+	var builder strings.Builder
+	for _, resource := range plan.Delete {
+		fmt.Fprintf(&builder, "- %s\n", resource)
+	}
+	for _, resource := range plan.Protected {
+		fmt.Fprintf(&builder, "  %s (protected)\n", resource)
+	}
+	return builder.String()
+}
+
+// ownedResource returns the ownership record of a document
+func ownedResource(header *Header) OwnedResource {
+	resource := OwnedResource{
+		Kind: header.Kind,
+		Name: header.Metadata.Name,
+	}
+	switch spec := header.Spec.(type) {
+	case *EdgeResource:
+		resource.Version = spec.Version
+	case *Registry:
+		resource.URL = spec.URL
+	}
+	return resource
+}
+
+// planPrune returns the owned resources absent from the headers
+// Agent configurations are never pruned since Agents are not created by manifests
+func planPrune(owned []OwnedResource, headers []Header, kinds []Kind) *PrunePlan {
+	declared := make(map[OwnedResource]bool)
+	for idx := range headers {
+		declared[ownedResource(&headers[idx])] = true
+	}
+	allowed := make(map[Kind]bool)
+	for _, kind := range kinds {
+		allowed[kind] = kind != AgentConfigKind
+	}
+
+	plan := new(PrunePlan)
+	for _, resource := range owned {
+		if declared[resource] {
+			continue
+		}
+		if allowed[resource.Kind] {
+			plan.Delete = append(plan.Delete, resource)
+		} else {
+			plan.Protected = append(plan.Protected, resource)
+		}
+	}
+	// Delete dependents before their dependencies
+	sort.SliceStable(plan.Delete, func(i, j int) bool {
+		return kindOrder(plan.Delete[i].Kind) > kindOrder(plan.Delete[j].Kind)
+	})
+	return plan
+}
+
+type pruneExecutor struct {
+	manifest *manifestExecutor
+	opt      PruneOptions
+	owned    []OwnedResource
+}
+
+func newPruneExecutor(controller IofogController, manifest io.Reader, opt PruneOptions) *pruneExecutor {
+	exe := &pruneExecutor{
+		manifest: newManifestExecutor(controller, manifest),
+		opt:      opt,
+	}
+
+	return exe
+}
+
+func (exe *pruneExecutor) init() (err error) {
+	if exe.opt.Set == "" {
+		return NewInputError("A manifest set name is required to prune resources")
+	}
+	if err = validateSetName(exe.opt.Set); err != nil {
+		return err
+	}
+	if exe.manifest.headers, err = DefaultScheme.DecodeAll(exe.manifest.manifest); err != nil {
+		return err
+	}
+	if err = exe.manifest.init(); err != nil {
+		return err
+	}
+	return exe.loadOwned()
+}
+
+// validateSetName checks that the set name makes a valid tracking config map name
+func validateSetName(set string) error {
+	v := new(validator)
+	v.name("set", set)
+	if len(v.errs) == 0 && len(pruneTrackingPrefix+set) > maxDNSLabelLength {
+		v.add("set", "must be at most %d characters", maxDNSLabelLength-len(pruneTrackingPrefix))
+	}
+	if err := v.result(); err != nil {
+		return NewInputError(fmt.Sprintf("Invalid manifest set name: %s", err.Error()))
+	}
+	return nil
+}
+
+func (exe *pruneExecutor) trackingName() string {
+	return pruneTrackingPrefix + exe.opt.Set
+}
+
+// loadOwned reads the resources owned by the set from its tracking config map
+func (exe *pruneExecutor) loadOwned() error {
+	configMap, err := exe.manifest.client.GetConfigMap(exe.trackingName())
+	if err != nil {
+		if _, ok := err.(*client.NotFoundError); ok {
+			return nil
+		}
+		return err
+	}
+	data, found := configMap.Data[pruneTrackingKey]
+	if !found {
+		return nil
+	}
+	if err = json.Unmarshal([]byte(data), &exe.owned); err != nil {
+		return NewInternalError(fmt.Sprintf("Could not read config map %s: %s", exe.trackingName(), err.Error()))
+	}
+	return nil
+}
+
+// saveOwned records the resources owned by the set
+func (exe *pruneExecutor) saveOwned(owned []OwnedResource) error {
+	sort.SliceStable(owned, func(i, j int) bool {
+		return owned[i].String() < owned[j].String()
+	})
+	data, err := json.Marshal(owned)
+	if err != nil {
+		return err
+	}
+	_, err = exe.manifest.client.ApplyConfigMap(&client.ConfigMapCreateRequest{
+		Name: exe.trackingName(),
+		Data: map[string]string{pruneTrackingKey: string(data)},
+	})
+	return err
+}
+
+func (exe *pruneExecutor) plan() (*PrunePlan, error) {
+	if err := exe.init(); err != nil {
+		return nil, err
+	}
+	return planPrune(exe.owned, exe.manifest.headers, exe.opt.Kinds), nil
+}
+
+// execute applies the manifest, deletes the owned resources it no longer declares and records the new ownership
+func (exe *pruneExecutor) execute() (report *ApplyReport, err error) {
+	report = new(ApplyReport)
+	if err = exe.init(); err != nil {
+		return report, err
+	}
+	plan := planPrune(exe.owned, exe.manifest.headers, exe.opt.Kinds)
+
+	// Keep track of everything owned so far, whatever the outcome
+	owned := make(map[OwnedResource]bool)
+	for _, resource := range exe.owned {
+		owned[resource] = true
+	}
+	defer func() {
+		resources := make([]OwnedResource, 0, len(owned))
+		for resource := range owned {
+			resources = append(resources, resource)
+		}
+		if saveErr := exe.saveOwned(resources); saveErr != nil && err == nil {
+			err = saveErr
+		}
+	}()
+
+	applyReport, err := exe.manifest.run()
+	report.Results = append(report.Results, applyReport.Results...)
+	for idx, result := range applyReport.Results {
+		if result.Err == nil {
+			owned[ownedResource(&exe.manifest.headers[idx])] = true
+		}
+	}
+	if err != nil {
+		return report, err
+	}
+
+	for _, resource := range plan.Delete {
+		err = exe.delete(resource)
+		report.Results = append(report.Results, ApplyResult{
+			Kind:   resource.Kind,
+			Name:   resource.Name,
+			Action: PruneDeleted,
+			Err:    err,
+		})
+		if err != nil {
+			return report, err
+		}
+		delete(owned, resource)
+	}
+	return report, nil
+}
+
+func (exe *pruneExecutor) delete(resource OwnedResource) (err error) {
+	clt := exe.manifest.client
+	switch resource.Kind {
+	case SecretKind:
+		err = clt.DeleteSecret(resource.Name)
+	case ConfigMapKind:
+		err = clt.DeleteConfigMap(resource.Name)
+	case VolumeMountKind:
+		err = clt.DeleteVolumeMount(resource.Name)
+	case CertificateAuthorityKind:
+		err = clt.DeleteCA(resource.Name)
+	case CertificateKind:
+		err = clt.DeleteCertificate(resource.Name)
+	case ServiceKind:
+		err = clt.DeleteService(resource.Name)
+	case EdgeResourceKind:
+		err = clt.DeleteEdgeResource(resource.Name, resource.Version)
+	case ApplicationTemplateKind:
+		err = clt.DeleteApplicationTemplate(resource.Name)
+	case ApplicationKind:
+		err = clt.DeleteApplication(resource.Name)
+	case RegistryKind:
+		err = exe.deleteRegistry(resource.URL)
+	case CatalogItemKind:
+		var item *client.CatalogItemInfo
+		if item, err = clt.GetCatalogItemByName(resource.Name); err == nil {
+			err = clt.DeleteCatalogItem(item.ID)
+		}
+	case MicroserviceKind, RouteKind:
+		var appName, name string
+		if appName, name, err = parseFQName(resource.Name); err != nil {
+			return err
+		}
+		if resource.Kind == RouteKind {
+			err = clt.DeleteRoute(appName, name)
+			break
+		}
+		var msvc *client.MicroserviceInfo
+		if msvc, err = clt.GetMicroserviceByName(appName, name); err == nil {
+			err = clt.DeleteMicroservice(msvc.UUID)
+		}
+	default:
+		return NewInputError(fmt.Sprintf("Cannot prune %s resources", resource.Kind))
+	}
+	// Resources deleted by other means are already pruned
+	if _, ok := err.(*client.NotFoundError); ok {
+		return nil
+	}
+	return err
+}
+
+func (exe *pruneExecutor) deleteRegistry(url string) error {
+	registries, err := exe.manifest.client.ListRegistries()
+	if err != nil {
+		return err
+	}
+	for _, registry := range registries.Registries {
+		if registry.URL == url {
+			return exe.manifest.client.DeleteRegistry(registry.ID)
+		}
+	}
+	return nil
+}
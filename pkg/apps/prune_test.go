@@ -0,0 +1,75 @@
+/*
+ *  *******************************************************************************
+ *  * Copyright (c) 2024 Datasance Teknoloji A.S.
+ *  *
+ *  * This program and the accompanying materials are made available under the
+ *  * terms of the Eclipse Public License v. 2.0 which is available at
+ *  * http://www.eclipse.org/legal/epl-2.0
+ *  *
+ *  * SPDX-License-Identifier: EPL-2.0
+ *  *******************************************************************************
+ *
+ */
+
+package apps
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPlanPrune(t *testing.T) {
+	headers, err := DefaultScheme.DecodeAll(strings.NewReader(testManifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	owned := []OwnedResource{
+		{Kind: SecretKind, Name: "creds"},
+		{Kind: SecretKind, Name: "old-creds"},
+		{Kind: ConfigMapKind, Name: "old-config"},
+		{Kind: RegistryKind, Name: "private", URL: "registry.local"},
+		{Kind: RegistryKind, Name: "private", URL: "registry.old"},
+		{Kind: ApplicationKind, Name: "old-app"},
+		{Kind: AgentConfigKind, Name: "agent"},
+	}
+	plan := planPrune(owned, headers, []Kind{SecretKind, RegistryKind, ApplicationKind, AgentConfigKind})
+
+	expected := []string{"Application/old-app", "Secret/old-creds", "Registry/private"}
+	if len(plan.Delete) != len(expected) {
+		t.Fatalf("Expected %v to be deleted, got %v", expected, plan.Delete)
+	}
+	for idx, resource := range plan.Delete {
+		if resource.String() != expected[idx] {
+			t.Errorf("Expected deletion %d to be %s, got %s", idx, expected[idx], resource)
+		}
+	}
+	if plan.Delete[2].URL != "registry.old" {
+		t.Errorf("Expected the registry no longer declared to be deleted, got %s", plan.Delete[2].URL)
+	}
+	if len(plan.Protected) != 2 {
+		t.Errorf("Expected config map and agent config to be protected, got %v", plan.Protected)
+	}
+}
+
+func TestValidateSetName(t *testing.T) {
+	testCases := []struct {
+		set   string
+		valid bool
+	}{
+		{"edge-site", true},
+		{"Edge_Site", false},
+		{"edge/site", false},
+		{"-edge", false},
+		{strings.Repeat("a", maxDNSLabelLength-len(pruneTrackingPrefix)), true},
+		{strings.Repeat("a", maxDNSLabelLength-len(pruneTrackingPrefix)+1), false},
+	}
+	for _, testCase := range testCases {
+		err := validateSetName(testCase.set)
+		if testCase.valid && err != nil {
+			t.Errorf("Expected set %s to be valid, got %s", testCase.set, err.Error())
+		}
+		if _, ok := err.(*InputError); !testCase.valid && !ok {
+			t.Errorf("Expected set %s to be rejected with an input error, got %v", testCase.set, err)
+		}
+	}
+}
@@ -0,0 +1,346 @@
+/*
+ *  *******************************************************************************
+ *  * Copyright (c) 2024 Datasance Teknoloji A.S.
+ *  *
+ *  * This program and the accompanying materials are made available under the
+ *  * terms of the Eclipse Public License v. 2.0 which is available at
+ *  * http://www.eclipse.org/legal/epl-2.0
+ *  *
+ *  * SPDX-License-Identifier: EPL-2.0
+ *  *******************************************************************************
+ *
+ */
+
+package apps
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+
+	"gopkg.in/yaml.v2"
+)
+
+// templateReference matches a reference to a template variable, e.g. {{ agent-name }}
+var templateReference = regexp.MustCompile(`\{\{\s*(.*?)\s*\}\}`)
+
+// templateVariableKey matches the keys of template variables
+var templateVariableKey = regexp.MustCompile(`^[A-Za-z_][-A-Za-z0-9_.]*$`)
+
+var templateInfoType = reflect.TypeOf(ApplicationTemplateInfo{})
+
+// RenderApplicationTemplate renders an application template offline, the way the Controller deploys an application from it
+// The template is an ApplicationTemplate, or its spec decoded from YAML when references replace non-string fields, e.g. a port
+// Values are used first, then the default value of the variables. A string made of a single reference takes the type of the value,
+// references inside a longer string are replaced by the formatted value
+// Errors carry the YAML path of the reference that could not be resolved, or whose value does not fit the field
+func RenderApplicationTemplate(template interface{}, name string, values []TemplateVariable) (*Application, error) {
+	spec, err := decodeTemplate(template)
+	if err != nil {
+		return nil, err
+	}
+	renderer := newTemplateRenderer(spec.variables, values)
+	tree := renderer.render(spec.application, templateInfoType, "")
+	if err := renderer.result(); err != nil {
+		return nil, err
+	}
+
+	data, err := yaml.Marshal(tree)
+	if err != nil {
+		return nil, err
+	}
+	info := ApplicationTemplateInfo{}
+	if err = yaml.Unmarshal(data, &info); err != nil {
+		return nil, NewInputError(fmt.Sprintf("Could not read rendered application %s: %s", name, err.Error()))
+	}
+	return &Application{
+		Name:          name,
+		Microservices: info.Microservices,
+		Routes:        info.Routes,
+	}, nil
+}
+
+// RenderApplication renders an application deployed from a template with the values of its template variables
+func RenderApplication(app *Application, template interface{}) (*Application, error) {
+	if app.Template == nil {
+		return nil, NewInputError(fmt.Sprintf("Application %s is not deployed from a template", app.Name))
+	}
+	return RenderApplicationTemplate(template, app.Name, app.Template.Variables)
+}
+
+type templateSpec struct {
+	variables   []TemplateVariable
+	application interface{}
+}
+
+// decodeTemplate splits a template into its variables and the generic YAML tree of its application
+func decodeTemplate(template interface{}) (*templateSpec, error) {
+	data, err := yaml.Marshal(template)
+	if err != nil {
+		return nil, err
+	}
+	raw := struct {
+		Variables   []TemplateVariable `yaml:"variables"`
+		Application interface{}        `yaml:"application"`
+	}{}
+	if err = yaml.Unmarshal(data, &raw); err != nil {
+		return nil, NewInputError(fmt.Sprintf("Could not read application template spec: %s", err.Error()))
+	}
+	if raw.Application == nil {
+		return nil, NewInputError("Application template has no application")
+	}
+	return &templateSpec{variables: raw.Variables, application: raw.Application}, nil
+}
+
+type templateRenderer struct {
+	v        *validator
+	declared map[string]bool
+	values   map[string]interface{}
+}
+
+// newTemplateRenderer resolves the value of every variable, type-checking supplied values against the default value
+func newTemplateRenderer(variables, values []TemplateVariable) *templateRenderer {
+	renderer := &templateRenderer{
+		v:        new(validator),
+		declared: make(map[string]bool),
+		values:   make(map[string]interface{}),
+	}
+	defaults := make(map[string]interface{})
+	for _, variable := range variables {
+		renderer.declared[variable.Key] = true
+		if variable.DefaultValue != nil && *variable.DefaultValue != nil {
+			defaults[variable.Key] = *variable.DefaultValue
+			renderer.values[variable.Key] = *variable.DefaultValue
+		}
+	}
+	for idx, variable := range values {
+		path := fmt.Sprintf("template.variables[%d]", idx)
+		if !renderer.declared[variable.Key] {
+			renderer.v.add(path+".key", "variable %s is not declared by the template", variable.Key)
+			continue
+		}
+		if variable.Value == nil || *variable.Value == nil {
+			continue
+		}
+		value := *variable.Value
+		if def, found := defaults[variable.Key]; found && valueKind(value) != valueKind(def) {
+			renderer.v.add(path+".value", "%s is a %s, variable %s defaults to a %s", formatVariable(value), valueKind(value), variable.Key, valueKind(def))
+			continue
+		}
+		renderer.values[variable.Key] = value
+	}
+	return renderer
+}
+
+func (renderer *templateRenderer) result() error {
+	return renderer.v.result()
+}
+
+// render returns a copy of the node with the references replaced, t is the Go type of the node or nil when unknown
+func (renderer *templateRenderer) render(node interface{}, t reflect.Type, path string) interface{} {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t != nil && t.Kind() == reflect.Interface {
+		t = nil
+	}
+	switch value := node.(type) {
+	case map[interface{}]interface{}:
+		out := make(map[interface{}]interface{}, len(value))
+		for key, child := range value {
+			keyName := fmt.Sprint(key)
+			out[key] = renderer.render(child, childType(t, keyName), joinPath(path, keyName))
+		}
+		return out
+	case []interface{}:
+		var elemType reflect.Type
+		if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+			elemType = t.Elem()
+		}
+		out := make([]interface{}, len(value))
+		for idx, child := range value {
+			out[idx] = renderer.render(child, elemType, fmt.Sprintf("%s[%d]", path, idx))
+		}
+		return out
+	case string:
+		return renderer.substitute(value, t, path)
+	}
+	return node
+}
+
+// substitute replaces the references of a string
+func (renderer *templateRenderer) substitute(in string, t reflect.Type, path string) interface{} {
+	matches := templateReference.FindAllStringSubmatchIndex(in, -1)
+	if len(matches) == 0 {
+		return in
+	}
+	if len(matches) == 1 && matches[0][0] == 0 && matches[0][1] == len(in) {
+		value, found := renderer.lookup(in[matches[0][2]:matches[0][3]], path)
+		if !found {
+			return in
+		}
+		return renderer.check(value, t, path)
+	}
+
+	resolved := true
+	out := templateReference.ReplaceAllStringFunc(in, func(reference string) string {
+		value, found := renderer.lookup(templateReference.FindStringSubmatch(reference)[1], path)
+		if !found {
+			resolved = false
+			return reference
+		}
+		switch valueKind(value) {
+		case "object", "array":
+			renderer.v.add(path, "%s variable cannot be part of a string", valueKind(value))
+			resolved = false
+			return reference
+		}
+		return formatVariable(value)
+	})
+	if !resolved {
+		return in
+	}
+	return renderer.check(out, t, path)
+}
+
+// lookup returns the value of a reference
+func (renderer *templateRenderer) lookup(expr, path string) (interface{}, bool) {
+	if !templateVariableKey.MatchString(expr) {
+		renderer.v.add(path, "unsupported expression %q, only references to variables are supported", expr)
+		return nil, false
+	}
+	value, found := renderer.values[expr]
+	if found {
+		return value, true
+	}
+	if renderer.declared[expr] {
+		renderer.v.add(path, "variable %s has no value and no default value", expr)
+	} else {
+		renderer.v.add(path, "unresolved reference to variable %s, it is not declared by the template", expr)
+	}
+	return nil, false
+}
+
+// check returns the value converted to the type of the field, or adds an error when it does not fit
+func (renderer *templateRenderer) check(value interface{}, t reflect.Type, path string) interface{} {
+	if t == nil {
+		return value
+	}
+	kind := valueKind(value)
+	expected := ""
+	switch t.Kind() {
+	case reflect.String:
+		if kind == "string" || kind == "number" || kind == "boolean" {
+			return formatVariable(value)
+		}
+		expected = "string"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if number, ok := toFloat(value); ok && number == math.Trunc(number) {
+			return value
+		}
+		expected = "integer"
+	case reflect.Float32, reflect.Float64:
+		if _, ok := toFloat(value); ok {
+			return value
+		}
+		expected = "number"
+	case reflect.Bool:
+		if kind == "boolean" {
+			return value
+		}
+		expected = "boolean"
+	case reflect.Struct, reflect.Map:
+		if kind == "object" {
+			return value
+		}
+		expected = "object"
+	case reflect.Slice, reflect.Array:
+		if kind == "array" {
+			return value
+		}
+		expected = "array"
+	default:
+		return value
+	}
+	renderer.v.add(path, "%s is a %s, expected a %s", formatVariable(value), kind, expected)
+	return value
+}
+
+// childType returns the Go type of the property of a struct or map, nil when unknown
+func childType(t reflect.Type, key string) reflect.Type {
+	if t == nil {
+		return nil
+	}
+	switch t.Kind() {
+	case reflect.Map:
+		return t.Elem()
+	case reflect.Struct:
+		for idx := 0; idx < t.NumField(); idx++ {
+			field := t.Field(idx)
+			if field.PkgPath != "" && !field.Anonymous {
+				continue
+			}
+			name, inline := yamlFieldName(field)
+			if inline {
+				fieldType := field.Type
+				for fieldType.Kind() == reflect.Ptr {
+					fieldType = fieldType.Elem()
+				}
+				if child := childType(fieldType, key); child != nil {
+					return child
+				}
+				continue
+			}
+			if name == key {
+				return field.Type
+			}
+		}
+	}
+	return nil
+}
+
+// valueKind returns the JSON type of a decoded value
+func valueKind(value interface{}) string {
+	if value == nil {
+		return "null"
+	}
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	}
+	return "unknown"
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	if valueKind(value) != "number" {
+		return 0, false
+	}
+	number, err := strconv.ParseFloat(fmt.Sprint(value), 64)
+	return number, err == nil
+}
+
+// formatVariable formats the scalar value of a variable, numbers without exponent
+func formatVariable(value interface{}) string {
+	switch number := value.(type) {
+	case float64:
+		return strconv.FormatFloat(number, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(number), 'f', -1, 32)
+	case string:
+		return number
+	}
+	return fmt.Sprint(value)
+}
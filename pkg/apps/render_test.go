@@ -0,0 +1,154 @@
+/*
+ *  *******************************************************************************
+ *  * Copyright (c) 2024 Datasance Teknoloji A.S.
+ *  *
+ *  * This program and the accompanying materials are made available under the
+ *  * terms of the Eclipse Public License v. 2.0 which is available at
+ *  * http://www.eclipse.org/legal/epl-2.0
+ *  *
+ *  * SPDX-License-Identifier: EPL-2.0
+ *  *******************************************************************************
+ *
+ */
+
+package apps
+
+import (
+	"errors"
+	"sort"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+	"k8s.io/apiextensions-apiserver/pkg/apis/apiextensions"
+)
+
+const renderTemplateYAML = `
+variables:
+- key: agent-name
+  description: Agent running the microservices
+- key: port
+  description: Published port
+  defaultValue: 8080
+- key: debug
+  description: Debug logs
+  defaultValue: false
+application:
+  microservices:
+  - name: web
+    agent:
+      name: "{{ agent-name }}"
+    images:
+      x86: "web:{{port}}"
+    container:
+      ports:
+      - internal: 80
+        external: "{{ port }}"
+      env:
+      - key: DEBUG
+        value: "{{ debug }}"
+    config:
+      debug: "{{ debug }}"
+  routes: []
+`
+
+func templateValue(key string, value interface{}) TemplateVariable {
+	json := apiextensions.JSON(value)
+	return TemplateVariable{Key: key, Value: &json}
+}
+
+func decodeRenderTemplate(t *testing.T, in string) map[string]interface{} {
+	template := make(map[string]interface{})
+	if err := yaml.Unmarshal([]byte(in), &template); err != nil {
+		t.Fatal(err)
+	}
+	return template
+}
+
+func TestRenderApplicationTemplate(t *testing.T) {
+	template := decodeRenderTemplate(t, renderTemplateYAML)
+	app, err := RenderApplicationTemplate(template, "my-app", []TemplateVariable{
+		templateValue("agent-name", "edge-1"),
+		templateValue("debug", true),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	msvc := app.Microservices[0]
+	if app.Name != "my-app" || msvc.Agent.Name != "edge-1" {
+		t.Errorf("Unexpected application %s on agent %s", app.Name, msvc.Agent.Name)
+	}
+	if msvc.Container.Ports[0].External != 8080 {
+		t.Errorf("Expected the default port, got %d", msvc.Container.Ports[0].External)
+	}
+	if msvc.Images.X86 != "web:8080" {
+		t.Errorf("Expected the port to be formatted in the image, got %s", msvc.Images.X86)
+	}
+	if (*msvc.Container.Env)[0].Value != "true" {
+		t.Errorf("Expected the boolean to be formatted in the env value, got %s", (*msvc.Container.Env)[0].Value)
+	}
+	if msvc.Config["debug"] != true {
+		t.Errorf("Expected the boolean to be kept in the config, got %v", msvc.Config["debug"])
+	}
+}
+
+func TestRenderApplicationTemplateErrors(t *testing.T) {
+	template := decodeRenderTemplate(t, renderTemplateYAML)
+	application := template["application"].(map[interface{}]interface{})
+	msvc := application["microservices"].([]interface{})[0].(map[interface{}]interface{})
+	msvc["name"] = "{{ name | upcase }}"
+	application["routes"] = []interface{}{map[interface{}]interface{}{"name": "r", "from": "web", "to": "{{ target }}"}}
+
+	_, err := RenderApplicationTemplate(template, "my-app", []TemplateVariable{
+		templateValue("port", "http"),
+		templateValue("region", "eu"),
+	})
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Expected a validation error, got %v", err)
+	}
+	var paths []string
+	for _, fieldErr := range validationErr.Errors {
+		paths = append(paths, fieldErr.Path)
+	}
+	sort.Strings(paths)
+	expected := []string{
+		"microservices[0].agent.name",
+		"microservices[0].name",
+		"routes[0].to",
+		"template.variables[0].value",
+		"template.variables[1].key",
+	}
+	if len(paths) != len(expected) {
+		t.Fatalf("Expected errors on %v, got %v", expected, validationErr.Errors)
+	}
+	for idx := range expected {
+		if paths[idx] != expected[idx] {
+			t.Errorf("Expected error on %s, got %s", expected[idx], paths[idx])
+		}
+	}
+}
+
+func TestRenderApplicationTemplateFieldType(t *testing.T) {
+	template := decodeRenderTemplate(t, renderTemplateYAML)
+	template["variables"] = []interface{}{
+		map[interface{}]interface{}{"key": "agent-name"},
+		map[interface{}]interface{}{"key": "port"},
+		map[interface{}]interface{}{"key": "debug"},
+	}
+	_, err := RenderApplicationTemplate(template, "my-app", []TemplateVariable{
+		templateValue("agent-name", "edge-1"),
+		templateValue("port", "http"),
+		templateValue("debug", true),
+	})
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || len(validationErr.Errors) != 1 || validationErr.Errors[0].Path != "microservices[0].container.ports[0].external" {
+		t.Fatalf("Expected a type error on the external port, got %v", err)
+	}
+}
+
+func TestRenderApplication(t *testing.T) {
+	app := &Application{Name: "plain"}
+	if _, err := RenderApplication(app, map[string]interface{}{}); err == nil {
+		t.Error("Expected an error for an application without template")
+	}
+}
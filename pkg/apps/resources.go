@@ -0,0 +1,134 @@
+/*
+ *  *******************************************************************************
+ *  * Copyright (c) 2024 Datasance Teknoloji A.S.
+ *  *
+ *  * This program and the accompanying materials are made available under the
+ *  * terms of the Eclipse Public License v. 2.0 which is available at
+ *  * http://www.eclipse.org/legal/epl-2.0
+ *  *
+ *  * SPDX-License-Identifier: EPL-2.0
+ *  *******************************************************************************
+ *
+ */
+
+package apps
+
+import (
+	"github.com/datasance/iofog-go-sdk/v3/pkg/client"
+)
+
+// Available kind of Controller resources
+const (
+	SecretKind               Kind = "Secret"
+	ConfigMapKind            Kind = "ConfigMap"
+	VolumeMountKind          Kind = "VolumeMount"
+	CertificateKind          Kind = "Certificate"
+	CertificateAuthorityKind Kind = "CertificateAuthority"
+	ServiceKind              Kind = "Service"
+	RegistryKind             Kind = "Registry"
+	CatalogItemKind          Kind = "CatalogItem"
+	EdgeResourceKind         Kind = "EdgeResource"
+	AgentConfigKind          Kind = "AgentConfig"
+)
+
+// Secret contains information for configuring a secret
+type Secret struct {
+	Type string            `yaml:"type" json:"type"`
+	Data map[string]string `yaml:"data" json:"data"`
+}
+
+// ConfigMap contains information for configuring a config map
+type ConfigMap struct {
+	Immutable bool              `yaml:"immutable,omitempty" json:"immutable,omitempty"`
+	Data      map[string]string `yaml:"data" json:"data"`
+}
+
+// VolumeMount contains information for configuring a volume mount backed by a secret or a config map
+type VolumeMount struct {
+	SecretName    string `yaml:"secretName,omitempty" json:"secretName,omitempty"`
+	ConfigMapName string `yaml:"configMapName,omitempty" json:"configMapName,omitempty"`
+}
+
+// CertificateAuthority contains information for configuring a CA
+type CertificateAuthority struct {
+	Subject    string `yaml:"subject,omitempty" json:"subject,omitempty"`
+	Expiration int    `yaml:"expiration,omitempty" json:"expiration,omitempty"`
+	Type       string `yaml:"type" json:"type"`
+	SecretName string `yaml:"secretName,omitempty" json:"secretName,omitempty"`
+}
+
+// CertificateCA references the CA signing a certificate
+type CertificateCA struct {
+	Type       string `yaml:"type" json:"type"`
+	SecretName string `yaml:"secretName,omitempty" json:"secretName,omitempty"`
+}
+
+// Certificate contains information for configuring a certificate
+type Certificate struct {
+	Subject    string        `yaml:"subject" json:"subject"`
+	Hosts      string        `yaml:"hosts" json:"hosts"`
+	Expiration int           `yaml:"expiration,omitempty" json:"expiration,omitempty"`
+	CA         CertificateCA `yaml:"ca" json:"ca"`
+}
+
+// Service contains information for configuring a service
+type Service struct {
+	Type          string   `yaml:"type" json:"type"`
+	Resource      string   `yaml:"resource" json:"resource"`
+	TargetPort    int      `yaml:"targetPort" json:"targetPort"`
+	ServicePort   int      `yaml:"servicePort,omitempty" json:"servicePort,omitempty"`
+	K8sType       string   `yaml:"k8sType,omitempty" json:"k8sType,omitempty"`
+	DefaultBridge string   `yaml:"defaultBridge,omitempty" json:"defaultBridge,omitempty"`
+	Tags          []string `yaml:"tags,omitempty" json:"tags,omitempty"`
+}
+
+// Registry contains information for configuring an image registry
+type Registry struct {
+	URL          string `yaml:"url" json:"url"`
+	IsPublic     bool   `yaml:"isPublic" json:"isPublic"`
+	Username     string `yaml:"username,omitempty" json:"username,omitempty"`
+	Password     string `yaml:"password,omitempty" json:"password,omitempty"`
+	Email        string `yaml:"email,omitempty" json:"email,omitempty"`
+	RequiresCert bool   `yaml:"requiresCert,omitempty" json:"requiresCert,omitempty"`
+	Certificate  string `yaml:"certificate,omitempty" json:"certificate,omitempty"`
+}
+
+// EdgeResourceDisplay contains display information of an Edge Resource
+type EdgeResourceDisplay struct {
+	Name  string `yaml:"name,omitempty" json:"name,omitempty"`
+	Icon  string `yaml:"icon,omitempty" json:"icon,omitempty"`
+	Color string `yaml:"color,omitempty" json:"color,omitempty"`
+}
+
+// HTTPEndpoint is an endpoint exposed by an HTTP based Edge Resource
+type HTTPEndpoint struct {
+	Name   string `yaml:"name,omitempty" json:"name,omitempty"`
+	Method string `yaml:"method,omitempty" json:"method,omitempty"`
+	URL    string `yaml:"url,omitempty" json:"url,omitempty"`
+}
+
+// HTTPEdgeResource is the interface of an HTTP based Edge Resource
+type HTTPEdgeResource struct {
+	Endpoints []HTTPEndpoint `yaml:"endpoints,omitempty" json:"endpoints,omitempty"`
+}
+
+// EdgeResource contains information for configuring a version of an Edge Resource
+type EdgeResource struct {
+	Description       string               `yaml:"description,omitempty" json:"description,omitempty"`
+	Version           string               `yaml:"version" json:"version"`
+	InterfaceProtocol string               `yaml:"interfaceProtocol,omitempty" json:"interfaceProtocol,omitempty"`
+	Display           *EdgeResourceDisplay `yaml:"display,omitempty" json:"display,omitempty"`
+	Interface         HTTPEdgeResource     `yaml:"interface,omitempty" json:"interface,omitempty"`
+	OrchestrationTags []string             `yaml:"orchestrationTags,omitempty" json:"orchestrationTags,omitempty"`
+	Custom            NestedMap            `yaml:"custom,omitempty" json:"custom,omitempty"`
+}
+
+// AgentConfig contains the configuration of an existing Agent
+type AgentConfig struct {
+	Location                  string    `yaml:"location,omitempty" json:"location,omitempty"`
+	Latitude                  float64   `yaml:"latitude,omitempty" json:"latitude,omitempty"`
+	Longitude                 float64   `yaml:"longitude,omitempty" json:"longitude,omitempty"`
+	Description               string    `yaml:"description,omitempty" json:"description,omitempty"`
+	Tags                      *[]string `yaml:"tags,omitempty" json:"tags,omitempty"`
+	client.AgentConfiguration `yaml:",inline"`
+}
@@ -0,0 +1,249 @@
+/*
+ *  *******************************************************************************
+ *  * Copyright (c) 2024 Datasance Teknoloji A.S.
+ *  *
+ *  * This program and the accompanying materials are made available under the
+ *  * terms of the Eclipse Public License v. 2.0 which is available at
+ *  * http://www.eclipse.org/legal/epl-2.0
+ *  *
+ *  * SPDX-License-Identifier: EPL-2.0
+ *  *******************************************************************************
+ *
+ */
+
+package apps
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/datasance/iofog-go-sdk/v3/pkg/client"
+	"gopkg.in/yaml.v2"
+)
+
+// Revision history stored on the Controller, in a config map per application
+const (
+	revisionConfigMapPrefix = "iofog-revisions-"
+	revisionKey             = "revisions"
+)
+
+// revisionHistoryLimit is the number of revisions kept per application
+const revisionHistoryLimit = 10
+
+// Revision is a spec of an application as it was applied
+type Revision struct {
+	Number    int          `json:"number"`
+	CreatedAt time.Time    `json:"createdAt"`
+	Hash      string       `json:"hash"`
+	Spec      *Application `json:"spec"`
+}
+
+// RevisionStore persists the revision history of applications, oldest revision first
+// Revisions are only recorded by DeployApplicationWithOptions with DeployOptions.Revisions, RollbackApplication and
+// RecordRevision. Applications deployed by DeployApplication, ApplyManifest or BulkDeploy are not recorded
+type RevisionStore interface {
+	Load(name string) ([]Revision, error)
+	Save(name string, revisions []Revision) error
+}
+
+// ConfigMapRevisionStore keeps the revision history of every application in a config map on the Controller
+type ConfigMapRevisionStore struct {
+	client *client.Client
+}
+
+// NewConfigMapRevisionStore returns a store keeping revisions in config maps named iofog-revisions-<application>
+func NewConfigMapRevisionStore(clt *client.Client) *ConfigMapRevisionStore {
+	return &ConfigMapRevisionStore{client: clt}
+}
+
+// Load reads the revisions of the application, none if it has no history
+func (store *ConfigMapRevisionStore) Load(name string) (revisions []Revision, err error) {
+	configMap, err := store.client.GetConfigMap(revisionConfigMapPrefix + name)
+	if err != nil {
+		if _, ok := err.(*client.NotFoundError); ok {
+			return nil, nil
+		}
+		return nil, err
+	}
+	data, found := configMap.Data[revisionKey]
+	if !found {
+		return nil, nil
+	}
+	if err = json.Unmarshal([]byte(data), &revisions); err != nil {
+		return nil, NewInternalError(fmt.Sprintf("Could not read config map %s: %s", revisionConfigMapPrefix+name, err.Error()))
+	}
+	return revisions, nil
+}
+
+// Save replaces the revisions of the application
+func (store *ConfigMapRevisionStore) Save(name string, revisions []Revision) error {
+	data, err := json.Marshal(revisions)
+	if err != nil {
+		return err
+	}
+	_, err = store.client.ApplyConfigMap(&client.ConfigMapCreateRequest{
+		Name: revisionConfigMapPrefix + name,
+		Data: map[string]string{revisionKey: string(data)},
+	})
+	return err
+}
+
+// FileRevisionStore keeps the revision history of every application in a JSON file of a local directory
+type FileRevisionStore struct {
+	dir string
+}
+
+// NewFileRevisionStore returns a store keeping revisions in <dir>/<application>.json, the directory is created on first save
+func NewFileRevisionStore(dir string) *FileRevisionStore {
+	return &FileRevisionStore{dir: dir}
+}
+
+func (store *FileRevisionStore) path(name string) string {
+	return filepath.Join(store.dir, name+".json")
+}
+
+// Load reads the revisions of the application, none if it has no history
+func (store *FileRevisionStore) Load(name string) (revisions []Revision, err error) {
+	data, err := os.ReadFile(store.path(name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if err = json.Unmarshal(data, &revisions); err != nil {
+		return nil, NewInternalError(fmt.Sprintf("Could not read revision file %s: %s", store.path(name), err.Error()))
+	}
+	return revisions, nil
+}
+
+// Save replaces the revisions of the application
+func (store *FileRevisionStore) Save(name string, revisions []Revision) error {
+	data, err := json.MarshalIndent(revisions, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(store.dir, 0o755); err != nil {
+		return err
+	}
+	// Write then rename, an interrupted save keeps the previous history
+	tmp := store.path(name) + ".tmp"
+	if err = os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, store.path(name))
+}
+
+// specHash returns the content hash of an application spec
+func specHash(app *Application) (string, error) {
+	data, err := yaml.Marshal(app)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// RecordRevision adds the spec to the history of the application, unless it is the latest revision already
+// Only the last 10 revisions are kept
+func RecordRevision(store RevisionStore, app *Application) (*Revision, error) {
+	if app.Name == "" {
+		return nil, NewInputError("Cannot record a revision of an application without name")
+	}
+	revisions, err := store.Load(app.Name)
+	if err != nil {
+		return nil, err
+	}
+	revisions, added, err := appendRevision(revisions, app, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if added {
+		if err = store.Save(app.Name, revisions); err != nil {
+			return nil, err
+		}
+	}
+	latest := revisions[len(revisions)-1]
+	return &latest, nil
+}
+
+// appendRevision returns the history with the spec as latest revision, added is false when it already was
+func appendRevision(revisions []Revision, app *Application, now time.Time) (_ []Revision, added bool, err error) {
+	hash, err := specHash(app)
+	if err != nil {
+		return revisions, false, err
+	}
+	number := 1
+	if len(revisions) > 0 {
+		latest := &revisions[len(revisions)-1]
+		if latest.Hash == hash {
+			return revisions, false, nil
+		}
+		number = latest.Number + 1
+	}
+	revisions = append(revisions, Revision{
+		Number:    number,
+		CreatedAt: now.UTC(),
+		Hash:      hash,
+		Spec:      app.DeepCopy(),
+	})
+	if len(revisions) > revisionHistoryLimit {
+		revisions = revisions[len(revisions)-revisionHistoryLimit:]
+	}
+	return revisions, true, nil
+}
+
+// ListRevisions returns the revisions of the application, oldest first
+func ListRevisions(store RevisionStore, name string) ([]Revision, error) {
+	return store.Load(name)
+}
+
+// findRevision returns the revision with the number
+func findRevision(revisions []Revision, name string, number int) (*Revision, error) {
+	for idx := range revisions {
+		if revisions[idx].Number == number {
+			return &revisions[idx], nil
+		}
+	}
+	return nil, NewNotFoundError(fmt.Sprintf("Could not find revision %d of application %s", number, name))
+}
+
+// DiffRevisions returns the changes between two revisions of the application
+func DiffRevisions(store RevisionStore, name string, from, to int) ([]Change, error) {
+	revisions, err := store.Load(name)
+	if err != nil {
+		return nil, err
+	}
+	fromRevision, err := findRevision(revisions, name, from)
+	if err != nil {
+		return nil, err
+	}
+	toRevision, err := findRevision(revisions, name, to)
+	if err != nil {
+		return nil, err
+	}
+	return diffSpecs(fromRevision.Spec, toRevision.Spec)
+}
+
+// rollbackApplication re-applies a revision of the application and records it as a new revision
+func rollbackApplication(clt *client.Client, store RevisionStore, name string, number int) (*Revision, error) {
+	revisions, err := store.Load(name)
+	if err != nil {
+		return nil, err
+	}
+	revision, err := findRevision(revisions, name, number)
+	if err != nil {
+		return nil, err
+	}
+	exe := newApplicationExecutor(IofogController{}, revision.Spec, name)
+	exe.client = clt
+	if err = exe.run(); err != nil {
+		return nil, err
+	}
+	return RecordRevision(store, revision.Spec)
+}
@@ -0,0 +1,74 @@
+/*
+ *  *******************************************************************************
+ *  * Copyright (c) 2024 Datasance Teknoloji A.S.
+ *  *
+ *  * This program and the accompanying materials are made available under the
+ *  * terms of the Eclipse Public License v. 2.0 which is available at
+ *  * http://www.eclipse.org/legal/epl-2.0
+ *  *
+ *  * SPDX-License-Identifier: EPL-2.0
+ *  *******************************************************************************
+ *
+ */
+
+package apps
+
+import (
+	"fmt"
+	"testing"
+)
+
+func revisionApp(image string) *Application {
+	return &Application{
+		Name: "my-app",
+		Microservices: []Microservice{
+			{Name: "web", Images: &MicroserviceImages{X86: image}},
+		},
+	}
+}
+
+func TestRecordRevision(t *testing.T) {
+	store := NewFileRevisionStore(t.TempDir())
+	first, err := RecordRevision(store, revisionApp("web:1"))
+	if err != nil || first.Number != 1 {
+		t.Fatalf("Expected revision 1, got %v, %v", first, err)
+	}
+	again, err := RecordRevision(store, revisionApp("web:1"))
+	if err != nil || again.Number != 1 {
+		t.Errorf("Expected an unchanged spec to keep revision 1, got %v, %v", again, err)
+	}
+	for idx := 2; idx <= revisionHistoryLimit+2; idx++ {
+		if _, err = RecordRevision(store, revisionApp(fmt.Sprintf("web:%d", idx))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	revisions, err := ListRevisions(store, "my-app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(revisions) != revisionHistoryLimit || revisions[0].Number != 3 || revisions[len(revisions)-1].Number != revisionHistoryLimit+2 {
+		t.Errorf("Expected the last %d revisions, got %d from %d", revisionHistoryLimit, len(revisions), revisions[0].Number)
+	}
+
+	changes, err := DiffRevisions(store, "my-app", 3, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0].Path != "microservices[web].images.x86" || changes[0].Old != "web:3" || changes[0].New != "web:5" {
+		t.Errorf("Unexpected changes %v", changes)
+	}
+	if _, err = DiffRevisions(store, "my-app", 1, 5); err == nil {
+		t.Error("Expected an error for a revision dropped from the history")
+	}
+}
+
+func TestRecordRevisionWithoutName(t *testing.T) {
+	store := NewFileRevisionStore(t.TempDir())
+	if _, err := RecordRevision(store, &Application{}); err == nil {
+		t.Error("Expected an error for an application without name")
+	}
+	revisions, err := ListRevisions(store, "missing")
+	if err != nil || len(revisions) != 0 {
+		t.Errorf("Expected no history, got %v, %v", revisions, err)
+	}
+}
@@ -0,0 +1,350 @@
+/*
+ *  *******************************************************************************
+ *  * Copyright (c) 2024 Datasance Teknoloji A.S.
+ *  *
+ *  * This program and the accompanying materials are made available under the
+ *  * terms of the Eclipse Public License v. 2.0 which is available at
+ *  * http://www.eclipse.org/legal/epl-2.0
+ *  *
+ *  * SPDX-License-Identifier: EPL-2.0
+ *  *******************************************************************************
+ *
+ */
+
+package apps
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/datasance/iofog-go-sdk/v3/pkg/client"
+)
+
+// Microservice states reported by the Controller
+const (
+	microserviceRunning   = "RUNNING"
+	microserviceFailed    = "FAILED"
+	microserviceHealthy   = "healthy"
+	microserviceUnhealthy = "unhealthy"
+)
+
+// Defaults of DeployOptions
+const (
+	defaultRolloutTimeout  = 5 * time.Minute
+	defaultRolloutInterval = 2 * time.Second
+)
+
+// DeployOptions controls how DeployApplicationWithOptions follows the rollout of an application
+type DeployOptions struct {
+	// Wait for every microservice to be running, and healthy when it has a health check
+	// The microservices the spec changes must also run a new container
+	Wait bool
+	// Timeout of the wait, defaults to 5 minutes
+	Timeout time.Duration
+	// Interval between two polls of the microservices status, defaults to 2 seconds
+	Interval time.Duration
+	// Progress is called every time the status of a microservice changes
+	Progress func(ProgressEvent)
+	// Rollback re-applies the previous application spec if the rollout fails or times out
+	// A new application is deleted instead
+	Rollback bool
+	// Revisions records the application spec once the rollout succeeded, see RecordRevision
+	// It is the only deploy function that records revisions, see RevisionStore
+	Revisions RevisionStore
+}
+
+// ProgressEvent describes the status of a microservice during a rollout
+type ProgressEvent struct {
+	Microservice string
+	Status       string
+	HealthStatus string
+	// Percentage of the image pull
+	Percentage float64
+	Error      string
+}
+
+// String returns a one line description of the event
+func (event ProgressEvent) String() string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "%s: %s", event.Microservice, event.Status)
+	if event.Percentage > 0 && event.Percentage < 100 {
+		fmt.Fprintf(&builder, " %.0f%%", event.Percentage)
+	}
+	if event.HealthStatus != "" {
+		fmt.Fprintf(&builder, " (%s)", event.HealthStatus)
+	}
+	if event.Error != "" {
+		fmt.Fprintf(&builder, ": %s", event.Error)
+	}
+	return builder.String()
+}
+
+// RolloutError is returned when an application did not become ready
+type RolloutError struct {
+	Name   string
+	Reason string
+	// RolledBack is set when the previous application spec was re-applied, or the new application deleted
+	RolledBack bool
+	// RollbackErr is set when the rollback itself failed
+	RollbackErr error
+}
+
+func (err *RolloutError) Error() string {
+	msg := fmt.Sprintf("Rollout of application %s failed: %s", err.Name, err.Reason)
+	if err.RolledBack {
+		msg += ", rolled back"
+	}
+	if err.RollbackErr != nil {
+		msg += fmt.Sprintf(", rollback failed: %s", err.RollbackErr.Error())
+	}
+	return msg
+}
+
+// rolloutState returns the progress events of the microservices, whether they are all ready and why the rollout failed
+// Only a FAILED microservice fails the rollout, an unhealthy one is not ready and may still recover before the timeout
+func rolloutState(msvcs []client.MicroserviceInfo) (events []ProgressEvent, ready bool, failures []string) {
+	ready = true
+	for idx := range msvcs {
+		status := &msvcs[idx].Status
+		events = append(events, ProgressEvent{
+			Microservice: msvcs[idx].Name,
+			Status:       status.Status,
+			HealthStatus: status.HealthStatus,
+			Percentage:   status.Percentage,
+			Error:        status.ErrorMessage,
+		})
+		if status.Status == microserviceFailed {
+			failures = append(failures, fmt.Sprintf("microservice %s failed: %s", msvcs[idx].Name, status.ErrorMessage))
+		}
+		healthy := status.HealthStatus == "" || status.HealthStatus == microserviceHealthy
+		if status.Status != microserviceRunning || !healthy {
+			ready = false
+		}
+	}
+	return
+}
+
+// notReady describes the microservices that are not running and healthy, e.g. to explain a timeout
+func notReady(events []ProgressEvent) string {
+	var pending []string
+	for _, event := range events {
+		healthy := event.HealthStatus == "" || event.HealthStatus == microserviceHealthy
+		if event.Status != microserviceRunning || !healthy {
+			pending = append(pending, event.String())
+		}
+	}
+	return strings.Join(pending, ", ")
+}
+
+type rolloutExecutor struct {
+	app      *applicationExecutor
+	opt      DeployOptions
+	previous *Application
+	reported map[string]ProgressEvent
+	// pending holds the start time of the deployed microservices the spec changes, until they run a new container
+	pending map[string]int64
+}
+
+func newRolloutExecutor(controller IofogController, app interface{}, name string, opt DeployOptions) *rolloutExecutor {
+	if opt.Timeout == 0 {
+		opt.Timeout = defaultRolloutTimeout
+	}
+	if opt.Interval == 0 {
+		opt.Interval = defaultRolloutInterval
+	}
+	exe := &rolloutExecutor{
+		app:      newApplicationExecutor(controller, app, name),
+		opt:      opt,
+		reported: make(map[string]ProgressEvent),
+	}
+
+	return exe
+}
+
+func (exe *rolloutExecutor) execute() error {
+	if err := exe.app.init(); err != nil {
+		return err
+	}
+	return exe.run()
+}
+
+func (exe *rolloutExecutor) run() error {
+	name := exe.app.name
+	if exe.opt.Rollback {
+		previous, err := exportApplication(exe.app.client, name)
+		if _, notFound := err.(*client.NotFoundError); err != nil && !notFound {
+			return err
+		}
+		exe.previous = previous
+	}
+	if exe.opt.Wait {
+		if err := exe.snapshot(); err != nil {
+			return err
+		}
+	}
+
+	if err := exe.app.run(); err != nil {
+		return exe.fail(err.Error())
+	}
+	if exe.opt.Wait {
+		if reason := exe.wait(); reason != "" {
+			return exe.fail(reason)
+		}
+	}
+	return exe.record()
+}
+
+// record adds the applied spec to the revision history of the application
+func (exe *rolloutExecutor) record() error {
+	if exe.opt.Revisions == nil {
+		return nil
+	}
+	app, err := toApplication(exe.app.app)
+	if err != nil {
+		return err
+	}
+	spec := app.DeepCopy()
+	spec.Name = exe.app.name
+	_, err = RecordRevision(exe.opt.Revisions, spec)
+	return err
+}
+
+// snapshot records the start time of the deployed microservices the spec changes, the Controller keeps reporting their
+// previous container until the Agent replaces it. The microservices of a template cannot be compared and are not tracked
+func (exe *rolloutExecutor) snapshot() error {
+	exe.pending = make(map[string]int64)
+	app, err := toApplication(exe.app.app)
+	if err != nil {
+		return err
+	}
+	if app.Template != nil {
+		return nil
+	}
+	if _, err = exe.app.client.GetApplicationByName(exe.app.name); err != nil {
+		if _, notFound := err.(*client.NotFoundError); notFound {
+			return nil
+		}
+		return err
+	}
+	msvcs, err := exe.app.client.GetMicroservicesByApplication(exe.app.name)
+	if err != nil {
+		return err
+	}
+	agentNames, err := agentNamesByUUID(exe.app.client)
+	if err != nil {
+		return err
+	}
+	desired := make(map[string]*Microservice, len(app.Microservices))
+	for idx := range app.Microservices {
+		desired[app.Microservices[idx].Name] = &app.Microservices[idx]
+	}
+	for idx := range msvcs.Microservices {
+		deployed := &msvcs.Microservices[idx]
+		spec, found := desired[deployed.Name]
+		if !found {
+			continue
+		}
+		changed, err := microserviceChanged(deployed, spec, agentNames)
+		if err != nil {
+			return err
+		}
+		if changed {
+			exe.pending[deployed.Name] = deployed.Status.StartTime
+		}
+	}
+	return nil
+}
+
+// restarted returns whether every changed microservice runs a new container, like restartState a microservice is
+// restarted once the start time of its container changed or once it was seen in another status than RUNNING
+func (exe *rolloutExecutor) restarted(msvcs []client.MicroserviceInfo) bool {
+	for idx := range msvcs {
+		startTime, found := exe.pending[msvcs[idx].Name]
+		if !found {
+			continue
+		}
+		status := &msvcs[idx].Status
+		if status.Status != microserviceRunning || status.StartTime != 0 && status.StartTime != startTime {
+			delete(exe.pending, msvcs[idx].Name)
+		}
+	}
+	return len(exe.pending) == 0
+}
+
+// wait polls the microservices of the application until they are ready, one fails or the timeout expires
+func (exe *rolloutExecutor) wait() (reason string) {
+	deadline := time.Now().Add(exe.opt.Timeout)
+	for {
+		msvcs, err := exe.app.client.GetMicroservicesByApplication(exe.app.name)
+		if err != nil {
+			return err.Error()
+		}
+		events, ready, failures := rolloutState(msvcs.Microservices)
+		exe.report(events)
+		if len(failures) > 0 {
+			return strings.Join(failures, ", ")
+		}
+		if exe.restarted(msvcs.Microservices) && ready {
+			return ""
+		}
+		if time.Now().After(deadline) {
+			return fmt.Sprintf("microservices not ready after %s: %s", exe.opt.Timeout, exe.notReady(events))
+		}
+		time.Sleep(exe.opt.Interval)
+	}
+}
+
+// notReady describes the microservices that are not running and healthy, and those still running their previous container
+func (exe *rolloutExecutor) notReady(events []ProgressEvent) string {
+	reason := notReady(events)
+	if len(exe.pending) == 0 {
+		return reason
+	}
+	previous := make([]string, 0, len(exe.pending))
+	for name := range exe.pending {
+		previous = append(previous, name)
+	}
+	sort.Strings(previous)
+	if reason != "" {
+		reason += ", "
+	}
+	return reason + "previous container still running for " + strings.Join(previous, ", ")
+}
+
+// report calls the progress callback for the microservices whose status changed since the last poll
+func (exe *rolloutExecutor) report(events []ProgressEvent) {
+	if exe.opt.Progress == nil {
+		return
+	}
+	for _, event := range events {
+		if last, found := exe.reported[event.Microservice]; found && last == event {
+			continue
+		}
+		exe.reported[event.Microservice] = event
+		exe.opt.Progress(event)
+	}
+}
+
+func (exe *rolloutExecutor) fail(reason string) error {
+	err := &RolloutError{
+		Name:   exe.app.name,
+		Reason: reason,
+	}
+	if !exe.opt.Rollback {
+		return err
+	}
+	if exe.previous == nil {
+		err.RollbackErr = exe.app.client.DeleteApplication(exe.app.name)
+		if _, notFound := err.RollbackErr.(*client.NotFoundError); notFound {
+			err.RollbackErr = nil
+		}
+	} else {
+		rollback := newApplicationExecutor(exe.app.controller, exe.previous, exe.app.name)
+		rollback.client = exe.app.client
+		err.RollbackErr = rollback.run()
+	}
+	err.RolledBack = err.RollbackErr == nil
+	return err
+}
@@ -0,0 +1,157 @@
+/*
+ *  *******************************************************************************
+ *  * Copyright (c) 2024 Datasance Teknoloji A.S.
+ *  *
+ *  * This program and the accompanying materials are made available under the
+ *  * terms of the Eclipse Public License v. 2.0 which is available at
+ *  * http://www.eclipse.org/legal/epl-2.0
+ *  *
+ *  * SPDX-License-Identifier: EPL-2.0
+ *  *******************************************************************************
+ *
+ */
+
+package apps
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/datasance/iofog-go-sdk/v3/pkg/client"
+)
+
+func msvcWithStatus(name string, status client.MicroserviceStatusInfo) client.MicroserviceInfo {
+	return client.MicroserviceInfo{Name: name, Status: status}
+}
+
+func TestRolloutState(t *testing.T) {
+	pulling := []client.MicroserviceInfo{
+		msvcWithStatus("web", client.MicroserviceStatusInfo{Status: "PULLING", Percentage: 42}),
+		msvcWithStatus("db", client.MicroserviceStatusInfo{Status: "RUNNING"}),
+	}
+	events, ready, failures := rolloutState(pulling)
+	if ready || len(failures) != 0 {
+		t.Errorf("Expected rollout in progress, got ready=%v failures=%v", ready, failures)
+	}
+	if len(events) != 2 || events[0].Percentage != 42 || events[0].String() != "web: PULLING 42%" {
+		t.Errorf("Unexpected events: %v", events)
+	}
+
+	starting := []client.MicroserviceInfo{
+		msvcWithStatus("web", client.MicroserviceStatusInfo{Status: "RUNNING", HealthStatus: "starting"}),
+	}
+	if _, ready, _ = rolloutState(starting); ready {
+		t.Error("Expected microservice with a pending health check not to be ready")
+	}
+
+	running := []client.MicroserviceInfo{
+		msvcWithStatus("web", client.MicroserviceStatusInfo{Status: "RUNNING", HealthStatus: "healthy"}),
+		msvcWithStatus("db", client.MicroserviceStatusInfo{Status: "RUNNING"}),
+	}
+	if _, ready, failures = rolloutState(running); !ready || len(failures) != 0 {
+		t.Errorf("Expected rollout to be ready, got ready=%v failures=%v", ready, failures)
+	}
+
+	unhealthy := []client.MicroserviceInfo{
+		msvcWithStatus("web", client.MicroserviceStatusInfo{Status: "RUNNING", HealthStatus: "healthy"}),
+		msvcWithStatus("db", client.MicroserviceStatusInfo{Status: "RUNNING", HealthStatus: "unhealthy"}),
+	}
+	events, ready, failures = rolloutState(unhealthy)
+	if ready || len(failures) != 0 {
+		t.Errorf("Expected unhealthy microservice not to be ready nor failed, got ready=%v failures=%v", ready, failures)
+	}
+	if reason := notReady(events); reason != "db: RUNNING (unhealthy)" {
+		t.Errorf("Unexpected not ready microservices: %s", reason)
+	}
+
+	failed := []client.MicroserviceInfo{
+		msvcWithStatus("web", client.MicroserviceStatusInfo{Status: "FAILED", ErrorMessage: "image not found"}),
+		msvcWithStatus("db", client.MicroserviceStatusInfo{Status: "RUNNING", HealthStatus: "unhealthy"}),
+	}
+	if _, _, failures = rolloutState(failed); len(failures) != 1 {
+		t.Errorf("Expected 1 failure, got %v", failures)
+	}
+}
+
+func TestRolloutReportChanges(t *testing.T) {
+	var received []ProgressEvent
+	exe := &rolloutExecutor{
+		opt:      DeployOptions{Progress: func(event ProgressEvent) { received = append(received, event) }},
+		reported: make(map[string]ProgressEvent),
+	}
+	event := ProgressEvent{Microservice: "web", Status: "PULLING", Percentage: 10}
+	exe.report([]ProgressEvent{event})
+	exe.report([]ProgressEvent{event})
+	event.Percentage = 50
+	exe.report([]ProgressEvent{event})
+	if len(received) != 2 {
+		t.Errorf("Expected only changes to be reported, got %v", received)
+	}
+}
+
+func TestRolloutRestarted(t *testing.T) {
+	exe := &rolloutExecutor{pending: map[string]int64{"web": 100}}
+	previous := []client.MicroserviceInfo{
+		msvcWithStatus("web", client.MicroserviceStatusInfo{Status: "RUNNING", StartTime: 100}),
+		msvcWithStatus("db", client.MicroserviceStatusInfo{Status: "RUNNING", StartTime: 50}),
+	}
+	if exe.restarted(previous) {
+		t.Error("Expected microservice running its previous container not to be restarted")
+	}
+	replaced := []client.MicroserviceInfo{
+		msvcWithStatus("web", client.MicroserviceStatusInfo{Status: "RUNNING", StartTime: 200}),
+		msvcWithStatus("db", client.MicroserviceStatusInfo{Status: "RUNNING", StartTime: 50}),
+	}
+	if !exe.restarted(replaced) {
+		t.Error("Expected microservice with a new start time to be restarted")
+	}
+
+	exe.pending = map[string]int64{"web": 100}
+	exe.restarted([]client.MicroserviceInfo{msvcWithStatus("web", client.MicroserviceStatusInfo{Status: "PULLING"})})
+	if !exe.restarted(previous) {
+		t.Error("Expected microservice seen in another status to be restarted")
+	}
+}
+
+func TestRolloutWaitsForNewContainers(t *testing.T) {
+	web := client.MicroserviceInfo{
+		UUID:      "web-uuid",
+		Name:      "web",
+		AgentUUID: "agent-uuid",
+		Ports:     []client.MicroservicePortMappingInfo{{Internal: 80, External: 8080}},
+		Status:    client.MicroserviceStatusInfo{Status: "RUNNING", StartTime: 100},
+	}
+	// The Controller keeps reporting the previous container, RUNNING, after the update
+	clt, ctrl := newFakeController(t, map[string]interface{}{
+		"/application/app":               client.ApplicationInfo{Name: "app", IsActivated: true},
+		"/microservices?application=app": client.MicroserviceListResponse{Microservices: []client.MicroserviceInfo{web}},
+		"/iofog-list":                    client.ListAgentsResponse{Agents: []client.AgentInfo{{UUID: "agent-uuid", Name: "edge"}}},
+	})
+	app := &Application{Microservices: []Microservice{{
+		Name:      "web",
+		Agent:     MicroserviceAgent{Name: "edge"},
+		Container: MicroserviceContainer{Ports: []MicroservicePortMapping{{Internal: 80, External: 8081}}},
+	}}}
+	exe := newRolloutExecutor(IofogController{}, app, "app", DeployOptions{
+		Wait:     true,
+		Timeout:  50 * time.Millisecond,
+		Interval: 10 * time.Millisecond,
+		Rollback: true,
+	})
+	exe.app.client = clt
+
+	err := exe.run()
+	rolloutErr, ok := err.(*RolloutError)
+	if !ok {
+		t.Fatalf("Expected a rollout error, got %v", err)
+	}
+	if !strings.Contains(rolloutErr.Reason, "previous container still running for web") || !rolloutErr.RolledBack {
+		t.Errorf("Expected a rollback of the microservice running its previous container, got %v", rolloutErr)
+	}
+	expected := []string{"PUT /application/yaml/app", "PATCH /application/app", "PUT /application/yaml/app", "PATCH /application/app"}
+	if !reflect.DeepEqual(ctrl.requests, expected) {
+		t.Errorf("Expected requests %v, got %v", expected, ctrl.requests)
+	}
+}
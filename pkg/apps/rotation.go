@@ -0,0 +1,327 @@
+/*
+ *  *******************************************************************************
+ *  * Copyright (c) 2024 Datasance Teknoloji A.S.
+ *  *
+ *  * This program and the accompanying materials are made available under the
+ *  * terms of the Eclipse Public License v. 2.0 which is available at
+ *  * http://www.eclipse.org/legal/epl-2.0
+ *  *
+ *  * SPDX-License-Identifier: EPL-2.0
+ *  *******************************************************************************
+ *
+ */
+
+package apps
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/datasance/iofog-go-sdk/v3/pkg/client"
+)
+
+// RestartStrategy is how the consumers of a rotated secret or config map pick up the new data
+type RestartStrategy string
+
+const (
+	// RestartRebuild rebuilds the container of the microservice
+	RestartRebuild RestartStrategy = "Rebuild"
+	// RestartStopStart stops then starts the microservice, system microservices cannot be stopped
+	RestartStopStart RestartStrategy = "StopStart"
+)
+
+// RotationOptions controls the rolling restart of the microservices consuming a rotated secret or config map
+type RotationOptions struct {
+	// BatchSize is the number of microservices restarted at once, defaults to 1
+	BatchSize int
+	// Strategy defaults to RestartRebuild
+	Strategy RestartStrategy
+	// Timeout of the wait for a batch to be running and healthy, defaults to 5 minutes
+	Timeout time.Duration
+	// Interval between two polls, defaults to 2 seconds
+	Interval time.Duration
+	// Progress is called every time the status of a restarted microservice changes
+	Progress func(ProgressEvent)
+}
+
+// RotationResult lists the microservices consuming the rotated resource, named <application>/<microservice>
+type RotationResult struct {
+	Kind Kind
+	Name string
+	// Microservices consuming the resource through env or volume mounts, in restart order
+	Microservices []string
+	// Restarted microservices are running and healthy
+	Restarted []string
+	// Pending microservices were not restarted because the rotation halted, including the failed batch
+	Pending []string
+}
+
+// RotationError is returned when a batch of microservices did not become healthy after its restart, later batches are not restarted
+type RotationError struct {
+	Kind  Kind
+	Name  string
+	Batch []string
+	// Reason the batch failed
+	Reason string
+}
+
+func (err *RotationError) Error() string {
+	return fmt.Sprintf("Rotation of %s %s halted, restart of %s failed: %s", err.Kind, err.Name, strings.Join(err.Batch, ", "), err.Reason)
+}
+
+// rotationConsumer is a microservice restarted by a rotation
+type rotationConsumer struct {
+	name     string
+	uuid     string
+	isSystem bool
+	// startTime of the container before the restart
+	startTime int64
+	// restarted is set once the previous container is known to be gone
+	restarted bool
+}
+
+// rotationBatches splits the consumers into batches of size
+func rotationBatches(consumers []rotationConsumer, size int) (batches [][]rotationConsumer) {
+	for start := 0; start < len(consumers); start += size {
+		end := start + size
+		if end > len(consumers) {
+			end = len(consumers)
+		}
+		batches = append(batches, consumers[start:end])
+	}
+	return batches
+}
+
+// restartState returns whether every restarted microservice runs a new container and is healthy, and why the batch failed
+// A consumer is restarted once the start time of its container changed or, as Agents may not report start times, once it
+// was seen in another status than RUNNING; until then it may still run the previous container
+func restartState(consumers []rotationConsumer, msvcs []client.MicroserviceInfo) (events []ProgressEvent, ready bool, failures []string) {
+	events, ready, failures = rolloutState(msvcs)
+	for idx := range msvcs {
+		consumer := &consumers[idx]
+		events[idx].Microservice = consumer.name
+		status := &msvcs[idx].Status
+		if status.Status != microserviceRunning || status.StartTime != 0 && status.StartTime != consumer.startTime {
+			consumer.restarted = true
+		}
+		if !consumer.restarted {
+			ready = false
+		}
+	}
+	return events, ready, failures
+}
+
+type rotationExecutor struct {
+	client   *client.Client
+	kind     Kind
+	name     string
+	update   func() error
+	opt      RotationOptions
+	reported map[string]ProgressEvent
+}
+
+func newRotationExecutor(clt *client.Client, kind Kind, name string, opt RotationOptions) *rotationExecutor {
+	if opt.BatchSize <= 0 {
+		opt.BatchSize = 1
+	}
+	if opt.Strategy == "" {
+		opt.Strategy = RestartRebuild
+	}
+	if opt.Timeout == 0 {
+		opt.Timeout = defaultRolloutTimeout
+	}
+	if opt.Interval == 0 {
+		opt.Interval = defaultRolloutInterval
+	}
+	exe := &rotationExecutor{
+		client:   clt,
+		kind:     kind,
+		name:     name,
+		opt:      opt,
+		reported: make(map[string]ProgressEvent),
+	}
+
+	return exe
+}
+
+func newSecretRotationExecutor(clt *client.Client, name string, data map[string]string, opt RotationOptions) *rotationExecutor {
+	exe := newRotationExecutor(clt, SecretKind, name, opt)
+	exe.update = func() error {
+		return exe.client.UpdateSecret(name, &client.SecretUpdateRequest{Name: name, Data: data})
+	}
+	return exe
+}
+
+func newConfigMapRotationExecutor(clt *client.Client, name string, data map[string]string, opt RotationOptions) *rotationExecutor {
+	exe := newRotationExecutor(clt, ConfigMapKind, name, opt)
+	exe.update = func() error {
+		return exe.client.UpdateConfigMap(name, &client.ConfigMapUpdateRequest{Name: name, Data: data})
+	}
+	return exe
+}
+
+// execute finds the consumers, updates the resource, then restarts the consumers batch by batch
+func (exe *rotationExecutor) execute() (*RotationResult, error) {
+	if exe.opt.Strategy != RestartRebuild && exe.opt.Strategy != RestartStopStart {
+		return nil, NewInputError(fmt.Sprintf("Unknown restart strategy %s", exe.opt.Strategy))
+	}
+	graph, err := dependencyGraph(exe.client)
+	if err != nil {
+		return nil, err
+	}
+	result := &RotationResult{
+		Kind:          exe.kind,
+		Name:          exe.name,
+		Microservices: graph.Microservices(exe.kind, exe.name),
+	}
+	consumers := make([]rotationConsumer, 0, len(result.Microservices))
+	for _, name := range result.Microservices {
+		consumer, err := exe.lookup(name)
+		if err != nil {
+			return result, err
+		}
+		consumers = append(consumers, consumer)
+	}
+
+	// Nothing is restarted before the resource is updated
+	if err := exe.update(); err != nil {
+		return result, err
+	}
+
+	result.Pending = append([]string{}, result.Microservices...)
+	for _, batch := range rotationBatches(consumers, exe.opt.BatchSize) {
+		if err := exe.restart(batch); err != nil {
+			return result, err
+		}
+		for _, consumer := range batch {
+			result.Restarted = append(result.Restarted, consumer.name)
+		}
+		result.Pending = result.Pending[len(batch):]
+	}
+	return result, nil
+}
+
+// lookup finds a consumer among regular then system microservices
+// System microservices cannot be stopped, they are only supported by the rebuild strategy
+func (exe *rotationExecutor) lookup(fqName string) (consumer rotationConsumer, err error) {
+	appName, name, err := ParseFQMsvcName(fqName)
+	if err != nil {
+		return consumer, err
+	}
+	consumer.name = fqName
+	msvc, err := exe.client.GetMicroserviceByName(appName, name)
+	if isApplicationNotFound(err) {
+		msvc, err = exe.client.GetSystemMicroserviceByName(appName, name)
+		consumer.isSystem = true
+	}
+	if err != nil {
+		return consumer, err
+	}
+	if consumer.isSystem && exe.opt.Strategy == RestartStopStart {
+		return consumer, NewInputError(fmt.Sprintf("Microservice %s belongs to a system application and cannot be stopped, use the %s strategy", fqName, RestartRebuild))
+	}
+	consumer.uuid = msvc.UUID
+	consumer.startTime = msvc.Status.StartTime
+	return consumer, nil
+}
+
+// restart restarts a batch and waits for it to be running and healthy
+func (exe *rotationExecutor) restart(batch []rotationConsumer) error {
+	fail := func(reason string) error {
+		names := make([]string, 0, len(batch))
+		for _, consumer := range batch {
+			names = append(names, consumer.name)
+		}
+		return &RotationError{Kind: exe.kind, Name: exe.name, Batch: names, Reason: reason}
+	}
+
+	switch exe.opt.Strategy {
+	case RestartRebuild:
+		for _, consumer := range batch {
+			var err error
+			if consumer.isSystem {
+				err = exe.client.RebuildsSystemMicroservice(consumer.uuid)
+			} else {
+				err = exe.client.RebuildsMicroservice(consumer.uuid)
+			}
+			if err != nil {
+				return fail(err.Error())
+			}
+		}
+	case RestartStopStart:
+		for _, consumer := range batch {
+			if err := exe.client.StopMicroservice(consumer.uuid); err != nil {
+				return fail(err.Error())
+			}
+		}
+		if reason := exe.wait(batch, func(msvcs []client.MicroserviceInfo) (bool, []string) {
+			return stoppedState(msvcs), nil
+		}); reason != "" {
+			return fail(reason)
+		}
+		for idx := range batch {
+			batch[idx].restarted = true
+		}
+		for _, consumer := range batch {
+			if err := exe.client.StartMicroservice(consumer.uuid); err != nil {
+				return fail(err.Error())
+			}
+		}
+	}
+
+	if reason := exe.wait(batch, func(msvcs []client.MicroserviceInfo) (bool, []string) {
+		events, ready, failures := restartState(batch, msvcs)
+		exe.report(events)
+		return ready, failures
+	}); reason != "" {
+		return fail(reason)
+	}
+	return nil
+}
+
+// wait polls the microservices of the batch until done, a failure or the timeout
+func (exe *rotationExecutor) wait(batch []rotationConsumer, done func([]client.MicroserviceInfo) (bool, []string)) (reason string) {
+	deadline := time.Now().Add(exe.opt.Timeout)
+	for {
+		msvcs := make([]client.MicroserviceInfo, 0, len(batch))
+		for _, consumer := range batch {
+			var msvc *client.MicroserviceInfo
+			var err error
+			if consumer.isSystem {
+				msvc, err = exe.client.GetSystemMicroserviceByID(consumer.uuid)
+			} else {
+				msvc, err = exe.client.GetMicroserviceByID(consumer.uuid)
+			}
+			if err != nil {
+				return err.Error()
+			}
+			msvcs = append(msvcs, *msvc)
+		}
+		ok, failures := done(msvcs)
+		if len(failures) > 0 {
+			return strings.Join(failures, ", ")
+		}
+		if ok {
+			return ""
+		}
+		if time.Now().After(deadline) {
+			return fmt.Sprintf("microservices not ready after %s", exe.opt.Timeout)
+		}
+		time.Sleep(exe.opt.Interval)
+	}
+}
+
+// report calls the progress callback for the microservices whose status changed since the last poll
+func (exe *rotationExecutor) report(events []ProgressEvent) {
+	if exe.opt.Progress == nil {
+		return
+	}
+	for _, event := range events {
+		if last, found := exe.reported[event.Microservice]; found && last == event {
+			continue
+		}
+		exe.reported[event.Microservice] = event
+		exe.opt.Progress(event)
+	}
+}
@@ -0,0 +1,68 @@
+/*
+ *  *******************************************************************************
+ *  * Copyright (c) 2024 Datasance Teknoloji A.S.
+ *  *
+ *  * This program and the accompanying materials are made available under the
+ *  * terms of the Eclipse Public License v. 2.0 which is available at
+ *  * http://www.eclipse.org/legal/epl-2.0
+ *  *
+ *  * SPDX-License-Identifier: EPL-2.0
+ *  *******************************************************************************
+ *
+ */
+
+package apps
+
+import (
+	"testing"
+
+	"github.com/datasance/iofog-go-sdk/v3/pkg/client"
+)
+
+func TestRotationBatches(t *testing.T) {
+	consumers := []rotationConsumer{{name: "a"}, {name: "b"}, {name: "c"}}
+	batches := rotationBatches(consumers, 2)
+	if len(batches) != 2 || len(batches[0]) != 2 || len(batches[1]) != 1 || batches[1][0].name != "c" {
+		t.Errorf("unexpected batches %v", batches)
+	}
+	if batches := rotationBatches(nil, 2); len(batches) != 0 {
+		t.Errorf("expected no batch, got %v", batches)
+	}
+}
+
+func TestRestartState(t *testing.T) {
+	consumers := []rotationConsumer{{name: "app/a", startTime: 100}, {name: "app/b"}}
+	withStatus := func(status string, startTime int64, health string) client.MicroserviceInfo {
+		return client.MicroserviceInfo{Status: client.MicroserviceStatusInfo{Status: status, StartTime: startTime, HealthStatus: health}}
+	}
+	running := func(startTime int64, health string) client.MicroserviceInfo {
+		return withStatus(microserviceRunning, startTime, health)
+	}
+
+	// The previous containers still run, b does not report its start time
+	events, ready, failures := restartState(consumers, []client.MicroserviceInfo{running(100, ""), running(0, "")})
+	if ready || len(failures) > 0 {
+		t.Errorf("expected the batch to be restarting, got ready %v, failures %v", ready, failures)
+	}
+	if events[0].Microservice != "app/a" {
+		t.Errorf("expected events named after the consumers, got %v", events)
+	}
+
+	// a runs a new container, b was not seen restarting yet
+	if _, ready, _ = restartState(consumers, []client.MicroserviceInfo{running(200, microserviceHealthy), running(0, "")}); ready {
+		t.Error("expected the batch to wait for b to restart")
+	}
+	if !consumers[0].restarted || consumers[1].restarted {
+		t.Errorf("expected only a to be restarted, got %+v", consumers)
+	}
+
+	if _, ready, _ = restartState(consumers, []client.MicroserviceInfo{running(200, microserviceHealthy), withStatus("STARTING", 0, "")}); ready {
+		t.Error("expected the batch to wait for b to run")
+	}
+	if _, ready, failures = restartState(consumers, []client.MicroserviceInfo{running(200, microserviceUnhealthy), running(0, "")}); ready || len(failures) > 0 {
+		t.Errorf("expected an unhealthy batch to be waited for, got ready %v, failures %v", ready, failures)
+	}
+	if _, ready, _ = restartState(consumers, []client.MicroserviceInfo{running(200, microserviceHealthy), running(0, "")}); !ready {
+		t.Error("expected the batch to be ready")
+	}
+}
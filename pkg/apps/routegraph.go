@@ -0,0 +1,433 @@
+/*
+ *  *******************************************************************************
+ *  * Copyright (c) 2024 Datasance Teknoloji A.S.
+ *  *
+ *  * This program and the accompanying materials are made available under the
+ *  * terms of the Eclipse Public License v. 2.0 which is available at
+ *  * http://www.eclipse.org/legal/epl-2.0
+ *  *
+ *  * SPDX-License-Identifier: EPL-2.0
+ *  *******************************************************************************
+ *
+ */
+
+package apps
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/datasance/iofog-go-sdk/v3/pkg/client"
+)
+
+// RouteIssueKind identifies a problem of the message routing of an application
+type RouteIssueKind string
+
+const (
+	// DanglingRoute starts or ends on a microservice that is not part of the application
+	DanglingRoute RouteIssueKind = "DanglingRoute"
+	// RouteCycle is a set of microservices whose messages loop back to them
+	RouteCycle RouteIssueKind = "RouteCycle"
+	// UnreachableMicroservice neither sends nor receives messages, while other microservices do
+	UnreachableMicroservice RouteIssueKind = "UnreachableMicroservice"
+	// UnmatchedPublication is a tag published without subscriber
+	UnmatchedPublication RouteIssueKind = "UnmatchedPublication"
+	// UnmatchedSubscription is a tag subscribed to without publisher
+	UnmatchedSubscription RouteIssueKind = "UnmatchedSubscription"
+)
+
+// RouteIssue is a problem found in a route graph
+type RouteIssue struct {
+	Kind RouteIssueKind
+	// Route is the name of the dangling route
+	Route string
+	// Microservices involved, in cycle order for cycles
+	Microservices []string
+	// Tag of unmatched publications and subscriptions
+	Tag string
+}
+
+// String returns a one line description of the issue
+func (issue RouteIssue) String() string {
+	switch issue.Kind {
+	case DanglingRoute:
+		return fmt.Sprintf("route %s references unknown microservice %s", issue.Route, strings.Join(issue.Microservices, ", "))
+	case RouteCycle:
+		return fmt.Sprintf("messages loop through %s -> %s", strings.Join(issue.Microservices, " -> "), issue.Microservices[0])
+	case UnreachableMicroservice:
+		return fmt.Sprintf("microservice %s is not connected to any other microservice", issue.Microservices[0])
+	case UnmatchedPublication:
+		return fmt.Sprintf("tag %s published by %s has no subscriber", issue.Tag, strings.Join(issue.Microservices, ", "))
+	case UnmatchedSubscription:
+		return fmt.Sprintf("tag %s subscribed by %s has no publisher", issue.Tag, strings.Join(issue.Microservices, ", "))
+	}
+	return string(issue.Kind)
+}
+
+// RouteEdge is a flow of messages between two microservices, through a route or a pub/sub tag
+type RouteEdge struct {
+	From string
+	To   string
+	// Route is the name of the route, empty for tags
+	Route string
+	// Tag is the pub/sub tag, empty for routes
+	Tag string
+}
+
+// RouteGraph is the message routing of an application
+type RouteGraph struct {
+	Application string
+	// Microservices are sorted by name
+	Microservices []string
+	Routes        []Route
+	// PubTags and SubTags are keyed by microservice name
+	PubTags map[string][]string
+	SubTags map[string][]string
+}
+
+// NewRouteGraph returns the route graph of an application spec
+func NewRouteGraph(app *Application) *RouteGraph {
+	graph := &RouteGraph{
+		Application: app.Name,
+		Routes:      append([]Route(nil), app.Routes...),
+		PubTags:     make(map[string][]string),
+		SubTags:     make(map[string][]string),
+	}
+	for idx := range app.Microservices {
+		msvc := &app.Microservices[idx]
+		graph.add(msvc.Name, msvc.MsRoutes.PubTags, msvc.MsRoutes.SubTags)
+	}
+	sort.Strings(graph.Microservices)
+	return graph
+}
+
+// routeGraph returns the route graph of a deployed regular or system application
+func routeGraph(clt *client.Client, name string) (*RouteGraph, error) {
+	msvcs, err := clt.GetMicroservicesByApplication(name)
+	if isApplicationNotFound(err) {
+		msvcs, err = clt.GetSystemMicroservicesByApplication(name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	routes, err := clt.ListRoutes()
+	if err != nil {
+		return nil, err
+	}
+
+	graph := &RouteGraph{
+		Application: name,
+		PubTags:     make(map[string][]string),
+		SubTags:     make(map[string][]string),
+	}
+	for idx := range msvcs.Microservices {
+		msvc := &msvcs.Microservices[idx]
+		graph.add(msvc.Name, msvc.PubTags, msvc.SubTags)
+	}
+	sort.Strings(graph.Microservices)
+	for idx := range routes.Routes {
+		if routes.Routes[idx].Application == name {
+			graph.Routes = append(graph.Routes, routeFromInfo(&routes.Routes[idx]))
+		}
+	}
+	sort.Slice(graph.Routes, func(i, j int) bool { return graph.Routes[i].Name < graph.Routes[j].Name })
+	return graph, nil
+}
+
+func (graph *RouteGraph) add(name string, pubTags, subTags []string) {
+	graph.Microservices = append(graph.Microservices, name)
+	if len(pubTags) > 0 {
+		graph.PubTags[name] = append([]string(nil), pubTags...)
+	}
+	if len(subTags) > 0 {
+		graph.SubTags[name] = append([]string(nil), subTags...)
+	}
+}
+
+func (graph *RouteGraph) hasMicroservice(name string) bool {
+	idx := sort.SearchStrings(graph.Microservices, name)
+	return idx < len(graph.Microservices) && graph.Microservices[idx] == name
+}
+
+// subscribers returns the subscribers of every tag, sorted
+func (graph *RouteGraph) subscribers() map[string][]string {
+	return tagIndex(graph.SubTags)
+}
+
+// publishers returns the publishers of every tag, sorted
+func (graph *RouteGraph) publishers() map[string][]string {
+	return tagIndex(graph.PubTags)
+}
+
+func tagIndex(tagsByMsvc map[string][]string) map[string][]string {
+	index := make(map[string][]string)
+	for msvc, tags := range tagsByMsvc {
+		for _, tag := range tags {
+			index[tag] = append(index[tag], msvc)
+		}
+	}
+	for tag := range index {
+		sort.Strings(index[tag])
+	}
+	return index
+}
+
+// Edges returns the flows of messages between microservices of the application: routes whose endpoints exist,
+// then every publisher to subscriber pair of each tag
+func (graph *RouteGraph) Edges() (edges []RouteEdge) {
+	for _, route := range graph.Routes {
+		if graph.hasMicroservice(route.From) && graph.hasMicroservice(route.To) {
+			edges = append(edges, RouteEdge{From: route.From, To: route.To, Route: route.Name})
+		}
+	}
+	subscribers := graph.subscribers()
+	publishers := graph.publishers()
+	tags := make([]string, 0, len(publishers))
+	for tag := range publishers {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	for _, tag := range tags {
+		for _, from := range publishers[tag] {
+			for _, to := range subscribers[tag] {
+				edges = append(edges, RouteEdge{From: from, To: to, Tag: tag})
+			}
+		}
+	}
+	return edges
+}
+
+// Validate returns the dangling routes, cycles, unreachable microservices and unmatched tags of the graph
+func (graph *RouteGraph) Validate() (issues []RouteIssue) {
+	for _, route := range graph.Routes {
+		var unknown []string
+		for _, endpoint := range []string{route.From, route.To} {
+			if !graph.hasMicroservice(endpoint) {
+				unknown = append(unknown, endpoint)
+			}
+		}
+		if len(unknown) > 0 {
+			issues = append(issues, RouteIssue{Kind: DanglingRoute, Route: route.Name, Microservices: unknown})
+		}
+	}
+
+	edges := graph.Edges()
+	for _, cycle := range findCycles(graph.Microservices, edges) {
+		issues = append(issues, RouteIssue{Kind: RouteCycle, Microservices: cycle})
+	}
+
+	if len(edges) > 0 {
+		connected := make(map[string]bool)
+		for _, edge := range edges {
+			if edge.From != edge.To {
+				connected[edge.From] = true
+				connected[edge.To] = true
+			}
+		}
+		for _, msvc := range graph.Microservices {
+			if !connected[msvc] {
+				issues = append(issues, RouteIssue{Kind: UnreachableMicroservice, Microservices: []string{msvc}})
+			}
+		}
+	}
+
+	issues = append(issues, unmatchedTags(graph.publishers(), graph.subscribers(), UnmatchedPublication)...)
+	issues = append(issues, unmatchedTags(graph.subscribers(), graph.publishers(), UnmatchedSubscription)...)
+	return issues
+}
+
+// unmatchedTags returns an issue for every tag of index that is missing from others
+func unmatchedTags(index, others map[string][]string, kind RouteIssueKind) (issues []RouteIssue) {
+	tags := make([]string, 0, len(index))
+	for tag := range index {
+		if _, found := others[tag]; !found {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Strings(tags)
+	for _, tag := range tags {
+		issues = append(issues, RouteIssue{Kind: kind, Tag: tag, Microservices: index[tag]})
+	}
+	return issues
+}
+
+// findCycles returns the strongly connected components looping back on themselves, with Tarjan's algorithm
+// Each cycle starts with its smallest microservice name, cycles are sorted
+func findCycles(nodes []string, edges []RouteEdge) (cycles [][]string) {
+	successors := make(map[string][]string)
+	selfLoops := make(map[string]bool)
+	for _, edge := range edges {
+		successors[edge.From] = append(successors[edge.From], edge.To)
+		if edge.From == edge.To {
+			selfLoops[edge.From] = true
+		}
+	}
+	for node := range successors {
+		sort.Strings(successors[node])
+	}
+
+	index := 0
+	indexes := make(map[string]int)
+	lowLinks := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	var connect func(node string)
+	connect = func(node string) {
+		indexes[node] = index
+		lowLinks[node] = index
+		index++
+		stack = append(stack, node)
+		onStack[node] = true
+		for _, next := range successors[node] {
+			if _, visited := indexes[next]; !visited {
+				connect(next)
+				if lowLinks[next] < lowLinks[node] {
+					lowLinks[node] = lowLinks[next]
+				}
+			} else if onStack[next] && indexes[next] < lowLinks[node] {
+				lowLinks[node] = indexes[next]
+			}
+		}
+		if lowLinks[node] != indexes[node] {
+			return
+		}
+		var component []string
+		for {
+			last := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[last] = false
+			component = append(component, last)
+			if last == node {
+				break
+			}
+		}
+		if len(component) > 1 || selfLoops[node] {
+			cycles = append(cycles, orderCycle(component, successors))
+		}
+	}
+	for _, node := range nodes {
+		if _, visited := indexes[node]; !visited {
+			connect(node)
+		}
+	}
+	sort.Slice(cycles, func(i, j int) bool { return cycles[i][0] < cycles[j][0] })
+	return cycles
+}
+
+// orderCycle orders the members of a component along its edges, starting from the smallest name
+// Members the walk does not reach are appended in name order
+func orderCycle(component []string, successors map[string][]string) []string {
+	members := make(map[string]bool)
+	for _, node := range component {
+		members[node] = true
+	}
+	sort.Strings(component)
+	ordered := []string{component[0]}
+	visited := map[string]bool{component[0]: true}
+	for current := component[0]; ; {
+		next := ""
+		for _, candidate := range successors[current] {
+			if members[candidate] && !visited[candidate] {
+				next = candidate
+				break
+			}
+		}
+		if next == "" {
+			break
+		}
+		ordered = append(ordered, next)
+		visited[next] = true
+		current = next
+	}
+	for _, node := range component {
+		if !visited[node] {
+			ordered = append(ordered, node)
+		}
+	}
+	return ordered
+}
+
+// danglingEndpoints returns the route endpoints that are not microservices of the application, sorted
+func (graph *RouteGraph) danglingEndpoints() []string {
+	unknown := make(map[string]bool)
+	for _, route := range graph.Routes {
+		for _, endpoint := range []string{route.From, route.To} {
+			if !graph.hasMicroservice(endpoint) {
+				unknown[endpoint] = true
+			}
+		}
+	}
+	endpoints := make([]string, 0, len(unknown))
+	for endpoint := range unknown {
+		endpoints = append(endpoints, endpoint)
+	}
+	sort.Strings(endpoints)
+	return endpoints
+}
+
+// DOT returns the graph in the Graphviz DOT language
+// Routes are solid edges, pub/sub tags dashed edges and unknown route endpoints red dashed nodes
+func (graph *RouteGraph) DOT() string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "digraph %s {\n", dotQuote(graph.Application))
+	builder.WriteString("  rankdir=LR;\n")
+	for _, msvc := range graph.Microservices {
+		fmt.Fprintf(&builder, "  %s;\n", dotQuote(msvc))
+	}
+	for _, endpoint := range graph.danglingEndpoints() {
+		fmt.Fprintf(&builder, "  %s [style=dashed, color=red];\n", dotQuote(endpoint))
+	}
+	for _, route := range graph.Routes {
+		fmt.Fprintf(&builder, "  %s -> %s [label=%s];\n", dotQuote(route.From), dotQuote(route.To), dotQuote(route.Name))
+	}
+	for _, edge := range graph.Edges() {
+		if edge.Tag != "" {
+			fmt.Fprintf(&builder, "  %s -> %s [label=%s, style=dashed];\n", dotQuote(edge.From), dotQuote(edge.To), dotQuote(edge.Tag))
+		}
+	}
+	builder.WriteString("}\n")
+	return builder.String()
+}
+
+// Mermaid returns the graph as a Mermaid flowchart
+// Routes are solid edges, pub/sub tags dotted edges and unknown route endpoints red nodes
+func (graph *RouteGraph) Mermaid() string {
+	var builder strings.Builder
+	builder.WriteString("flowchart LR\n")
+	ids := make(map[string]string)
+	node := func(name string) {
+		ids[name] = fmt.Sprintf("n%d", len(ids))
+		fmt.Fprintf(&builder, "  %s[\"%s\"]\n", ids[name], mermaidEscape(name))
+	}
+	for _, msvc := range graph.Microservices {
+		node(msvc)
+	}
+	dangling := graph.danglingEndpoints()
+	for _, endpoint := range dangling {
+		node(endpoint)
+	}
+	for _, route := range graph.Routes {
+		fmt.Fprintf(&builder, "  %s -->|\"%s\"| %s\n", ids[route.From], mermaidEscape(route.Name), ids[route.To])
+	}
+	for _, edge := range graph.Edges() {
+		if edge.Tag != "" {
+			fmt.Fprintf(&builder, "  %s -.->|\"%s\"| %s\n", ids[edge.From], mermaidEscape(edge.Tag), ids[edge.To])
+		}
+	}
+	if len(dangling) > 0 {
+		builder.WriteString("  classDef dangling stroke:#d00,stroke-dasharray:4\n")
+		for _, endpoint := range dangling {
+			fmt.Fprintf(&builder, "  class %s dangling\n", ids[endpoint])
+		}
+	}
+	return builder.String()
+}
+
+func dotQuote(in string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(in) + `"`
+}
+
+func mermaidEscape(in string) string {
+	return strings.ReplaceAll(in, `"`, "#quot;")
+}
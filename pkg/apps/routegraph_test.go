@@ -0,0 +1,121 @@
+/*
+ *  *******************************************************************************
+ *  * Copyright (c) 2024 Datasance Teknoloji A.S.
+ *  *
+ *  * This program and the accompanying materials are made available under the
+ *  * terms of the Eclipse Public License v. 2.0 which is available at
+ *  * http://www.eclipse.org/legal/epl-2.0
+ *  *
+ *  * SPDX-License-Identifier: EPL-2.0
+ *  *******************************************************************************
+ *
+ */
+
+package apps
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func routeGraphApplication() *Application {
+	app := &Application{
+		Name: "app",
+		Microservices: []Microservice{
+			{Name: "sensor"},
+			{Name: "filter"},
+			{Name: "store"},
+			{Name: "idle"},
+		},
+		Routes: []Route{
+			{Name: "sensor-filter", From: "sensor", To: "filter"},
+			{Name: "filter-store", From: "filter", To: "store"},
+			{Name: "store-sensor", From: "store", To: "sensor"},
+			{Name: "store-missing", From: "store", To: "missing"},
+		},
+	}
+	app.Microservices[0].MsRoutes.PubTags = []string{"raw", "debug"}
+	app.Microservices[1].MsRoutes.SubTags = []string{"raw", "config"}
+	return app
+}
+
+func TestRouteGraphValidate(t *testing.T) {
+	graph := NewRouteGraph(routeGraphApplication())
+	issues := graph.Validate()
+	expected := []RouteIssue{
+		{Kind: DanglingRoute, Route: "store-missing", Microservices: []string{"missing"}},
+		{Kind: RouteCycle, Microservices: []string{"filter", "store", "sensor"}},
+		{Kind: UnreachableMicroservice, Microservices: []string{"idle"}},
+		{Kind: UnmatchedPublication, Tag: "debug", Microservices: []string{"sensor"}},
+		{Kind: UnmatchedSubscription, Tag: "config", Microservices: []string{"filter"}},
+	}
+	if !reflect.DeepEqual(issues, expected) {
+		t.Fatalf("expected %v, got %v", expected, issues)
+	}
+	for _, issue := range issues {
+		if issue.String() == "" {
+			t.Errorf("expected a description of %v", issue)
+		}
+	}
+}
+
+func TestRouteGraphValidateClean(t *testing.T) {
+	app := &Application{
+		Name:          "app",
+		Microservices: []Microservice{{Name: "a"}, {Name: "b"}, {Name: "c"}},
+		Routes:        []Route{{Name: "a-b", From: "a", To: "b"}},
+	}
+	app.Microservices[1].MsRoutes.PubTags = []string{"out"}
+	app.Microservices[2].MsRoutes.SubTags = []string{"out"}
+	if issues := NewRouteGraph(app).Validate(); len(issues) != 0 {
+		t.Errorf("expected no issue, got %v", issues)
+	}
+
+	// Independent microservices do not use routing
+	app = &Application{Name: "app", Microservices: []Microservice{{Name: "a"}, {Name: "b"}}}
+	if issues := NewRouteGraph(app).Validate(); len(issues) != 0 {
+		t.Errorf("expected no issue, got %v", issues)
+	}
+}
+
+func TestRouteGraphSelfLoop(t *testing.T) {
+	app := &Application{
+		Name:          "app",
+		Microservices: []Microservice{{Name: "a"}, {Name: "b"}},
+		Routes:        []Route{{Name: "a-a", From: "a", To: "a"}, {Name: "a-b", From: "a", To: "b"}},
+	}
+	issues := NewRouteGraph(app).Validate()
+	if len(issues) != 1 || issues[0].Kind != RouteCycle || !reflect.DeepEqual(issues[0].Microservices, []string{"a"}) {
+		t.Errorf("expected a self loop, got %v", issues)
+	}
+}
+
+func TestRouteGraphExport(t *testing.T) {
+	graph := NewRouteGraph(routeGraphApplication())
+	dot := graph.DOT()
+	for _, line := range []string{
+		`digraph "app" {`,
+		`  "missing" [style=dashed, color=red];`,
+		`  "sensor" -> "filter" [label="sensor-filter"];`,
+		`  "sensor" -> "filter" [label="raw", style=dashed];`,
+	} {
+		if !strings.Contains(dot, line+"\n") {
+			t.Errorf("expected DOT line %s in\n%s", line, dot)
+		}
+	}
+
+	mermaid := graph.Mermaid()
+	for _, line := range []string{
+		"flowchart LR",
+		`  n2["sensor"]`,
+		`  n4["missing"]`,
+		`  n2 -->|"sensor-filter"| n0`,
+		`  n2 -.->|"raw"| n0`,
+		"  class n4 dangling",
+	} {
+		if !strings.Contains(mermaid, line+"\n") {
+			t.Errorf("expected Mermaid line %s in\n%s", line, mermaid)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+/*
+ *  *******************************************************************************
+ *  * Copyright (c) 2024 Datasance Teknoloji A.S.
+ *  *
+ *  * This program and the accompanying materials are made available under the
+ *  * terms of the Eclipse Public License v. 2.0 which is available at
+ *  * http://www.eclipse.org/legal/epl-2.0
+ *  *
+ *  * SPDX-License-Identifier: EPL-2.0
+ *  *******************************************************************************
+ *
+ */
+
+package apps
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/datasance/iofog-go-sdk/v3/pkg/client"
+)
+
+// RouteUpdate is a route whose endpoints change
+type RouteUpdate struct {
+	Old Route
+	New Route
+}
+
+// RoutePlan lists the route operations bringing an application to its desired routes, each list sorted by name
+type RoutePlan struct {
+	Application string
+	Create      []Route
+	Patch       []RouteUpdate
+	Delete      []Route
+}
+
+// HasChanges returns true if reconciling the routes would change anything
+func (plan *RoutePlan) HasChanges() bool {
+	return len(plan.Create)+len(plan.Patch)+len(plan.Delete) > 0
+}
+
+// String returns one line per route operation of the plan
+func (plan *RoutePlan) String() string {
+	var builder strings.Builder
+	if !plan.HasChanges() {
+		fmt.Fprintf(&builder, "Routes of application %s are up to date\n", plan.Application)
+		return builder.String()
+	}
+	fmt.Fprintf(&builder, "Routes of application %s will be updated\n", plan.Application)
+	for _, route := range plan.Delete {
+		fmt.Fprintf(&builder, "  %s %s: %s -> %s\n", changeSymbols[ChangeRemove], route.Name, route.From, route.To)
+	}
+	for _, update := range plan.Patch {
+		fmt.Fprintf(&builder, "  %s %s: %s -> %s => %s -> %s\n", changeSymbols[ChangeUpdate], update.New.Name,
+			update.Old.From, update.Old.To, update.New.From, update.New.To)
+	}
+	for _, route := range plan.Create {
+		fmt.Fprintf(&builder, "  %s %s: %s -> %s\n", changeSymbols[ChangeAdd], route.Name, route.From, route.To)
+	}
+	return builder.String()
+}
+
+// planRoutes matches the current and desired routes by name
+func planRoutes(appName string, current, desired []Route) *RoutePlan {
+	plan := &RoutePlan{Application: appName}
+	currentByName := make(map[string]Route)
+	for _, route := range current {
+		currentByName[route.Name] = route
+	}
+	desiredByName := make(map[string]bool)
+	for _, route := range desired {
+		desiredByName[route.Name] = true
+		existing, found := currentByName[route.Name]
+		switch {
+		case !found:
+			plan.Create = append(plan.Create, route)
+		case existing.From != route.From || existing.To != route.To:
+			plan.Patch = append(plan.Patch, RouteUpdate{Old: existing, New: route})
+		}
+	}
+	for _, route := range current {
+		if !desiredByName[route.Name] {
+			plan.Delete = append(plan.Delete, route)
+		}
+	}
+	sort.Slice(plan.Create, func(i, j int) bool { return plan.Create[i].Name < plan.Create[j].Name })
+	sort.Slice(plan.Patch, func(i, j int) bool { return plan.Patch[i].New.Name < plan.Patch[j].New.Name })
+	sort.Slice(plan.Delete, func(i, j int) bool { return plan.Delete[i].Name < plan.Delete[j].Name })
+	return plan
+}
+
+type routeExecutor struct {
+	client  *client.Client
+	appName string
+	routes  []Route
+}
+
+func newRouteExecutor(clt *client.Client, appName string, routes []Route) *routeExecutor {
+	exe := &routeExecutor{
+		client:  clt,
+		appName: appName,
+		routes:  routes,
+	}
+
+	return exe
+}
+
+// plan validates the desired routes against the microservices of the application and diffs them with the deployed routes
+func (exe *routeExecutor) plan() (*RoutePlan, error) {
+	msvcs, err := exe.client.GetMicroservicesByApplication(exe.appName)
+	if isApplicationNotFound(err) {
+		msvcs, err = exe.client.GetSystemMicroservicesByApplication(exe.appName)
+		if isApplicationNotFound(err) {
+			return nil, NewNotFoundError(fmt.Sprintf("Could not find application %s", exe.appName))
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]bool)
+	for idx := range msvcs.Microservices {
+		names[msvcs.Microservices[idx].Name] = true
+	}
+	v := new(validator)
+	validateRoutes(v, exe.routes, names)
+	if err := v.result(); err != nil {
+		return nil, err
+	}
+
+	routes, err := exe.client.ListRoutes()
+	if err != nil {
+		return nil, err
+	}
+	var current []Route
+	for idx := range routes.Routes {
+		if routes.Routes[idx].Application == exe.appName {
+			current = append(current, routeFromInfo(&routes.Routes[idx]))
+		}
+	}
+	return planRoutes(exe.appName, current, exe.routes), nil
+}
+
+// execute applies the plan, deleting routes first so that their endpoints are released before new routes are created
+func (exe *routeExecutor) execute() (*RoutePlan, error) {
+	plan, err := exe.plan()
+	if err != nil {
+		return nil, err
+	}
+	for _, route := range plan.Delete {
+		if err := exe.client.DeleteRoute(exe.appName, route.Name); err != nil {
+			if _, notFound := err.(*client.NotFoundError); !notFound {
+				return plan, err
+			}
+		}
+	}
+	for _, update := range plan.Patch {
+		if err := exe.client.PatchRoute(exe.appName, update.New.Name, exe.clientRoute(update.New)); err != nil {
+			return plan, err
+		}
+	}
+	for _, route := range plan.Create {
+		if err := exe.client.CreateRoute(exe.clientRoute(route)); err != nil {
+			return plan, err
+		}
+	}
+	return plan, nil
+}
+
+func (exe *routeExecutor) clientRoute(route Route) *client.Route {
+	return &client.Route{
+		Name:        route.Name,
+		Application: exe.appName,
+		From:        route.From,
+		To:          route.To,
+	}
+}
@@ -0,0 +1,73 @@
+/*
+ *  *******************************************************************************
+ *  * Copyright (c) 2024 Datasance Teknoloji A.S.
+ *  *
+ *  * This program and the accompanying materials are made available under the
+ *  * terms of the Eclipse Public License v. 2.0 which is available at
+ *  * http://www.eclipse.org/legal/epl-2.0
+ *  *
+ *  * SPDX-License-Identifier: EPL-2.0
+ *  *******************************************************************************
+ *
+ */
+
+package apps
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPlanRoutes(t *testing.T) {
+	current := []Route{
+		{Name: "keep", From: "a", To: "b"},
+		{Name: "move", From: "a", To: "b"},
+		{Name: "old", From: "b", To: "c"},
+	}
+	desired := []Route{
+		{Name: "new", From: "c", To: "a"},
+		{Name: "move", From: "a", To: "c"},
+		{Name: "keep", From: "a", To: "b"},
+	}
+	plan := planRoutes("app", current, desired)
+	expected := &RoutePlan{
+		Application: "app",
+		Create:      []Route{{Name: "new", From: "c", To: "a"}},
+		Patch:       []RouteUpdate{{Old: Route{Name: "move", From: "a", To: "b"}, New: Route{Name: "move", From: "a", To: "c"}}},
+		Delete:      []Route{{Name: "old", From: "b", To: "c"}},
+	}
+	if !reflect.DeepEqual(plan, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, plan)
+	}
+	for _, line := range []string{"- old: b -> c", "~ move: a -> b => a -> c", "+ new: c -> a"} {
+		if !strings.Contains(plan.String(), line) {
+			t.Errorf("expected %s in\n%s", line, plan)
+		}
+	}
+
+	if plan = planRoutes("app", current, current); plan.HasChanges() {
+		t.Errorf("expected no changes, got %+v", plan)
+	}
+}
+
+func TestValidateRoutes(t *testing.T) {
+	v := new(validator)
+	validateRoutes(v, []Route{
+		{Name: "a-b", From: "a", To: "b"},
+		{Name: "a-b", From: "a", To: "missing"},
+		{Name: "self", From: "a", To: "a"},
+	}, map[string]bool{"a": true, "b": true})
+	err, ok := v.result().(*ValidationError)
+	if !ok {
+		t.Fatalf("expected a validation error, got %v", v.result())
+	}
+	var paths []string
+	for _, fieldErr := range err.Errors {
+		paths = append(paths, fieldErr.Path)
+	}
+	expected := []string{"routes[1].name", "routes[1].to", "routes[2].to"}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Errorf("expected errors on %v, got %v", expected, err)
+	}
+}
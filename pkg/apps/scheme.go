@@ -0,0 +1,389 @@
+/*
+ *  *******************************************************************************
+ *  * Copyright (c) 2024 Datasance Teknoloji A.S.
+ *  *
+ *  * This program and the accompanying materials are made available under the
+ *  * terms of the Eclipse Public License v. 2.0 which is available at
+ *  * http://www.eclipse.org/legal/epl-2.0
+ *  *
+ *  * SPDX-License-Identifier: EPL-2.0
+ *  *******************************************************************************
+ *
+ */
+
+package apps
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Supported API versions
+const (
+	APIVersion       = "datasance.com/v3"
+	IofogAPIVersion  = "iofog.org/v3"
+	LegacyAPIVersion = "iofog.org/v2"
+)
+
+// ConversionFunc converts the generic spec of a document from one API version to another
+type ConversionFunc func(spec interface{}) (interface{}, error)
+
+type schemeKey struct {
+	apiVersion string
+	kind       Kind
+}
+
+type conversionKey struct {
+	from string
+	to   string
+	kind Kind
+}
+
+// Scheme maps API versions and kinds to the Go types of their spec
+type Scheme struct {
+	types       map[schemeKey]reflect.Type
+	conversions map[conversionKey]ConversionFunc
+}
+
+// NewScheme returns an empty Scheme
+func NewScheme() *Scheme {
+	return &Scheme{
+		types:       make(map[schemeKey]reflect.Type),
+		conversions: make(map[conversionKey]ConversionFunc),
+	}
+}
+
+// DefaultScheme knows every kind of the datasance.com/v3 API and how to convert the kinds of older iofog API versions
+var DefaultScheme = newDefaultScheme()
+
+func newDefaultScheme() *Scheme {
+	scheme := NewScheme()
+	specs := map[Kind]interface{}{
+		ApplicationKind:          Application{},
+		ApplicationTemplateKind:  ApplicationTemplate{},
+		MicroserviceKind:         Microservice{},
+		RouteKind:                Route{},
+		SecretKind:               Secret{},
+		ConfigMapKind:            ConfigMap{},
+		VolumeMountKind:          VolumeMount{},
+		CertificateKind:          Certificate{},
+		CertificateAuthorityKind: CertificateAuthority{},
+		ServiceKind:              Service{},
+		RegistryKind:             Registry{},
+		CatalogItemKind:          CatalogItem{},
+		EdgeResourceKind:         EdgeResource{},
+		AgentConfigKind:          AgentConfig{},
+	}
+	for kind, spec := range specs {
+		scheme.AddKnownType(APIVersion, kind, spec)
+	}
+
+	// Kinds that existed before the datasance.com API group decode into the same types, the fields their API version
+	// does not have are rejected by the conversions
+	iofogKinds := []Kind{ApplicationKind, ApplicationTemplateKind, MicroserviceKind, RouteKind, RegistryKind, CatalogItemKind, EdgeResourceKind, AgentConfigKind}
+	for _, kind := range iofogKinds {
+		scheme.AddKnownType(IofogAPIVersion, kind, specs[kind])
+		fields := microserviceFields(kind, datasanceMicroserviceFields)
+		scheme.AddConversion(IofogAPIVersion, APIVersion, kind, unsupportedFieldsConversion(IofogAPIVersion, fields))
+		scheme.AddConversion(APIVersion, IofogAPIVersion, kind, unsupportedFieldsConversion(IofogAPIVersion, fields))
+	}
+	legacyKinds := []Kind{ApplicationKind, MicroserviceKind, RegistryKind, CatalogItemKind, AgentConfigKind}
+	for _, kind := range legacyKinds {
+		scheme.AddKnownType(LegacyAPIVersion, kind, specs[kind])
+		fields := microserviceFields(kind, append(datasanceMicroserviceFields, iofogMicroserviceFields...))
+		scheme.AddConversion(LegacyAPIVersion, APIVersion, kind, unsupportedFieldsConversion(LegacyAPIVersion, fields))
+		scheme.AddConversion(APIVersion, LegacyAPIVersion, kind, unsupportedFieldsConversion(LegacyAPIVersion, fields))
+	}
+	return scheme
+}
+
+// datasanceMicroserviceFields were added to microservices by the datasance.com/v3 API, "[]" marks the items of a list
+var datasanceMicroserviceFields = []string{
+	"msRoutes",
+	"container.pidMode",
+	"container.ipcMode",
+	"container.platform",
+	"container.runAsUser",
+	"container.cdiDevices",
+	"container.capAdd",
+	"container.capDrop",
+	"container.annotations",
+	"container.cpuSetCpus",
+	"container.memoryLimit",
+	"container.healthCheck",
+	"container.env[].valueFromSecret",
+	"container.env[].valueFromConfigMap",
+}
+
+// iofogMicroserviceFields were added to microservices by the iofog.org/v3 API
+var iofogMicroserviceFields = []string{
+	"schedule",
+	"container.runtime",
+}
+
+// microserviceFieldPrefixes locate the microservices in the spec of the kinds embedding them
+var microserviceFieldPrefixes = map[Kind]string{
+	MicroserviceKind:        "",
+	ApplicationKind:         "microservices[].",
+	ApplicationTemplateKind: "application.microservices[].",
+}
+
+// microserviceFields returns the paths of microservice fields in the spec of a kind, none if it has no microservices
+func microserviceFields(kind Kind, fields []string) (paths []string) {
+	prefix, found := microserviceFieldPrefixes[kind]
+	if !found {
+		return nil
+	}
+	for _, field := range fields {
+		paths = append(paths, prefix+field)
+	}
+	return paths
+}
+
+// unsupportedFieldsConversion converts the spec of a kind from or to an API version that does not have some of its
+// fields: fields left empty are dropped, fields that are set are rejected rather than silently lost
+func unsupportedFieldsConversion(apiVersion string, fields []string) ConversionFunc {
+	return func(spec interface{}) (interface{}, error) {
+		var set []string
+		for _, field := range fields {
+			set = append(set, stripField(spec, strings.Split(field, "."), "")...)
+		}
+		if len(set) > 0 {
+			return nil, NewInputError(fmt.Sprintf("Fields not supported by %s: %s", apiVersion, strings.Join(set, ", ")))
+		}
+		return spec, nil
+	}
+}
+
+// stripField deletes the empty values of a field from a generic spec and returns the paths where it is set
+func stripField(node interface{}, path []string, parent string) (set []string) {
+	object, ok := node.(map[interface{}]interface{})
+	if !ok {
+		return nil
+	}
+	name := strings.TrimSuffix(path[0], "[]")
+	value, found := object[name]
+	if !found {
+		return nil
+	}
+	fieldPath := joinPath(parent, name)
+	if name != path[0] {
+		items, _ := value.([]interface{})
+		for idx, item := range items {
+			set = append(set, stripField(item, path[1:], fmt.Sprintf("%s[%d]", fieldPath, idx))...)
+		}
+		return set
+	}
+	if len(path) > 1 {
+		return stripField(value, path[1:], fieldPath)
+	}
+	if isEmptyGeneric(value) {
+		delete(object, name)
+		return nil
+	}
+	return []string{fieldPath}
+}
+
+// isEmptyGeneric returns true for nil, zero values, empty lists and maps whose values are all empty
+func isEmptyGeneric(value interface{}) bool {
+	switch typed := value.(type) {
+	case nil:
+		return true
+	case []interface{}:
+		return len(typed) == 0
+	case map[interface{}]interface{}:
+		for _, item := range typed {
+			if !isEmptyGeneric(item) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.ValueOf(value).IsZero()
+}
+
+// AddKnownType registers the type of spec for a kind of an API version
+func (scheme *Scheme) AddKnownType(apiVersion string, kind Kind, spec interface{}) {
+	specType := reflect.TypeOf(spec)
+	if specType.Kind() == reflect.Ptr {
+		specType = specType.Elem()
+	}
+	scheme.types[schemeKey{apiVersion: apiVersion, kind: kind}] = specType
+}
+
+// AddConversion registers how to convert the spec of a kind from one API version to another
+func (scheme *Scheme) AddConversion(from, to string, kind Kind, conversion ConversionFunc) {
+	scheme.conversions[conversionKey{from: from, to: to, kind: kind}] = conversion
+}
+
+// Recognizes returns true if the scheme has a type for the kind of the API version
+func (scheme *Scheme) Recognizes(apiVersion string, kind Kind) bool {
+	_, found := scheme.types[schemeKey{apiVersion: apiVersion, kind: kind}]
+	return found
+}
+
+// New returns a pointer to a new spec of the type registered for the kind of the API version
+func (scheme *Scheme) New(apiVersion string, kind Kind) (interface{}, error) {
+	specType, found := scheme.types[schemeKey{apiVersion: apiVersion, kind: kind}]
+	if !found {
+		return nil, NewNotFoundError(fmt.Sprintf("No type registered for kind %s in %s", kind, apiVersion))
+	}
+	return reflect.New(specType).Interface(), nil
+}
+
+// Decode decodes a single YAML or JSON document into a Header whose Spec is a pointer to the registered type
+// Documents of older API versions that convert to datasance.com/v3 are converted
+func (scheme *Scheme) Decode(data []byte) (*Header, error) {
+	header := new(Header)
+	if err := yaml.UnmarshalStrict(data, header); err != nil {
+		return nil, NewInputError(fmt.Sprintf("Could not decode document: %s", err.Error()))
+	}
+	if err := scheme.decodeSpec(header); err != nil {
+		return nil, err
+	}
+	return header, nil
+}
+
+// DecodeAll decodes every document of a multi-document YAML stream, skipping empty documents
+func (scheme *Scheme) DecodeAll(reader io.Reader) (headers []Header, err error) {
+	decoder := yaml.NewDecoder(reader)
+	for {
+		var document interface{}
+		if err = decoder.Decode(&document); err != nil {
+			if err == io.EOF {
+				return headers, nil
+			}
+			return nil, NewInputError(fmt.Sprintf("Could not decode manifest: %s", err.Error()))
+		}
+		if document == nil {
+			continue
+		}
+		data, err := yaml.Marshal(document)
+		if err != nil {
+			return nil, err
+		}
+		header, err := scheme.Decode(data)
+		if err != nil {
+			return nil, err
+		}
+		headers = append(headers, *header)
+	}
+}
+
+// Encode encodes a document as YAML
+func (scheme *Scheme) Encode(header *Header) ([]byte, error) {
+	if header.APIVersion == "" {
+		return nil, NewInputError(fmt.Sprintf("Missing apiVersion for %s %s", header.Kind, header.Metadata.Name))
+	}
+	return yaml.Marshal(IofogHeader(*header))
+}
+
+// EncodeAll encodes documents as a multi-document YAML stream
+func (scheme *Scheme) EncodeAll(headers []Header) ([]byte, error) {
+	var buffer bytes.Buffer
+	for idx := range headers {
+		data, err := scheme.Encode(&headers[idx])
+		if err != nil {
+			return nil, err
+		}
+		if idx > 0 {
+			buffer.WriteString("---\n")
+		}
+		buffer.Write(data)
+	}
+	return buffer.Bytes(), nil
+}
+
+// Convert returns a copy of the document converted to another API version, the spec of the copy is a pointer to the
+// registered type of the target version if any
+func (scheme *Scheme) Convert(header *Header, apiVersion string) (*Header, error) {
+	conversion, found := scheme.conversions[conversionKey{from: header.APIVersion, to: apiVersion, kind: header.Kind}]
+	if header.APIVersion == apiVersion {
+		conversion, found = identityConversion, true
+	}
+	if !found {
+		return nil, NewNotFoundError(fmt.Sprintf("Cannot convert %s from %s to %s", header.Kind, header.APIVersion, apiVersion))
+	}
+	spec, err := toGeneric(header.Spec)
+	if err != nil {
+		return nil, err
+	}
+	if spec, err = conversion(spec); err != nil {
+		return nil, err
+	}
+	converted := &Header{
+		APIVersion: apiVersion,
+		Kind:       header.Kind,
+		Metadata:   header.Metadata,
+		Spec:       spec,
+	}
+	if !scheme.Recognizes(apiVersion, header.Kind) {
+		return converted, nil
+	}
+	if err = scheme.typedSpec(converted); err != nil {
+		return nil, err
+	}
+	return converted, nil
+}
+
+func identityConversion(spec interface{}) (interface{}, error) {
+	return spec, nil
+}
+
+func (scheme *Scheme) decodeSpec(header *Header) error {
+	if header.Kind == "" {
+		return NewInputError(fmt.Sprintf("Missing kind for resource %s", header.Metadata.Name))
+	}
+	if _, found := scheme.conversions[conversionKey{from: header.APIVersion, to: APIVersion, kind: header.Kind}]; found {
+		converted, err := scheme.Convert(header, APIVersion)
+		if err != nil {
+			return err
+		}
+		*header = *converted
+		return nil
+	}
+	if !scheme.Recognizes(header.APIVersion, header.Kind) {
+		return NewInputError(fmt.Sprintf("Unsupported kind %s in %s", header.Kind, header.APIVersion))
+	}
+	return scheme.typedSpec(header)
+}
+
+// typedSpec replaces the generic spec of a document with a pointer to the registered type of its kind
+func (scheme *Scheme) typedSpec(header *Header) error {
+	spec, err := scheme.New(header.APIVersion, header.Kind)
+	if err != nil {
+		return err
+	}
+	if err = decodeSpec(header, spec); err != nil {
+		return err
+	}
+	header.Spec = spec
+	return nil
+}
+
+// decodeSpec decodes the generic spec of a document into the struct matching its kind
+func decodeSpec(header *Header, spec interface{}) error {
+	specBytes, err := yaml.Marshal(header.Spec)
+	if err != nil {
+		return err
+	}
+	if err = yaml.UnmarshalStrict(specBytes, spec); err != nil {
+		return NewInputError(fmt.Sprintf("Invalid spec for %s %s: %s", header.Kind, header.Metadata.Name, err.Error()))
+	}
+	return nil
+}
+
+// toGeneric converts a typed spec into the generic representation produced by the YAML decoder
+func toGeneric(spec interface{}) (generic interface{}, err error) {
+	data, err := yaml.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+	err = yaml.Unmarshal(data, &generic)
+	return generic, err
+}
@@ -0,0 +1,198 @@
+/*
+ *  *******************************************************************************
+ *  * Copyright (c) 2024 Datasance Teknoloji A.S.
+ *  *
+ *  * This program and the accompanying materials are made available under the
+ *  * terms of the Eclipse Public License v. 2.0 which is available at
+ *  * http://www.eclipse.org/legal/epl-2.0
+ *  *
+ *  * SPDX-License-Identifier: EPL-2.0
+ *  *******************************************************************************
+ *
+ */
+
+package apps
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSchemeRoundTrip(t *testing.T) {
+	memoryLimit := int64(256)
+	header := &Header{
+		APIVersion: APIVersion,
+		Kind:       MicroserviceKind,
+		Metadata:   HeaderMetadata{Name: "app/msvc"},
+		Spec: &Microservice{
+			Name:  "msvc",
+			Agent: MicroserviceAgent{Name: "agent"},
+			Images: &MicroserviceImages{
+				X86: "nginx:latest",
+			},
+			Container: MicroserviceContainer{
+				Ports:       []MicroservicePortMapping{{Internal: 80, External: 8080}},
+				Env:         &[]MicroserviceEnvironment{{Key: "KEY", ValueFromSecret: "secret/key"}},
+				MemoryLimit: &memoryLimit,
+			},
+			Config: NestedMap{"level": "debug"},
+		},
+	}
+	data, err := DefaultScheme.Encode(header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DefaultScheme.Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msvc, ok := decoded.Spec.(*Microservice)
+	if !ok {
+		t.Fatalf("Expected microservice spec, got %T", decoded.Spec)
+	}
+	expected := header.Spec.(*Microservice)
+	if !reflect.DeepEqual(msvc.Container, expected.Container) || msvc.Images.X86 != expected.Images.X86 || msvc.Config["level"] != "debug" {
+		t.Errorf("Round trip changed the microservice: %v", msvc)
+	}
+}
+
+func TestSchemeStrictDecoding(t *testing.T) {
+	document := []byte(`apiVersion: datasance.com/v3
+kind: ConfigMap
+metadata:
+  name: config
+spec:
+  datas:
+    key: value
+`)
+	if _, err := DefaultScheme.Decode(document); err == nil {
+		t.Error("Expected unknown field to be rejected")
+	}
+
+	json := []byte(`{"apiVersion": "datasance.com/v3", "kind": "ConfigMap", "metadata": {"name": "config"}, "spec": {"data": {"key": "value"}}}`)
+	header, err := DefaultScheme.Decode(json)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header.Spec.(*ConfigMap).Data["key"] != "value" {
+		t.Errorf("Unexpected config map: %v", header.Spec)
+	}
+}
+
+func TestSchemeConversion(t *testing.T) {
+	document := []byte(`apiVersion: iofog.org/v3
+kind: Route
+metadata:
+  name: app/route
+spec:
+  name: route
+  from: first
+  to: second
+`)
+	header, err := DefaultScheme.Decode(document)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header.APIVersion != APIVersion {
+		t.Errorf("Expected document to be converted to %s, got %s", APIVersion, header.APIVersion)
+	}
+	if route, ok := header.Spec.(*Route); !ok || route.From != "first" {
+		t.Errorf("Unexpected route spec: %v", header.Spec)
+	}
+
+	legacy, err := DefaultScheme.Convert(header, IofogAPIVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if legacy.APIVersion != IofogAPIVersion {
+		t.Errorf("Expected document to be converted to %s, got %s", IofogAPIVersion, legacy.APIVersion)
+	}
+
+	secret := &Header{APIVersion: APIVersion, Kind: SecretKind, Metadata: HeaderMetadata{Name: "secret"}, Spec: &Secret{}}
+	if _, err := DefaultScheme.Convert(secret, LegacyAPIVersion); err == nil {
+		t.Error("Expected Secret conversion to iofog.org/v2 to fail")
+	}
+}
+
+func TestSchemeConversionFields(t *testing.T) {
+	memoryLimit := int64(256)
+	newMicroservice := func() *Microservice {
+		return &Microservice{
+			Name:      "msvc",
+			Agent:     MicroserviceAgent{Name: "agent"},
+			Images:    &MicroserviceImages{X86: "nginx:latest"},
+			Container: MicroserviceContainer{Ports: []MicroservicePortMapping{{Internal: 80, External: 8080}}},
+		}
+	}
+	withSecretEnv := newMicroservice()
+	withSecretEnv.Container.Env = &[]MicroserviceEnvironment{{Key: "PLAIN", Value: "value"}, {Key: "KEY", ValueFromSecret: "secret/key"}}
+	withMemoryLimit := newMicroservice()
+	withMemoryLimit.Container.MemoryLimit = &memoryLimit
+	withSchedule := newMicroservice()
+	withSchedule.Schedule = 10
+
+	testCases := []struct {
+		name       string
+		from       string
+		to         string
+		kind       Kind
+		spec       interface{}
+		unexpected string
+	}{
+		{"datasance to iofog", APIVersion, IofogAPIVersion, MicroserviceKind, newMicroservice(), ""},
+		{"datasance to iofog with secret env", APIVersion, IofogAPIVersion, MicroserviceKind, withSecretEnv, "container.env[1].valueFromSecret"},
+		{"datasance to iofog with memory limit", APIVersion, IofogAPIVersion, MicroserviceKind, withMemoryLimit, "container.memoryLimit"},
+		{"datasance to iofog with schedule", APIVersion, IofogAPIVersion, MicroserviceKind, withSchedule, ""},
+		{"datasance to legacy", APIVersion, LegacyAPIVersion, MicroserviceKind, newMicroservice(), ""},
+		{"datasance to legacy with schedule", APIVersion, LegacyAPIVersion, MicroserviceKind, withSchedule, "schedule"},
+		{"datasance to legacy application", APIVersion, LegacyAPIVersion, ApplicationKind, &Application{Name: "app", Microservices: []Microservice{*withMemoryLimit}}, "microservices[0].container.memoryLimit"},
+		{"iofog to datasance", IofogAPIVersion, APIVersion, MicroserviceKind, newMicroservice(), ""},
+		{"iofog to datasance with secret env", IofogAPIVersion, APIVersion, MicroserviceKind, withSecretEnv, "container.env[1].valueFromSecret"},
+		{"iofog to datasance template", IofogAPIVersion, APIVersion, ApplicationTemplateKind, &ApplicationTemplate{Name: "template", Application: &ApplicationTemplateInfo{Microservices: []Microservice{*withMemoryLimit}}}, "application.microservices[0].container.memoryLimit"},
+		{"legacy to datasance", LegacyAPIVersion, APIVersion, MicroserviceKind, newMicroservice(), ""},
+		{"legacy to datasance with schedule", LegacyAPIVersion, APIVersion, MicroserviceKind, withSchedule, "schedule"},
+		{"iofog route to datasance", IofogAPIVersion, APIVersion, RouteKind, &Route{Name: "route", From: "first", To: "second"}, ""},
+	}
+	for _, testCase := range testCases {
+		header := &Header{APIVersion: testCase.from, Kind: testCase.kind, Metadata: HeaderMetadata{Name: "app/msvc"}, Spec: testCase.spec}
+		converted, err := DefaultScheme.Convert(header, testCase.to)
+		if testCase.unexpected != "" {
+			if err == nil || !strings.Contains(err.Error(), testCase.unexpected) {
+				t.Errorf("%s: expected %s to be rejected, got %v", testCase.name, testCase.unexpected, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %s", testCase.name, err.Error())
+			continue
+		}
+		if converted.APIVersion != testCase.to {
+			t.Errorf("%s: expected %s, got %s", testCase.name, testCase.to, converted.APIVersion)
+		}
+		if reflect.TypeOf(converted.Spec) != reflect.TypeOf(testCase.spec) {
+			t.Errorf("%s: expected %T spec, got %T", testCase.name, testCase.spec, converted.Spec)
+		}
+	}
+}
+
+func TestSchemeConvertCopies(t *testing.T) {
+	header := &Header{
+		APIVersion: APIVersion,
+		Kind:       RouteKind,
+		Metadata:   HeaderMetadata{Name: "app/route"},
+		Spec:       &Route{Name: "route", From: "first", To: "second"},
+	}
+	converted, err := DefaultScheme.Convert(header, APIVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if converted == header || converted.Spec == header.Spec {
+		t.Fatal("Expected Convert to return a copy")
+	}
+	converted.Metadata.Name = "app/other"
+	converted.Spec.(*Route).To = "third"
+	if header.Metadata.Name != "app/route" || header.Spec.(*Route).To != "second" {
+		t.Errorf("Modifying the copy changed the document: %v", header)
+	}
+}
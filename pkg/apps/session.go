@@ -0,0 +1,111 @@
+/*
+ *  *******************************************************************************
+ *  * Copyright (c) 2024 Datasance Teknoloji A.S.
+ *  *
+ *  * This program and the accompanying materials are made available under the
+ *  * terms of the Eclipse Public License v. 2.0 which is available at
+ *  * http://www.eclipse.org/legal/epl-2.0
+ *  *
+ *  * SPDX-License-Identifier: EPL-2.0
+ *  *******************************************************************************
+ *
+ */
+
+package apps
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/datasance/iofog-go-sdk/v3/pkg/client"
+)
+
+// tokenRefreshMargin is how long before its expiration an access token is refreshed
+const tokenRefreshMargin = 30 * time.Second
+
+// SessionManager caches one authenticated Controller client per IofogController
+// It is safe for concurrent use, a returned client is never modified and a refreshed session gets a new client
+type SessionManager struct {
+	mutex    sync.Mutex
+	sessions map[IofogController]*client.Client
+	now      func() time.Time
+}
+
+// NewSessionManager returns an empty session manager
+func NewSessionManager() *SessionManager {
+	return &SessionManager{
+		sessions: make(map[IofogController]*client.Client),
+		now:      time.Now,
+	}
+}
+
+// Client returns the cached client of the controller, logging in on first use
+// The access token is refreshed when it is about to expire, and the client logs in again if the refresh fails
+func (sm *SessionManager) Client(controller IofogController) (*client.Client, error) {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
+	clt, found := sm.sessions[controller]
+	if found && !sm.expiring(clt.GetAccessToken()) {
+		return clt, nil
+	}
+	if found && clt.GetRefreshToken() != "" {
+		// The cached client may be in use, the tokens are refreshed on a new client that replaces it
+		if refreshed, err := refreshClient(controller, clt.GetRefreshToken()); err == nil {
+			sm.sessions[controller] = refreshed
+			return refreshed, nil
+		}
+	}
+	clt, err := newClient(controller)
+	if err != nil {
+		delete(sm.sessions, controller)
+		return nil, err
+	}
+	sm.sessions[controller] = clt
+	return clt, nil
+}
+
+// refreshClient returns a new client of the controller logged in with the refresh token
+func refreshClient(controller IofogController, refreshToken string) (*client.Client, error) {
+	baseURL, err := url.Parse(controller.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf(errParseControllerURL, err.Error())
+	}
+	clt := client.New(client.Options{BaseURL: baseURL})
+	if err = clt.Refresh(client.RefreshTokenRequest{RefreshToken: refreshToken}); err != nil {
+		return nil, err
+	}
+	return clt, nil
+}
+
+// Invalidate drops the cached client of the controller, the next call to Client logs in again
+func (sm *SessionManager) Invalidate(controller IofogController) {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+	delete(sm.sessions, controller)
+}
+
+// expiring returns whether the JWT access token expires within tokenRefreshMargin
+// Tokens that are not JWTs, or have no expiration, are assumed valid
+func (sm *SessionManager) expiring(token string) bool {
+	parts := strings.Split(strings.TrimPrefix(token, "Bearer "), ".")
+	if len(parts) != 3 {
+		return false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return false
+	}
+	claims := struct {
+		Expiration int64 `json:"exp"`
+	}{}
+	if err = json.Unmarshal(payload, &claims); err != nil || claims.Expiration == 0 {
+		return false
+	}
+	return sm.now().Add(tokenRefreshMargin).After(time.Unix(claims.Expiration, 0))
+}
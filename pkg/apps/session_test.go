@@ -0,0 +1,103 @@
+/*
+ *  *******************************************************************************
+ *  * Copyright (c) 2024 Datasance Teknoloji A.S.
+ *  *
+ *  * This program and the accompanying materials are made available under the
+ *  * terms of the Eclipse Public License v. 2.0 which is available at
+ *  * http://www.eclipse.org/legal/epl-2.0
+ *  *
+ *  * SPDX-License-Identifier: EPL-2.0
+ *  *******************************************************************************
+ *
+ */
+
+package apps
+
+import (
+	"encoding/base64"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/datasance/iofog-go-sdk/v3/pkg/client"
+)
+
+func testToken(expiration time.Time) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"exp":%d}`, expiration.Unix())))
+	return "header." + payload + ".signature"
+}
+
+func TestSessionTokenExpiration(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	sm := NewSessionManager()
+	sm.now = func() time.Time { return now }
+
+	if sm.expiring(testToken(now.Add(time.Hour))) {
+		t.Error("Expected token valid for an hour not to be expiring")
+	}
+	if !sm.expiring(testToken(now.Add(10 * time.Second))) {
+		t.Error("Expected token valid for 10 seconds to be expiring")
+	}
+	if sm.expiring("opaque-token") {
+		t.Error("Expected opaque token to be assumed valid")
+	}
+}
+
+func TestSessionReuse(t *testing.T) {
+	controller := IofogController{Endpoint: "http://controller:51121", Email: "user@domain.com"}
+	cached := new(client.Client)
+	cached.SetAccessToken(testToken(time.Now().Add(time.Hour)))
+
+	sm := NewSessionManager()
+	sm.sessions[controller] = cached
+	for i := 0; i < 3; i++ {
+		clt, err := sm.Client(controller)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if clt != cached {
+			t.Error("Expected cached client to be reused")
+		}
+	}
+
+	sm.Invalidate(controller)
+	if _, found := sm.sessions[controller]; found {
+		t.Error("Expected session to be dropped")
+	}
+}
+
+// TestSessionConcurrentRefresh detects a client modified while in use when run with -race
+func TestSessionConcurrentRefresh(t *testing.T) {
+	// Every refreshed token is about to expire, so that each call to Client refreshes while other clients are in use
+	expiring := testToken(time.Now().Add(10 * time.Second))
+	clt, _ := newFakeController(t, map[string]interface{}{
+		"POST /user/refresh": client.LoginResponse{AccessToken: expiring, RefreshToken: "refresh"},
+	})
+	controller := IofogController{Endpoint: clt.GetBaseURL(), Email: "user@domain.com"}
+	clt.SetAccessToken(expiring)
+	clt.SetRefreshToken("refresh")
+
+	sm := NewSessionManager()
+	sm.sessions[controller] = clt
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 5; j++ {
+				session, err := sm.Client(controller)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				// The client is used while other calls refresh the session
+				_, _ = session.GetApplicationByName("app")
+				if token := session.GetAccessToken(); token != expiring {
+					t.Errorf("Expected refreshed access token, got %s", token)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
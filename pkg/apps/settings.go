@@ -0,0 +1,324 @@
+/*
+ *  *******************************************************************************
+ *  * Copyright (c) 2024 Datasance Teknoloji A.S.
+ *  *
+ *  * This program and the accompanying materials are made available under the
+ *  * terms of the Eclipse Public License v. 2.0 which is available at
+ *  * http://www.eclipse.org/legal/epl-2.0
+ *  *
+ *  * SPDX-License-Identifier: EPL-2.0
+ *  *******************************************************************************
+ *
+ */
+
+package apps
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/datasance/iofog-go-sdk/v3/pkg/client"
+)
+
+// MicroserviceSettings are the lists of a microservice reconciled in place, nil lists are left unmanaged
+// An empty, non nil list removes every item
+type MicroserviceSettings struct {
+	Ports      []client.MicroservicePortMappingInfo
+	Env        []client.MicroserviceEnvironmentInfo
+	Volumes    []client.MicroserviceVolumeMappingInfo
+	ExtraHosts []client.MicroserviceExtraHost
+}
+
+// SettingsPlan lists the changes reconciling the settings of a microservice applies
+// Paths are keyed like application plans, e.g. ports[80], env[KEY].value or volumes[/data]
+type SettingsPlan struct {
+	Application  string
+	Microservice string
+	Changes      []Change
+}
+
+// HasChanges returns true if reconciling the settings would change anything
+func (plan *SettingsPlan) HasChanges() bool {
+	return len(plan.Changes) > 0
+}
+
+// String returns a human readable description of the plan
+func (plan *SettingsPlan) String() string {
+	var builder strings.Builder
+	if !plan.HasChanges() {
+		fmt.Fprintf(&builder, "Microservice %s/%s is up to date\n", plan.Application, plan.Microservice)
+		return builder.String()
+	}
+	fmt.Fprintf(&builder, "Microservice %s/%s will be updated\n", plan.Application, plan.Microservice)
+	for _, change := range plan.Changes {
+		builder.WriteString("  " + change.String() + "\n")
+	}
+	return builder.String()
+}
+
+// changed returns whether a change touches the list
+func (plan *SettingsPlan) changed(field string) bool {
+	for _, change := range plan.Changes {
+		if strings.HasPrefix(change.Path, field+"[") {
+			return true
+		}
+	}
+	return false
+}
+
+// keyedItems returns the items of a list keyed like normalizeMicroservice keys container lists
+// Port protocols are lower cased and tcp, the default, is omitted so that both spellings compare equal
+func keyedItems(field string, items interface{}) (map[string]interface{}, error) {
+	data, err := json.Marshal(items)
+	if err != nil {
+		return nil, err
+	}
+	var list []map[string]interface{}
+	if err = json.Unmarshal(data, &list); err != nil {
+		return nil, err
+	}
+	keyed := make(map[string]interface{}, len(list))
+	for _, item := range list {
+		if field == "ports" {
+			protocol, _ := item["protocol"].(string)
+			if protocol = strings.ToLower(protocol); protocol == "" || protocol == "tcp" {
+				delete(item, "protocol")
+			} else {
+				item["protocol"] = protocol
+			}
+		}
+		key := fmt.Sprintf("[%s]", listKeys[field](item))
+		if _, found := keyed[key]; found {
+			return nil, NewInputError(fmt.Sprintf("Duplicate %s item %s", field, key))
+		}
+		keyed[key] = item
+	}
+	return keyed, nil
+}
+
+// diffSettings returns the changes between the current microservice and the managed lists of the desired settings
+func diffSettings(current *client.MicroserviceInfo, desired *MicroserviceSettings) ([]Change, error) {
+	var changes []Change
+	for _, list := range []struct {
+		field            string
+		managed          bool
+		current, desired interface{}
+	}{
+		{"ports", desired.Ports != nil, current.Ports, desired.Ports},
+		{"env", desired.Env != nil, current.Env, desired.Env},
+		{"volumes", desired.Volumes != nil, current.Volumes, desired.Volumes},
+		{"extraHosts", desired.ExtraHosts != nil, current.ExtraHosts, desired.ExtraHosts},
+	} {
+		if !list.managed {
+			continue
+		}
+		currentItems, err := keyedItems(list.field, list.current)
+		if err != nil {
+			return nil, err
+		}
+		desiredItems, err := keyedItems(list.field, list.desired)
+		if err != nil {
+			return nil, err
+		}
+		diffGeneric(list.field, currentItems, desiredItems, &changes)
+	}
+	return changes, nil
+}
+
+type settingsExecutor struct {
+	client   *client.Client
+	appName  string
+	name     string
+	settings MicroserviceSettings
+	current  *client.MicroserviceInfo
+}
+
+func newSettingsExecutor(clt *client.Client, appName, name string, settings MicroserviceSettings) *settingsExecutor {
+	exe := &settingsExecutor{
+		client:   clt,
+		appName:  appName,
+		name:     name,
+		settings: settings,
+	}
+
+	return exe
+}
+
+func (exe *settingsExecutor) plan() (*SettingsPlan, error) {
+	msvc := newMicroserviceExecutor(IofogController{}, nil, exe.appName, exe.name)
+	msvc.client = exe.client
+	if err := msvc.lookup(); err != nil {
+		return nil, err
+	}
+	if msvc.uuid == "" {
+		return nil, NewNotFoundError(fmt.Sprintf("Could not find microservice %s/%s", exe.appName, exe.name))
+	}
+	if msvc.isSystem {
+		return nil, NewInputError(fmt.Sprintf("Microservice %s/%s belongs to a system application, its settings cannot be reconciled", exe.appName, exe.name))
+	}
+	current, err := exe.client.GetMicroserviceByID(msvc.uuid)
+	if err != nil {
+		return nil, err
+	}
+	exe.current = current
+
+	changes, err := diffSettings(current, &exe.settings)
+	if err != nil {
+		return nil, err
+	}
+	if exe.settings.Ports != nil {
+		if err := checkPortChanges(current.Ports, exe.settings.Ports); err != nil {
+			return nil, err
+		}
+	}
+	return &SettingsPlan{
+		Application:  exe.appName,
+		Microservice: exe.name,
+		Changes:      changes,
+	}, nil
+}
+
+// execute applies the plan: changed port mappings one by one, then the other changed lists in a single update
+// Unchanged lists are not sent, and nothing is sent when the microservice is up to date, so that its container is only
+// rebuilt when the Controller requires it
+func (exe *settingsExecutor) execute() (*SettingsPlan, error) {
+	plan, err := exe.plan()
+	if err != nil {
+		return nil, err
+	}
+	if !plan.HasChanges() {
+		return plan, nil
+	}
+	uuid := exe.current.UUID
+
+	if plan.changed("ports") {
+		if err := exe.reconcilePorts(uuid); err != nil {
+			return plan, err
+		}
+	}
+
+	request := new(client.MicroserviceUpdateRequest)
+	update := false
+	if plan.changed("env") {
+		env := append([]client.MicroserviceEnvironmentInfo{}, exe.settings.Env...)
+		request.Env = &env
+		update = true
+	}
+	if plan.changed("volumes") {
+		volumes := append([]client.MicroserviceVolumeMappingInfo{}, exe.settings.Volumes...)
+		request.Volumes = &volumes
+		update = true
+	}
+	if plan.changed("extraHosts") {
+		extraHosts := append([]client.MicroserviceExtraHost{}, exe.settings.ExtraHosts...)
+		request.ExtraHosts = &extraHosts
+		update = true
+	}
+	if update {
+		if _, err := exe.client.UpdateMicroservice(uuid, request); err != nil {
+			return plan, err
+		}
+	}
+	return plan, nil
+}
+
+// reconcilePorts deletes the removed and changed port mappings, then creates the added and changed ones, in key order
+func (exe *settingsExecutor) reconcilePorts(uuid string) error {
+	current, err := portsByKey(exe.current.Ports)
+	if err != nil {
+		return err
+	}
+	desired, err := portsByKey(exe.settings.Ports)
+	if err != nil {
+		return err
+	}
+	for _, key := range sortedPortKeys(current) {
+		port := current[key]
+		if desiredPort, found := desired[key]; !found || desiredPort.External != port.External {
+			if err := exe.client.DeleteMicroservicePortMapping(uuid, &port); err != nil {
+				return err
+			}
+		}
+	}
+	for _, key := range sortedPortKeys(desired) {
+		port := desired[key]
+		if currentPort, found := current[key]; !found || currentPort.External != port.External {
+			if err := exe.client.CreateMicroservicePortMapping(uuid, &port); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkPortChanges refuses to change a port mapping whose internal port is also mapped with another protocol
+// The Controller deletes port mappings by internal port only, replacing 53/udp would also delete 53/tcp
+func checkPortChanges(currentPorts, desiredPorts []client.MicroservicePortMappingInfo) error {
+	current, err := portsByKey(currentPorts)
+	if err != nil {
+		return err
+	}
+	desired, err := portsByKey(desiredPorts)
+	if err != nil {
+		return err
+	}
+	shared := sharedInternalPorts(current)
+	for internal := range sharedInternalPorts(desired) {
+		shared[internal] = true
+	}
+	for _, key := range sortedPortKeys(current) {
+		port := current[key]
+		desiredPort, found := desired[key]
+		if found && desiredPort.External == port.External {
+			continue
+		}
+		if shared[port.Internal] {
+			return NewInputError(fmt.Sprintf("Cannot change port mapping %s, internal port %d is mapped with several protocols and the Controller would delete every mapping of it", key, port.Internal))
+		}
+	}
+	for _, key := range sortedPortKeys(desired) {
+		port := desired[key]
+		if _, found := current[key]; found {
+			continue
+		}
+		if shared[port.Internal] {
+			return NewInputError(fmt.Sprintf("Cannot add port mapping %s, internal port %d would be mapped with several protocols, which the Controller cannot replace separately", key, port.Internal))
+		}
+	}
+	return nil
+}
+
+func portsByKey(ports []client.MicroservicePortMappingInfo) (map[string]client.MicroservicePortMappingInfo, error) {
+	byKey := make(map[string]client.MicroservicePortMappingInfo, len(ports))
+	for _, port := range ports {
+		item, err := toJSONMap(port)
+		if err != nil {
+			return nil, err
+		}
+		byKey[listKeys["ports"](item)] = port
+	}
+	return byKey, nil
+}
+
+// sharedInternalPorts returns the internal ports mapped with several protocols
+func sharedInternalPorts(ports map[string]client.MicroservicePortMappingInfo) map[int64]bool {
+	count := make(map[int64]int)
+	shared := make(map[int64]bool)
+	for _, port := range ports {
+		if count[port.Internal]++; count[port.Internal] > 1 {
+			shared[port.Internal] = true
+		}
+	}
+	return shared
+}
+
+func sortedPortKeys(ports map[string]client.MicroservicePortMappingInfo) []string {
+	keys := make([]string, 0, len(ports))
+	for key := range ports {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
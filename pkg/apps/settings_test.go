@@ -0,0 +1,106 @@
+/*
+ *  *******************************************************************************
+ *  * Copyright (c) 2024 Datasance Teknoloji A.S.
+ *  *
+ *  * This program and the accompanying materials are made available under the
+ *  * terms of the Eclipse Public License v. 2.0 which is available at
+ *  * http://www.eclipse.org/legal/epl-2.0
+ *  *
+ *  * SPDX-License-Identifier: EPL-2.0
+ *  *******************************************************************************
+ *
+ */
+
+package apps
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/datasance/iofog-go-sdk/v3/pkg/client"
+)
+
+func TestDiffSettings(t *testing.T) {
+	current := &client.MicroserviceInfo{
+		Ports: []client.MicroservicePortMappingInfo{
+			{Internal: 80, External: 8080, Protocol: "tcp"},
+			{Internal: 53, External: 53, Protocol: "udp"},
+		},
+		Env: []client.MicroserviceEnvironmentInfo{
+			{Key: "B", Value: "2"},
+			{Key: "A", Value: "1"},
+		},
+		Volumes: []client.MicroserviceVolumeMappingInfo{
+			{HostDestination: "/var/data", ContainerDestination: "/data", AccessMode: "rw"},
+		},
+		ExtraHosts: []client.MicroserviceExtraHost{{Name: "db", Address: "10.0.0.1"}},
+	}
+	desired := &MicroserviceSettings{
+		Ports: []client.MicroservicePortMappingInfo{
+			{Internal: 80, External: 8080},
+			{Internal: 53, External: 5353, Protocol: "UDP"},
+		},
+		// Reordered, unchanged
+		Env: []client.MicroserviceEnvironmentInfo{
+			{Key: "A", Value: "1"},
+			{Key: "B", Value: "2"},
+		},
+		Volumes: []client.MicroserviceVolumeMappingInfo{},
+	}
+	changes, err := diffSettings(current, desired)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, change := range changes {
+		paths = append(paths, string(change.Action)+" "+change.Path)
+	}
+	expected := []string{"Update ports[53/udp].external", "Remove volumes[/data]"}
+	if !reflect.DeepEqual(paths, expected) {
+		t.Errorf("expected %v, got %v", expected, paths)
+	}
+
+	plan := &SettingsPlan{Changes: changes}
+	if !plan.changed("ports") || !plan.changed("volumes") || plan.changed("env") || plan.changed("extraHosts") {
+		t.Errorf("unexpected changed lists for %v", paths)
+	}
+}
+
+func TestDiffSettingsDuplicate(t *testing.T) {
+	desired := &MicroserviceSettings{Env: []client.MicroserviceEnvironmentInfo{{Key: "A"}, {Key: "A"}}}
+	if _, err := diffSettings(&client.MicroserviceInfo{}, desired); err == nil {
+		t.Error("expected an error for duplicate env keys")
+	}
+}
+
+func TestCheckPortChanges(t *testing.T) {
+	dnsTCP := client.MicroservicePortMappingInfo{Internal: 53, External: 53, Protocol: "tcp"}
+	dnsUDP := client.MicroservicePortMappingInfo{Internal: 53, External: 53, Protocol: "udp"}
+	web := client.MicroservicePortMappingInfo{Internal: 80, External: 8080}
+	movedUDP := dnsUDP
+	movedUDP.External = 5353
+	movedWeb := web
+	movedWeb.External = 9090
+
+	testCases := []struct {
+		name             string
+		current, desired []client.MicroservicePortMappingInfo
+		valid            bool
+	}{
+		{"unchanged shared port", []client.MicroservicePortMappingInfo{dnsTCP, dnsUDP, web}, []client.MicroservicePortMappingInfo{dnsTCP, dnsUDP, movedWeb}, true},
+		{"changed shared port", []client.MicroservicePortMappingInfo{dnsTCP, dnsUDP}, []client.MicroservicePortMappingInfo{dnsTCP, movedUDP}, false},
+		{"removed shared port", []client.MicroservicePortMappingInfo{dnsTCP, dnsUDP}, []client.MicroservicePortMappingInfo{dnsTCP}, false},
+		{"added shared port", []client.MicroservicePortMappingInfo{dnsTCP}, []client.MicroservicePortMappingInfo{dnsTCP, dnsUDP}, false},
+		{"replaced protocol", []client.MicroservicePortMappingInfo{dnsTCP}, []client.MicroservicePortMappingInfo{dnsUDP}, true},
+		{"single protocol", []client.MicroservicePortMappingInfo{dnsUDP}, []client.MicroservicePortMappingInfo{movedUDP}, true},
+	}
+	for _, testCase := range testCases {
+		err := checkPortChanges(testCase.current, testCase.desired)
+		if testCase.valid && err != nil {
+			t.Errorf("%s: %s", testCase.name, err.Error())
+		}
+		if _, ok := err.(*InputError); !testCase.valid && !ok {
+			t.Errorf("%s: expected an input error, got %v", testCase.name, err)
+		}
+	}
+}
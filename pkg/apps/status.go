@@ -0,0 +1,273 @@
+/*
+ *  *******************************************************************************
+ *  * Copyright (c) 2024 Datasance Teknoloji A.S.
+ *  *
+ *  * This program and the accompanying materials are made available under the
+ *  * terms of the Eclipse Public License v. 2.0 which is available at
+ *  * http://www.eclipse.org/legal/epl-2.0
+ *  *
+ *  * SPDX-License-Identifier: EPL-2.0
+ *  *******************************************************************************
+ *
+ */
+
+package apps
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/datasance/iofog-go-sdk/v3/pkg/client"
+)
+
+// ApplicationPhase summarizes the state of an application or of one of its microservices
+type ApplicationPhase string
+
+const (
+	// ApplicationPending is waiting to be scheduled, or stopped
+	ApplicationPending ApplicationPhase = "Pending"
+	// ApplicationDeploying is pulling images or starting containers
+	ApplicationDeploying ApplicationPhase = "Deploying"
+	// ApplicationRunning has every microservice running, healthy and on a connected Agent
+	ApplicationRunning ApplicationPhase = "Running"
+	// ApplicationDegraded has microservices that are unhealthy, stopped or on a disconnected Agent
+	ApplicationDegraded ApplicationPhase = "Degraded"
+	// ApplicationFailed has failed microservices
+	ApplicationFailed ApplicationPhase = "Failed"
+)
+
+// phaseSeverity orders phases from the best to the worst
+var phaseSeverity = map[ApplicationPhase]int{
+	ApplicationRunning:   0,
+	ApplicationPending:   1,
+	ApplicationDeploying: 2,
+	ApplicationDegraded:  3,
+	ApplicationFailed:    4,
+}
+
+// Microservice states reported by the Controller while a microservice is deployed
+var microserviceDeployingStates = map[string]bool{
+	"PULLING":    true,
+	"CREATING":   true,
+	"STARTING":   true,
+	"RESTARTING": true,
+	"UPDATING":   true,
+	"STOPPING":   true,
+	"DELETING":   true,
+}
+
+// Microservice states reported by the Controller before a microservice is scheduled on its Agent
+var microservicePendingStates = map[string]bool{
+	"":       true,
+	"QUEUED": true,
+}
+
+// agentRunning is the daemon status of a connected Agent
+const agentRunning = "RUNNING"
+
+// microserviceStarting is the health status of a container whose health check did not pass yet
+const microserviceStarting = "starting"
+
+// MicroserviceStatus is the state of a microservice of an application
+type MicroserviceStatus struct {
+	Name         string
+	UUID         string
+	Agent        string
+	AgentStatus  string
+	Phase        ApplicationPhase
+	Status       string
+	HealthStatus string
+	// Percentage of the image pull
+	Percentage float64
+	Error      string
+	// ExecSessions is the number of open exec sessions
+	ExecSessions int
+	// Reasons explain why the microservice is not running
+	Reasons []string
+}
+
+// ApplicationStatus is the state of an application and of its microservices
+type ApplicationStatus struct {
+	Name          string
+	System        bool
+	Active        bool
+	Phase         ApplicationPhase
+	Microservices []MicroserviceStatus
+	// Reasons explain why the application is not running
+	Reasons []string
+}
+
+// String returns a human readable description of the status
+func (status *ApplicationStatus) String() string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "Application %s: %s\n", status.Name, status.Phase)
+	for _, reason := range status.Reasons {
+		fmt.Fprintf(&builder, "  %s\n", reason)
+	}
+	for idx := range status.Microservices {
+		msvc := &status.Microservices[idx]
+		fmt.Fprintf(&builder, "  %s: %s", msvc.Name, msvc.Phase)
+		if len(msvc.Reasons) > 0 {
+			fmt.Fprintf(&builder, " (%s)", strings.Join(msvc.Reasons, ", "))
+		}
+		builder.WriteString("\n")
+	}
+	return builder.String()
+}
+
+// applicationStatus fetches the application, its microservices and their Agents, trying system applications when the application is not found
+func applicationStatus(clt *client.Client, name string) (*ApplicationStatus, error) {
+	info, err := clt.GetApplicationByName(name)
+	var msvcs *client.MicroserviceListResponse
+	switch {
+	case err == nil:
+		msvcs, err = clt.GetMicroservicesByApplication(name)
+	case isApplicationNotFound(err):
+		if info, err = clt.GetSystemApplicationByName(name); err != nil {
+			if isApplicationNotFound(err) {
+				return nil, NewNotFoundError(fmt.Sprintf("Could not find application %s", name))
+			}
+			return nil, err
+		}
+		info.IsSystem = true
+		msvcs, err = clt.GetSystemMicroservicesByApplication(name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	agents := make(map[string]*client.AgentInfo)
+	for idx := range msvcs.Microservices {
+		uuid := msvcs.Microservices[idx].AgentUUID
+		if _, found := agents[uuid]; found || uuid == "" {
+			continue
+		}
+		agent, err := clt.GetAgentByID(uuid)
+		if err != nil {
+			if _, notFound := err.(*client.NotFoundError); !notFound {
+				return nil, err
+			}
+		}
+		agents[uuid] = agent
+	}
+	return aggregateStatus(info, msvcs.Microservices, agents), nil
+}
+
+// aggregateStatus computes the phase of every microservice, and of the application from the worst of them
+// agents is keyed by UUID, a nil Agent is missing
+func aggregateStatus(info *client.ApplicationInfo, msvcs []client.MicroserviceInfo, agents map[string]*client.AgentInfo) *ApplicationStatus {
+	status := &ApplicationStatus{
+		Name:   info.Name,
+		System: info.IsSystem,
+		Active: info.IsActivated,
+	}
+	for idx := range msvcs {
+		status.Microservices = append(status.Microservices, microserviceStatus(&msvcs[idx], agents[msvcs[idx].AgentUUID], info.IsActivated))
+	}
+
+	switch {
+	case !info.IsActivated:
+		status.Phase = ApplicationPending
+		status.Reasons = append(status.Reasons, "application is not activated")
+		return status
+	case len(msvcs) == 0:
+		status.Phase = ApplicationPending
+		status.Reasons = append(status.Reasons, "application has no microservices")
+		return status
+	}
+
+	status.Phase = ApplicationRunning
+	pending := 0
+	for idx := range status.Microservices {
+		msvc := &status.Microservices[idx]
+		if msvc.Phase == ApplicationPending {
+			pending++
+		}
+		if phaseSeverity[msvc.Phase] > phaseSeverity[status.Phase] {
+			status.Phase = msvc.Phase
+		}
+		for _, reason := range msvc.Reasons {
+			status.Reasons = append(status.Reasons, fmt.Sprintf("microservice %s %s", msvc.Name, reason))
+		}
+	}
+	// Some microservices are scheduled while others are still waiting
+	if status.Phase == ApplicationPending && pending < len(status.Microservices) {
+		status.Phase = ApplicationDeploying
+	}
+	return status
+}
+
+// microserviceStatus computes the phase of a microservice and the reasons it is not running
+func microserviceStatus(info *client.MicroserviceInfo, agent *client.AgentInfo, active bool) MicroserviceStatus {
+	msvc := MicroserviceStatus{
+		Name:         info.Name,
+		UUID:         info.UUID,
+		Status:       info.Status.Status,
+		HealthStatus: info.Status.HealthStatus,
+		Percentage:   info.Status.Percentage,
+		Error:        info.Status.ErrorMessage,
+		ExecSessions: len(info.Status.ExecSessionIDs),
+	}
+	agentConnected := false
+	switch {
+	case agent == nil:
+		msvc.Reasons = append(msvc.Reasons, "is not on a known agent")
+	default:
+		msvc.Agent = agent.Name
+		msvc.AgentStatus = agent.DaemonStatus
+		agentConnected = agent.DaemonStatus == agentRunning
+		if !agentConnected {
+			daemonStatus := strings.ToLower(agent.DaemonStatus)
+			if daemonStatus == "" {
+				daemonStatus = "unknown"
+			}
+			msvc.Reasons = append(msvc.Reasons, fmt.Sprintf("is on agent %s which is %s", agent.Name, daemonStatus))
+		}
+	}
+
+	status := info.Status.Status
+	switch {
+	case status == microserviceFailed:
+		msvc.Phase = ApplicationFailed
+		reason := "failed"
+		if msvc.Error != "" {
+			reason += ": " + msvc.Error
+		}
+		msvc.Reasons = append(msvc.Reasons, reason)
+	case status == microserviceRunning:
+		switch info.Status.HealthStatus {
+		case microserviceUnhealthy:
+			msvc.Phase = ApplicationDegraded
+			msvc.Reasons = append(msvc.Reasons, "is unhealthy")
+		case microserviceStarting:
+			msvc.Phase = ApplicationDeploying
+			msvc.Reasons = append(msvc.Reasons, "health check has not passed yet")
+		default:
+			msvc.Phase = ApplicationRunning
+		}
+		if msvc.Error != "" {
+			msvc.Reasons = append(msvc.Reasons, "reported an error: "+msvc.Error)
+		}
+	case microserviceDeployingStates[status]:
+		msvc.Phase = ApplicationDeploying
+		reason := "is " + strings.ToLower(status)
+		if msvc.Percentage > 0 && msvc.Percentage < 100 {
+			reason += fmt.Sprintf(" %.0f%%", msvc.Percentage)
+		}
+		msvc.Reasons = append(msvc.Reasons, reason)
+	case microservicePendingStates[status]:
+		msvc.Phase = ApplicationPending
+		msvc.Reasons = append(msvc.Reasons, "is waiting to be deployed")
+	default:
+		msvc.Phase = ApplicationDegraded
+		if !active {
+			msvc.Phase = ApplicationPending
+		}
+		msvc.Reasons = append(msvc.Reasons, "is "+strings.ToLower(status))
+	}
+	// A running microservice on a disconnected Agent has an outdated status
+	if !agentConnected && msvc.Phase == ApplicationRunning {
+		msvc.Phase = ApplicationDegraded
+	}
+	return msvc
+}
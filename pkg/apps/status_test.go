@@ -0,0 +1,76 @@
+/*
+ *  *******************************************************************************
+ *  * Copyright (c) 2024 Datasance Teknoloji A.S.
+ *  *
+ *  * This program and the accompanying materials are made available under the
+ *  * terms of the Eclipse Public License v. 2.0 which is available at
+ *  * http://www.eclipse.org/legal/epl-2.0
+ *  *
+ *  * SPDX-License-Identifier: EPL-2.0
+ *  *******************************************************************************
+ *
+ */
+
+package apps
+
+import (
+	"testing"
+
+	"github.com/datasance/iofog-go-sdk/v3/pkg/client"
+)
+
+func statusMicroservice(name, agent, status, health string) client.MicroserviceInfo {
+	return client.MicroserviceInfo{
+		Name:      name,
+		AgentUUID: agent,
+		Status:    client.MicroserviceStatusInfo{Status: status, HealthStatus: health},
+	}
+}
+
+func TestAggregateStatus(t *testing.T) {
+	agents := map[string]*client.AgentInfo{
+		"online":  {Name: "edge-1", DaemonStatus: agentRunning},
+		"offline": {Name: "edge-2", DaemonStatus: "UNKNOWN"},
+	}
+	info := &client.ApplicationInfo{Name: "app", IsActivated: true}
+	cases := []struct {
+		name     string
+		msvcs    []client.MicroserviceInfo
+		expected ApplicationPhase
+	}{
+		{"running", []client.MicroserviceInfo{statusMicroservice("a", "online", "RUNNING", "healthy"), statusMicroservice("b", "online", "RUNNING", "")}, ApplicationRunning},
+		{"pending", []client.MicroserviceInfo{statusMicroservice("a", "online", "QUEUED", ""), statusMicroservice("b", "online", "", "")}, ApplicationPending},
+		{"partially scheduled", []client.MicroserviceInfo{statusMicroservice("a", "online", "RUNNING", ""), statusMicroservice("b", "online", "QUEUED", "")}, ApplicationDeploying},
+		{"pulling", []client.MicroserviceInfo{statusMicroservice("a", "online", "PULLING", "")}, ApplicationDeploying},
+		{"unhealthy", []client.MicroserviceInfo{statusMicroservice("a", "online", "RUNNING", "unhealthy"), statusMicroservice("b", "online", "PULLING", "")}, ApplicationDegraded},
+		{"agent offline", []client.MicroserviceInfo{statusMicroservice("a", "offline", "RUNNING", "")}, ApplicationDegraded},
+		{"stopped", []client.MicroserviceInfo{statusMicroservice("a", "online", "STOPPED", "")}, ApplicationDegraded},
+		{"failed", []client.MicroserviceInfo{statusMicroservice("a", "online", "FAILED", ""), statusMicroservice("b", "offline", "RUNNING", "unhealthy")}, ApplicationFailed},
+		{"empty", nil, ApplicationPending},
+	}
+	for _, tc := range cases {
+		status := aggregateStatus(info, tc.msvcs, agents)
+		if status.Phase != tc.expected {
+			t.Errorf("%s: expected %s, got %s: %v", tc.name, tc.expected, status.Phase, status.Reasons)
+		}
+		if tc.expected != ApplicationRunning && len(status.Reasons) == 0 {
+			t.Errorf("%s: expected reasons", tc.name)
+		}
+	}
+}
+
+func TestAggregateStatusDetails(t *testing.T) {
+	msvc := statusMicroservice("a", "missing", "FAILED", "")
+	msvc.Status.ErrorMessage = "image not found"
+	msvc.Status.ExecSessionIDs = []string{"s1", "s2"}
+	status := aggregateStatus(&client.ApplicationInfo{Name: "sys", IsActivated: true, IsSystem: true}, []client.MicroserviceInfo{msvc}, map[string]*client.AgentInfo{})
+	result := status.Microservices[0]
+	if !status.System || result.ExecSessions != 2 || len(result.Reasons) != 2 || result.Reasons[1] != "failed: image not found" {
+		t.Errorf("Unexpected status %+v", result)
+	}
+
+	inactive := aggregateStatus(&client.ApplicationInfo{Name: "app"}, []client.MicroserviceInfo{statusMicroservice("a", "missing", "STOPPED", "")}, nil)
+	if inactive.Phase != ApplicationPending || inactive.Microservices[0].Phase != ApplicationPending {
+		t.Errorf("Expected a stopped application to be pending, got %s", inactive)
+	}
+}
@@ -16,6 +16,7 @@ package apps
 import (
 	"bytes"
 	"net/url"
+	"reflect"
 
 	"github.com/datasance/iofog-go-sdk/v3/pkg/client"
 	"gopkg.in/yaml.v2"
@@ -51,18 +52,15 @@ func (exe *applicationTemplateExecutor) execute() error {
 }
 
 func (exe *applicationTemplateExecutor) init() (err error) {
-	if exe.controller.Token != "" {
-		exe.client, err = client.NewWithToken(client.Options{BaseURL: exe.baseURL}, exe.controller.Token)
-	} else {
-		exe.client, err = client.SessionLogin(client.Options{BaseURL: exe.baseURL}, exe.controller.RefreshToken, exe.controller.Email, exe.controller.Password)
+	if exe.client == nil {
+		exe.client, err = login(exe.baseURL, exe.controller)
 	}
-
 	return err
 }
 
 func (exe *applicationTemplateExecutor) deploy() error {
 	file := IofogHeader{
-		APIVersion: "datasance.com/v3",
+		APIVersion: APIVersion,
 		Kind:       ApplicationTemplateKind,
 		Metadata: HeaderMetadata{
 			Name: exe.name,
@@ -89,3 +87,28 @@ func (exe *applicationTemplateExecutor) deploy() error {
 	}
 	return nil
 }
+
+// templateChanged compares a template spec to the template deployed under the same name
+// Unset fields of the spec compare equal to empty fields of the deployed template
+func templateChanged(name string, spec *ApplicationTemplate, deployed *client.ApplicationTemplate) (bool, error) {
+	desired := *spec
+	desired.Name = name
+	yamlBytes, err := yaml.Marshal(desired)
+	if err != nil {
+		return false, err
+	}
+	var tree interface{}
+	if err = yaml.Unmarshal(yamlBytes, &tree); err != nil {
+		return false, err
+	}
+	// JSON round trip so that numbers have the same type on both sides
+	desiredValue, err := toJSONMap(toJSONCompatible(tree))
+	if err != nil {
+		return false, err
+	}
+	deployedValue, err := toJSONMap(deployed)
+	if err != nil {
+		return false, err
+	}
+	return !reflect.DeepEqual(prune(desiredValue), prune(deployedValue)), nil
+}
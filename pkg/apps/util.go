@@ -14,30 +14,91 @@
 package apps
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/url"
+	"reflect"
 
 	"github.com/datasance/iofog-go-sdk/v3/pkg/client"
 )
 
-func validateRoutes(routes []string, microserviceByName map[string]*client.MicroserviceInfo) (routesUUIDs []string, err error) { // nolint:deadcode,unused
-	// Validate routes
-	for _, route := range routes {
-		msvc, foundTo := microserviceByName[route]
-		if !foundTo {
-			return routesUUIDs, NewNotFoundError(fmt.Sprintf("Could not find microservice [%s] required by a route", route))
+// newClient returns a Controller client logged in with the credentials of the controller
+func newClient(controller IofogController) (clt *client.Client, err error) {
+	baseURL, err := url.Parse(controller.Endpoint)
+	if err != nil {
+		return nil, fmt.Errorf(errParseControllerURL, err.Error())
+	}
+	return login(baseURL, controller)
+}
+
+// login returns a client of the Controller at baseURL logged in with the credentials of the controller
+func login(baseURL *url.URL, controller IofogController) (clt *client.Client, err error) {
+	if controller.Token != "" {
+		return client.NewWithToken(client.Options{BaseURL: baseURL}, controller.Token)
+	}
+	return client.SessionLogin(client.Options{BaseURL: baseURL}, controller.RefreshToken, controller.Email, controller.Password)
+}
+
+// clientOrLogin returns the client provided by the caller, or a new client logged in with the credentials of the controller
+func clientOrLogin(clt *client.Client, controller IofogController) (*client.Client, error) {
+	if clt != nil {
+		return clt, nil
+	}
+	return newClient(controller)
+}
+
+// toJSONCompatible converts the map[interface{}]interface{} values produced by the YAML decoder into map[string]interface{}
+func toJSONCompatible(in interface{}) interface{} {
+	switch value := in.(type) {
+	case map[interface{}]interface{}:
+		out := make(map[string]interface{}, len(value))
+		for key, val := range value {
+			out[fmt.Sprint(key)] = toJSONCompatible(val)
+		}
+		return out
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(value))
+		for key, val := range value {
+			out[key] = toJSONCompatible(val)
 		}
-		routesUUIDs = append(routesUUIDs, msvc.UUID)
+		return out
+	case NestedMap:
+		return toJSONCompatible(map[string]interface{}(value))
+	case []interface{}:
+		out := make([]interface{}, len(value))
+		for idx, val := range value {
+			out[idx] = toJSONCompatible(val)
+		}
+		return out
+	default:
+		return value
 	}
-	return routesUUIDs, nil
 }
 
-func createRoutes(routes []Route, microserviceByName map[string]*client.MicroserviceInfo, clt *client.Client) error { // nolint:deadcode,unused
-	for _, route := range routes {
-		fromMsvc := microserviceByName[route.From]
-		toMsvc := microserviceByName[route.To]
-		if err := clt.CreateMicroserviceRoute(fromMsvc.UUID, toMsvc.UUID); err != nil {
-			return err
+// jsonSubsetEqual returns true if every field set in desired has the same JSON value in current
+func jsonSubsetEqual(desired, current interface{}) (bool, error) {
+	desiredMap, err := toJSONMap(desired)
+	if err != nil {
+		return false, err
+	}
+	currentMap, err := toJSONMap(current)
+	if err != nil {
+		return false, err
+	}
+	for key, val := range desiredMap {
+		other, found := currentMap[key]
+		if !found || !reflect.DeepEqual(val, other) {
+			return false, nil
 		}
 	}
-	return nil
+	return true, nil
+}
+
+func toJSONMap(in interface{}) (out map[string]interface{}, err error) {
+	bytes, err := json.Marshal(in)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(bytes, &out)
+	return out, err
 }
@@ -0,0 +1,291 @@
+/*
+ *  *******************************************************************************
+ *  * Copyright (c) 2024 Datasance Teknoloji A.S.
+ *  *
+ *  * This program and the accompanying materials are made available under the
+ *  * terms of the Eclipse Public License v. 2.0 which is available at
+ *  * http://www.eclipse.org/legal/epl-2.0
+ *  *
+ *  * SPDX-License-Identifier: EPL-2.0
+ *  *******************************************************************************
+ *
+ */
+
+package apps
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// dnsLabel matches RFC 1123 labels, the names the Controller can expose on the network
+var dnsLabel = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+const maxDNSLabelLength = 63
+
+// Volume access modes accepted by the container engines, the Agent defaults to rw
+var volumeAccessModes = map[string]bool{
+	"":   true,
+	"rw": true,
+	"ro": true,
+}
+
+// First element of a health check test
+const (
+	healthCheckNone     = "NONE"
+	healthCheckCmd      = "CMD"
+	healthCheckCmdShell = "CMD-SHELL"
+)
+
+// FieldError is an invalid field of a spec, identified by its YAML path
+type FieldError struct {
+	Path    string
+	Message string
+}
+
+func (err *FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", err.Path, err.Message)
+}
+
+// ValidationError contains every invalid field of a spec
+type ValidationError struct {
+	Errors []*FieldError
+}
+
+func (err *ValidationError) Error() string {
+	msgs := make([]string, 0, len(err.Errors))
+	for _, fieldErr := range err.Errors {
+		msgs = append(msgs, fieldErr.Error())
+	}
+	return fmt.Sprintf("Invalid spec: %s", strings.Join(msgs, "; "))
+}
+
+type validator struct {
+	errs []*FieldError
+}
+
+func (v *validator) add(path, format string, args ...interface{}) {
+	v.errs = append(v.errs, &FieldError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) result() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Errors: v.errs}
+}
+
+func (v *validator) name(path, name string) {
+	switch {
+	case name == "":
+		v.add(path, "is required")
+	case len(name) > maxDNSLabelLength:
+		v.add(path, "must be at most %d characters", maxDNSLabelLength)
+	case !dnsLabel.MatchString(name):
+		v.add(path, "%q must consist of lower case alphanumeric characters or '-', and start and end with an alphanumeric character", name)
+	}
+}
+
+// optionalName checks a name the spec may leave to the metadata of its manifest document
+func (v *validator) optionalName(path, name string) {
+	if name != "" {
+		v.name(path, name)
+	}
+}
+
+// Validate checks the application offline, errors carry the YAML path of the invalid field
+// The name is optional, a manifest document sets it in its metadata
+func (app *Application) Validate() error {
+	v := new(validator)
+	app.validate(v)
+	return v.result()
+}
+
+func (app *Application) validate(v *validator) {
+	v.optionalName("name", app.Name)
+	names := make(map[string]bool)
+	for idx := range app.Microservices {
+		path := fmt.Sprintf("microservices[%d]", idx)
+		msvc := &app.Microservices[idx]
+		v.name(path+".name", msvc.Name)
+		msvc.validate(v, path)
+		if names[msvc.Name] {
+			v.add(path+".name", "duplicate microservice %s", msvc.Name)
+		}
+		names[msvc.Name] = true
+	}
+	validateRoutes(v, app.Routes, names)
+}
+
+// validateRoutes checks the routes of an application whose microservices are known by name
+func validateRoutes(v *validator, routes []Route, microservices map[string]bool) {
+	names := make(map[string]bool)
+	for idx := range routes {
+		path := fmt.Sprintf("routes[%d]", idx)
+		route := &routes[idx]
+		v.name(path+".name", route.Name)
+		route.validate(v, path)
+		if names[route.Name] {
+			v.add(path+".name", "duplicate route %s", route.Name)
+		}
+		names[route.Name] = true
+		if route.From != "" && !microservices[route.From] {
+			v.add(path+".from", "microservice %s is not part of the application", route.From)
+		}
+		if route.To != "" && !microservices[route.To] {
+			v.add(path+".to", "microservice %s is not part of the application", route.To)
+		}
+	}
+}
+
+// Validate checks the microservice offline, errors carry the YAML path of the invalid field
+// The name is optional, a manifest document sets it in its metadata
+func (msvc *Microservice) Validate() error {
+	v := new(validator)
+	v.optionalName("name", msvc.Name)
+	msvc.validate(v, "")
+	return v.result()
+}
+
+func (msvc *Microservice) validate(v *validator, path string) {
+	if msvc.Images != nil && msvc.Images.CatalogID < 0 {
+		v.add(joinPath(path, "images.catalogId"), "must not be negative")
+	}
+	msvc.Container.validate(v, joinPath(path, "container"))
+}
+
+// Validate checks the container offline, errors carry the YAML path of the invalid field
+func (container *MicroserviceContainer) Validate() error {
+	v := new(validator)
+	container.validate(v, "")
+	return v.result()
+}
+
+func (container *MicroserviceContainer) validate(v *validator, path string) {
+	externals := make(map[string]int)
+	for idx, port := range container.Ports {
+		portPath := joinPath(path, fmt.Sprintf("ports[%d]", idx))
+		if port.Internal < 1 || port.Internal > 65535 {
+			v.add(portPath+".internal", "%d is not a valid port", port.Internal)
+		}
+		if port.External < 1 || port.External > 65535 {
+			v.add(portPath+".external", "%d is not a valid port", port.External)
+			continue
+		}
+		protocol := strings.ToLower(port.Protocol)
+		if protocol == "" {
+			protocol = "tcp"
+		}
+		if protocol != "tcp" && protocol != "udp" {
+			v.add(portPath+".protocol", "%s is not tcp or udp", port.Protocol)
+		}
+		key := fmt.Sprintf("%d/%s", port.External, protocol)
+		if first, found := externals[key]; found {
+			v.add(portPath+".external", "port %s is already published by ports[%d]", key, first)
+			continue
+		}
+		externals[key] = idx
+	}
+
+	if container.Env != nil {
+		keys := make(map[string]bool)
+		for idx, env := range *container.Env {
+			envPath := joinPath(path, fmt.Sprintf("env[%d]", idx))
+			if env.Key == "" {
+				v.add(envPath+".key", "is required")
+			} else if keys[env.Key] {
+				v.add(envPath+".key", "duplicate variable %s", env.Key)
+			}
+			keys[env.Key] = true
+			sources := 0
+			for _, value := range []string{env.Value, env.ValueFromSecret, env.ValueFromConfigMap} {
+				if value != "" {
+					sources++
+				}
+			}
+			if sources > 1 {
+				v.add(envPath, "value, valueFromSecret and valueFromConfigMap are mutually exclusive")
+			}
+		}
+	}
+
+	if container.Volumes != nil {
+		for idx, volume := range *container.Volumes {
+			volumePath := joinPath(path, fmt.Sprintf("volumes[%d]", idx))
+			if volume.HostDestination == "" {
+				v.add(volumePath+".hostDestination", "is required")
+			}
+			if volume.ContainerDestination == "" {
+				v.add(volumePath+".containerDestination", "is required")
+			}
+			if !volumeAccessModes[volume.AccessMode] {
+				v.add(volumePath+".accessMode", "%q is not rw or ro", volume.AccessMode)
+			}
+		}
+	}
+
+	if container.HealthCheck != nil {
+		container.HealthCheck.validate(v, joinPath(path, "healthCheck"))
+	}
+}
+
+func (healthCheck *MicroserviceHealthCheck) validate(v *validator, path string) {
+	if len(healthCheck.Test) == 0 {
+		v.add(path+".test", "is required")
+	} else {
+		switch healthCheck.Test[0] {
+		case healthCheckNone:
+			if len(healthCheck.Test) > 1 {
+				v.add(path+".test", "%s takes no arguments", healthCheckNone)
+			}
+		case healthCheckCmd, healthCheckCmdShell:
+			if len(healthCheck.Test) < 2 {
+				v.add(path+".test", "%s requires a command", healthCheck.Test[0])
+			}
+		default:
+			v.add(path+".test[0]", "%q is not %s, %s or %s", healthCheck.Test[0], healthCheckNone, healthCheckCmd, healthCheckCmdShell)
+		}
+	}
+	durations := []struct {
+		field string
+		value *int64
+	}{
+		{"interval", healthCheck.Interval},
+		{"timeout", healthCheck.Timeout},
+		{"startPeriod", healthCheck.StartPeriod},
+		{"startInterval", healthCheck.StartInterval},
+	}
+	for _, duration := range durations {
+		if duration.value != nil && *duration.value < 0 {
+			v.add(path+"."+duration.field, "must not be negative")
+		}
+	}
+	if healthCheck.Retries != nil && *healthCheck.Retries < 0 {
+		v.add(path+".retries", "must not be negative")
+	}
+	if healthCheck.Interval != nil && healthCheck.Timeout != nil && *healthCheck.Interval > 0 && *healthCheck.Timeout > *healthCheck.Interval {
+		v.add(path+".timeout", "must not be longer than the interval")
+	}
+}
+
+// Validate checks the route offline, errors carry the YAML path of the invalid field
+// Whether the microservices exist is checked by Application.Validate, the name is optional as for microservices
+func (route *Route) Validate() error {
+	v := new(validator)
+	v.optionalName("name", route.Name)
+	route.validate(v, "")
+	return v.result()
+}
+
+func (route *Route) validate(v *validator, path string) {
+	if route.From == "" {
+		v.add(joinPath(path, "from"), "is required")
+	}
+	if route.To == "" {
+		v.add(joinPath(path, "to"), "is required")
+	}
+	if route.From != "" && route.From == route.To {
+		v.add(joinPath(path, "to"), "route cannot end on its source microservice")
+	}
+}
@@ -0,0 +1,132 @@
+/*
+ *  *******************************************************************************
+ *  * Copyright (c) 2024 Datasance Teknoloji A.S.
+ *  *
+ *  * This program and the accompanying materials are made available under the
+ *  * terms of the Eclipse Public License v. 2.0 which is available at
+ *  * http://www.eclipse.org/legal/epl-2.0
+ *  *
+ *  * SPDX-License-Identifier: EPL-2.0
+ *  *******************************************************************************
+ *
+ */
+
+package apps
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestValidateApplication(t *testing.T) {
+	interval, timeout := int64(10), int64(30)
+	app := &Application{
+		Name: "my-app",
+		Microservices: []Microservice{
+			{
+				Name: "web",
+				Container: MicroserviceContainer{
+					Ports: []MicroservicePortMapping{{Internal: 80, External: 8080}, {Internal: 81, External: 8080, Protocol: "TCP"}, {Internal: 70000, External: 53, Protocol: "udp"}},
+					Env:   &[]MicroserviceEnvironment{{Key: "TOKEN", Value: "abc", ValueFromSecret: "creds/token"}},
+					Volumes: &[]MicroserviceVolumeMapping{
+						{HostDestination: "/data", ContainerDestination: "/data", AccessMode: "rw"},
+						{HostDestination: "/logs", ContainerDestination: "/logs", AccessMode: "write"},
+					},
+					HealthCheck: &MicroserviceHealthCheck{Test: []string{"CMD"}, Interval: &interval, Timeout: &timeout},
+				},
+			},
+			{Name: "Web_2"},
+		},
+		Routes: []Route{
+			{Name: "to-db", From: "web", To: "db"},
+		},
+	}
+	err := app.Validate()
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Expected a validation error, got %v", err)
+	}
+	var paths []string
+	for _, fieldErr := range validationErr.Errors {
+		paths = append(paths, fieldErr.Path)
+	}
+	sort.Strings(paths)
+	expected := []string{
+		"microservices[0].container.env[0]",
+		"microservices[0].container.healthCheck.test",
+		"microservices[0].container.healthCheck.timeout",
+		"microservices[0].container.ports[1].external",
+		"microservices[0].container.ports[2].internal",
+		"microservices[0].container.volumes[1].accessMode",
+		"microservices[1].name",
+		"routes[0].to",
+	}
+	if len(paths) != len(expected) {
+		t.Fatalf("Expected errors on %v, got %v", expected, validationErr)
+	}
+	for idx := range expected {
+		if paths[idx] != expected[idx] {
+			t.Errorf("Expected error on %s, got %s", expected[idx], paths[idx])
+		}
+	}
+}
+
+func TestValidateValid(t *testing.T) {
+	app := &Application{
+		Name: "my-app",
+		Microservices: []Microservice{
+			{Name: "producer", Container: MicroserviceContainer{Ports: []MicroservicePortMapping{{Internal: 80, External: 8080}}}},
+			{Name: "consumer", Container: MicroserviceContainer{HealthCheck: &MicroserviceHealthCheck{Test: []string{"CMD-SHELL", "curl -f localhost"}}}},
+		},
+		Routes: []Route{{Name: "feed", From: "producer", To: "consumer"}},
+	}
+	if err := app.Validate(); err != nil {
+		t.Errorf("Expected valid application, got %v", err)
+	}
+	route := &Route{Name: "loop", From: "producer", To: "producer"}
+	if err := route.Validate(); err == nil || err.(*ValidationError).Errors[0].Path != "to" {
+		t.Errorf("Expected loop route to be rejected on to, got %v", err)
+	}
+}
+
+func TestValidateManifestDocuments(t *testing.T) {
+	manifest := testManifest + `---
+apiVersion: datasance.com/v3
+kind: Microservice
+metadata:
+  name: app/web
+spec:
+  agent:
+    name: edge
+  container:
+    ports:
+    - internal: 80
+      external: 8080
+`
+	headers, err := DefaultScheme.DecodeAll(strings.NewReader(manifest))
+	if err != nil {
+		t.Fatal(err)
+	}
+	validated := 0
+	for _, header := range headers {
+		spec, ok := header.Spec.(interface{ Validate() error })
+		if !ok {
+			continue
+		}
+		validated++
+		if err := spec.Validate(); err != nil {
+			t.Errorf("Expected %s %s to be valid, got %v", header.Kind, header.Metadata.Name, err)
+		}
+	}
+	if validated != 3 {
+		t.Errorf("Expected the application, route and microservice to be validated, got %d documents", validated)
+	}
+
+	// The microservices of an application are only known by their name
+	app := &Application{Microservices: []Microservice{{}}}
+	if err := app.Validate(); err == nil || err.(*ValidationError).Errors[0].Path != "microservices[0].name" {
+		t.Errorf("Expected unnamed microservice to be rejected, got %v", err)
+	}
+}
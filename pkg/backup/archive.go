@@ -0,0 +1,210 @@
+/*
+ *  *******************************************************************************
+ *  * Copyright (c) 2024 Datasance Teknoloji A.S.
+ *  *
+ *  * This program and the accompanying materials are made available under the
+ *  * terms of the Eclipse Public License v. 2.0 which is available at
+ *  * http://www.eclipse.org/legal/epl-2.0
+ *  *
+ *  * SPDX-License-Identifier: EPL-2.0
+ *  *******************************************************************************
+ *
+ */
+
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/datasance/iofog-go-sdk/v3/pkg/apps"
+)
+
+// KeySize is the size in bytes of the AES-256 key used to encrypt the secrets of an archive
+const KeySize = 32
+
+// Entries of an archive
+const (
+	metadataFile         = "metadata.json"
+	resourcesFile        = "resources.yaml"
+	secretsFile          = "secrets.yaml"
+	encryptedSecretsFile = "secrets.yaml.enc"
+)
+
+// Write stores the snapshot as a gzipped tar archive
+// When key is set, the secrets are encrypted with AES-256-GCM; otherwise they are stored in clear text
+func Write(w io.Writer, snap *Snapshot, key []byte) error {
+	var resources, secrets []apps.Header
+	for idx := range snap.Documents {
+		if snap.Documents[idx].Kind == apps.SecretKind {
+			secrets = append(secrets, snap.Documents[idx])
+		} else {
+			resources = append(resources, snap.Documents[idx])
+		}
+	}
+	resourcesData, err := apps.DefaultScheme.EncodeAll(resources)
+	if err != nil {
+		return err
+	}
+	secretsData, err := apps.DefaultScheme.EncodeAll(secrets)
+	if err != nil {
+		return err
+	}
+	secretsName := secretsFile
+	if key != nil {
+		if secretsData, err = encrypt(key, secretsData); err != nil {
+			return err
+		}
+		secretsName = encryptedSecretsFile
+	}
+	metadata := snap.Metadata
+	metadata.FormatVersion = FormatVersion
+	metadata.Encrypted = key != nil
+	metadataData, err := json.MarshalIndent(metadata, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	gzipWriter := gzip.NewWriter(w)
+	tarWriter := tar.NewWriter(gzipWriter)
+	entries := []struct {
+		name string
+		data []byte
+	}{
+		{metadataFile, metadataData},
+		{resourcesFile, resourcesData},
+		{secretsName, secretsData},
+	}
+	for _, entry := range entries {
+		header := &tar.Header{
+			Name:    entry.name,
+			Mode:    0600,
+			Size:    int64(len(entry.data)),
+			ModTime: metadata.CreatedAt,
+		}
+		if err = tarWriter.WriteHeader(header); err != nil {
+			return err
+		}
+		if _, err = tarWriter.Write(entry.data); err != nil {
+			return err
+		}
+	}
+	if err = tarWriter.Close(); err != nil {
+		return err
+	}
+	return gzipWriter.Close()
+}
+
+// Read loads a snapshot written by Write
+// The key is only required when the secrets of the archive are encrypted
+func Read(r io.Reader, key []byte) (*Snapshot, error) {
+	gzipReader, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, apps.NewInputError(fmt.Sprintf("Could not read backup archive: %s", err.Error()))
+	}
+	defer gzipReader.Close()
+
+	entries := make(map[string][]byte)
+	tarReader := tar.NewReader(gzipReader)
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, apps.NewInputError(fmt.Sprintf("Could not read backup archive: %s", err.Error()))
+		}
+		if entries[header.Name], err = io.ReadAll(tarReader); err != nil {
+			return nil, err
+		}
+	}
+
+	metadataData, found := entries[metadataFile]
+	if !found {
+		return nil, apps.NewInputError(fmt.Sprintf("Backup archive has no %s", metadataFile))
+	}
+	snap := new(Snapshot)
+	if err = json.Unmarshal(metadataData, &snap.Metadata); err != nil {
+		return nil, apps.NewInputError(fmt.Sprintf("Could not read backup metadata: %s", err.Error()))
+	}
+	if snap.Metadata.FormatVersion < 1 || snap.Metadata.FormatVersion > FormatVersion {
+		return nil, apps.NewInputError(fmt.Sprintf("Unsupported backup format version %d", snap.Metadata.FormatVersion))
+	}
+
+	secretsData, found := entries[secretsFile]
+	if snap.Metadata.Encrypted {
+		if key == nil {
+			return nil, apps.NewInputError("Backup archive secrets are encrypted, a key is required")
+		}
+		if secretsData, err = decrypt(key, entries[encryptedSecretsFile]); err != nil {
+			return nil, err
+		}
+	} else if !found {
+		return nil, apps.NewInputError(fmt.Sprintf("Backup archive has no %s", secretsFile))
+	}
+
+	for _, data := range [][]byte{entries[resourcesFile], secretsData} {
+		documents, err := apps.DefaultScheme.DecodeAll(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		snap.Documents = append(snap.Documents, documents...)
+	}
+	return snap, nil
+}
+
+// GenerateKey returns a random key suitable for Write and Read
+func GenerateKey() ([]byte, error) {
+	key := make([]byte, KeySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// encrypt seals the plaintext with AES-GCM, the nonce is prepended to the ciphertext
+func encrypt(key, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func decrypt(key, ciphertext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, apps.NewInputError("Backup archive encrypted secrets are truncated")
+	}
+	nonce, sealed := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return nil, apps.NewInputError("Could not decrypt backup archive secrets, the key is invalid or the archive is corrupted")
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != KeySize {
+		return nil, apps.NewInputError(fmt.Sprintf("Encryption key must be %d bytes long, got %d", KeySize, len(key)))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
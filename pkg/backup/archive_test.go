@@ -0,0 +1,122 @@
+/*
+ *  *******************************************************************************
+ *  * Copyright (c) 2024 Datasance Teknoloji A.S.
+ *  *
+ *  * This program and the accompanying materials are made available under the
+ *  * terms of the Eclipse Public License v. 2.0 which is available at
+ *  * http://www.eclipse.org/legal/epl-2.0
+ *  *
+ *  * SPDX-License-Identifier: EPL-2.0
+ *  *******************************************************************************
+ *
+ */
+
+package backup
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/datasance/iofog-go-sdk/v3/pkg/apps"
+)
+
+func testSnapshot() *Snapshot {
+	snap := &Snapshot{
+		Metadata: Metadata{
+			CreatedAt:    time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			Registries:   map[int]string{3: "registry.local"},
+			CatalogItems: map[int]string{101: "sensor"},
+		},
+	}
+	snap.add(apps.SecretKind, "creds", &apps.Secret{Type: "Opaque", Data: map[string]string{"password": "hunter2"}})
+	snap.add(apps.ConfigMapKind, "settings", &apps.ConfigMap{Data: map[string]string{"level": "debug"}})
+	snap.add(apps.ApplicationKind, "app", &apps.Application{
+		Name: "app",
+		Microservices: []apps.Microservice{{
+			Name:   "msvc",
+			Agent:  apps.MicroserviceAgent{Name: "agent"},
+			Images: &apps.MicroserviceImages{CatalogID: 101, Registry: "3"},
+		}},
+	})
+	return snap
+}
+
+func TestArchiveEncryptedRoundTrip(t *testing.T) {
+	key, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var archive bytes.Buffer
+	if err = Write(&archive, testSnapshot(), key); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(archive.String(), "hunter2") {
+		t.Error("Secret data found in clear text in the archive")
+	}
+
+	if _, err = Read(bytes.NewReader(archive.Bytes()), nil); err == nil {
+		t.Error("Expected encrypted archive to require a key")
+	}
+	wrongKey, _ := GenerateKey()
+	if _, err = Read(bytes.NewReader(archive.Bytes()), wrongKey); err == nil {
+		t.Error("Expected wrong key to be rejected")
+	}
+
+	snap, err := Read(bytes.NewReader(archive.Bytes()), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !snap.Metadata.Encrypted || snap.Metadata.FormatVersion != FormatVersion {
+		t.Errorf("Unexpected metadata: %+v", snap.Metadata)
+	}
+	if snap.Metadata.CatalogItems[101] != "sensor" {
+		t.Errorf("Catalog item IDs were not preserved: %v", snap.Metadata.CatalogItems)
+	}
+	for _, kind := range []apps.Kind{apps.SecretKind, apps.ConfigMapKind, apps.ApplicationKind} {
+		if snap.Count(kind) != 1 {
+			t.Errorf("Expected one %s document, got %d", kind, snap.Count(kind))
+		}
+	}
+	for _, header := range snap.Documents {
+		if secret, ok := header.Spec.(*apps.Secret); ok && secret.Data["password"] != "hunter2" {
+			t.Errorf("Unexpected secret data: %v", secret.Data)
+		}
+	}
+}
+
+func TestArchivePlain(t *testing.T) {
+	var archive bytes.Buffer
+	if err := Write(&archive, testSnapshot(), nil); err != nil {
+		t.Fatal(err)
+	}
+	snap, err := Read(&archive, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snap.Metadata.Encrypted || len(snap.Documents) != 3 {
+		t.Errorf("Unexpected snapshot: %+v", snap)
+	}
+
+	if err = Write(&archive, testSnapshot(), []byte("short")); err == nil {
+		t.Error("Expected short key to be rejected")
+	}
+}
+
+func TestTranslateMicroservices(t *testing.T) {
+	snap := testSnapshot()
+	translator := &idTranslator{
+		registries:   map[string]string{"3": "7"},
+		catalogItems: map[int]int{101: 12},
+	}
+	header := translator.translate(snap.Documents[2])
+	images := header.Spec.(*apps.Application).Microservices[0].Images
+	if images.CatalogID != 12 || images.Registry != "7" {
+		t.Errorf("Unexpected translated images: %+v", images)
+	}
+	original := snap.Documents[2].Spec.(*apps.Application).Microservices[0].Images
+	if original.CatalogID != 101 || original.Registry != "3" {
+		t.Errorf("Translation modified the snapshot: %+v", original)
+	}
+}
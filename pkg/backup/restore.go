@@ -0,0 +1,298 @@
+/*
+ *  *******************************************************************************
+ *  * Copyright (c) 2024 Datasance Teknoloji A.S.
+ *  *
+ *  * This program and the accompanying materials are made available under the
+ *  * terms of the Eclipse Public License v. 2.0 which is available at
+ *  * http://www.eclipse.org/legal/epl-2.0
+ *  *
+ *  * SPDX-License-Identifier: EPL-2.0
+ *  *******************************************************************************
+ *
+ */
+
+package backup
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/datasance/iofog-go-sdk/v3/pkg/apps"
+	"github.com/datasance/iofog-go-sdk/v3/pkg/client"
+)
+
+// ConflictPolicy decides what a restore does with resources that already exist on the Controller
+type ConflictPolicy string
+
+const (
+	// ConflictSkip leaves existing resources untouched
+	ConflictSkip ConflictPolicy = "skip"
+	// ConflictOverwrite replaces existing resources with the content of the snapshot
+	// CAs and certificates cannot be updated, one that differs from the snapshot fails the restore with a conflict
+	ConflictOverwrite ConflictPolicy = "overwrite"
+	// ConflictFail aborts the restore before any change if a resource already exists
+	ConflictFail ConflictPolicy = "fail"
+)
+
+// RestoreSkipped is reported for the resources a restore did not apply
+const RestoreSkipped client.ApplyAction = "Skipped"
+
+// RestoreOptions controls how a snapshot is restored
+type RestoreOptions struct {
+	// Conflict defaults to ConflictFail
+	Conflict ConflictPolicy
+}
+
+// Restore applies the snapshot onto the Controller in dependency order
+// Registry and catalog item IDs referenced by microservices are translated to the IDs of the target Controller
+// The configuration of Agents missing from the target Controller is skipped
+func Restore(clt *client.Client, snap *Snapshot, opt RestoreOptions) (*apps.ApplyReport, error) {
+	exe := &restoreExecutor{
+		client:   clt,
+		snapshot: snap,
+		policy:   opt.Conflict,
+		report:   new(apps.ApplyReport),
+	}
+	exe.exists = exe.existsOnController
+	if exe.policy == "" {
+		exe.policy = ConflictFail
+	}
+	return exe.execute()
+}
+
+type restoreExecutor struct {
+	client   *client.Client
+	snapshot *Snapshot
+	policy   ConflictPolicy
+	report   *apps.ApplyReport
+	// exists returns whether the resource of a document is already on the Controller
+	exists func(header *apps.Header) (bool, error)
+	// registryURLs of the Controller, listed once
+	registryURLs map[string]bool
+}
+
+func (exe *restoreExecutor) execute() (*apps.ApplyReport, error) {
+	switch exe.policy {
+	case ConflictSkip, ConflictOverwrite, ConflictFail:
+	default:
+		return exe.report, apps.NewInputError(fmt.Sprintf("Unknown conflict policy %s", exe.policy))
+	}
+
+	documents, err := exe.filter()
+	if err != nil {
+		return exe.report, err
+	}
+
+	// Registries and catalog items get new IDs on the target Controller, apply them first to translate references
+	var first, rest []apps.Header
+	for idx := range documents {
+		switch documents[idx].Kind {
+		case apps.RegistryKind, apps.CatalogItemKind:
+			first = append(first, documents[idx])
+		default:
+			rest = append(rest, documents[idx])
+		}
+	}
+	if err = exe.apply(first); err != nil {
+		return exe.report, err
+	}
+	translator, err := newIDTranslator(exe.client, &exe.snapshot.Metadata)
+	if err != nil {
+		return exe.report, err
+	}
+	for idx := range rest {
+		rest[idx] = translator.translate(rest[idx])
+	}
+	err = exe.apply(rest)
+	return exe.report, err
+}
+
+func (exe *restoreExecutor) apply(documents []apps.Header) error {
+	if len(documents) == 0 {
+		return nil
+	}
+	report, err := apps.ApplyDocuments(exe.client, documents)
+	if report != nil {
+		exe.report.Results = append(exe.report.Results, report.Results...)
+	}
+	return err
+}
+
+// filter applies the conflict policy and returns the documents to apply
+func (exe *restoreExecutor) filter() (documents []apps.Header, err error) {
+	var conflicts []string
+	for idx := range exe.snapshot.Documents {
+		header := exe.snapshot.Documents[idx]
+		exists, err := exe.exists(&header)
+		if err != nil {
+			return nil, err
+		}
+		// Agents are not created by a restore, only their configuration is
+		if header.Kind == apps.AgentConfigKind {
+			if exists {
+				documents = append(documents, header)
+			} else {
+				exe.skip(&header)
+			}
+			continue
+		}
+		if !exists {
+			documents = append(documents, header)
+			continue
+		}
+		switch exe.policy {
+		case ConflictOverwrite:
+			documents = append(documents, header)
+		case ConflictSkip:
+			exe.skip(&header)
+		case ConflictFail:
+			conflicts = append(conflicts, fmt.Sprintf("%s/%s", header.Kind, header.Metadata.Name))
+		}
+	}
+	if len(conflicts) > 0 {
+		return nil, apps.NewConflictError(fmt.Sprintf("Resources already exist on the Controller: %s", strings.Join(conflicts, ", ")))
+	}
+	return documents, nil
+}
+
+func (exe *restoreExecutor) skip(header *apps.Header) {
+	exe.report.Results = append(exe.report.Results, apps.ApplyResult{
+		Kind:   header.Kind,
+		Name:   header.Metadata.Name,
+		Action: RestoreSkipped,
+	})
+}
+
+// existsOnController returns whether the resource of the document is already on the Controller
+// Registries have no name, they are matched by URL against the registries listed on first use
+func (exe *restoreExecutor) existsOnController(header *apps.Header) (bool, error) {
+	clt := exe.client
+	name := header.Metadata.Name
+	var err error
+	switch spec := header.Spec.(type) {
+	case *apps.Registry:
+		if exe.registryURLs == nil {
+			registries, listErr := clt.ListRegistries()
+			if listErr != nil {
+				return false, listErr
+			}
+			exe.registryURLs = make(map[string]bool, len(registries.Registries))
+			for _, registry := range registries.Registries {
+				exe.registryURLs[registry.URL] = true
+			}
+		}
+		return exe.registryURLs[spec.URL], nil
+	case *apps.CatalogItem:
+		_, err = clt.GetCatalogItemByName(name)
+	case *apps.Secret:
+		_, err = clt.GetSecret(name)
+	case *apps.ConfigMap:
+		_, err = clt.GetConfigMap(name)
+	case *apps.CertificateAuthority:
+		_, err = clt.GetCA(name)
+	case *apps.Certificate:
+		_, err = clt.GetCertificate(name)
+	case *apps.VolumeMount:
+		_, err = clt.GetVolumeMount(name)
+	case *apps.Service:
+		_, err = clt.GetService(name)
+	case *apps.EdgeResource:
+		_, err = clt.GetHTTPEdgeResourceByName(name, spec.Version)
+	case *apps.AgentConfig:
+		_, err = clt.GetAgentByName(name)
+	case *apps.ApplicationTemplate:
+		_, err = clt.GetApplicationTemplate(name)
+	case *apps.Application:
+		_, err = clt.GetApplicationByName(name)
+	default:
+		return false, apps.NewInputError(fmt.Sprintf("Cannot restore %s documents", header.Kind))
+	}
+	if err == nil {
+		return true, nil
+	}
+	if _, notFound := err.(*client.NotFoundError); notFound {
+		return false, nil
+	}
+	return false, err
+}
+
+// idTranslator maps the registry and catalog item IDs of the source Controller to the target Controller
+type idTranslator struct {
+	registries   map[string]string
+	catalogItems map[int]int
+}
+
+func newIDTranslator(clt *client.Client, metadata *Metadata) (*idTranslator, error) {
+	registries, err := clt.ListRegistries()
+	if err != nil {
+		return nil, err
+	}
+	registryIDByURL := make(map[string]int)
+	for _, registry := range registries.Registries {
+		registryIDByURL[registry.URL] = registry.ID
+	}
+
+	catalog, err := clt.GetCatalog()
+	if err != nil {
+		return nil, err
+	}
+	catalogIDByName := make(map[string]int)
+	for _, item := range catalog.CatalogItems {
+		catalogIDByName[item.Name] = item.ID
+	}
+	return mapIDs(metadata, registryIDByURL, catalogIDByName), nil
+}
+
+// mapIDs matches the registries of the snapshot by URL and its catalog items by name, IDs without a match on the
+// target Controller are left as is
+func mapIDs(metadata *Metadata, registryIDByURL, catalogIDByName map[string]int) *idTranslator {
+	translator := &idTranslator{
+		registries:   make(map[string]string),
+		catalogItems: make(map[int]int),
+	}
+	for oldID, url := range metadata.Registries {
+		if newID, found := registryIDByURL[url]; found {
+			translator.registries[strconv.Itoa(oldID)] = strconv.Itoa(newID)
+		}
+	}
+	for oldID, name := range metadata.CatalogItems {
+		if newID, found := catalogIDByName[name]; found {
+			translator.catalogItems[oldID] = newID
+		}
+	}
+	return translator
+}
+
+// translate returns the document with its microservice image references translated
+// The snapshot itself is left untouched
+func (translator *idTranslator) translate(header apps.Header) apps.Header {
+	switch spec := header.Spec.(type) {
+	case *apps.Application:
+		app := spec.DeepCopy()
+		translator.translateMicroservices(app.Microservices)
+		header.Spec = app
+	case *apps.ApplicationTemplate:
+		template := spec.DeepCopy()
+		if template.Application != nil {
+			translator.translateMicroservices(template.Application.Microservices)
+		}
+		header.Spec = template
+	}
+	return header
+}
+
+func (translator *idTranslator) translateMicroservices(msvcs []apps.Microservice) {
+	for idx := range msvcs {
+		images := msvcs[idx].Images
+		if images == nil {
+			continue
+		}
+		if newID, found := translator.catalogItems[images.CatalogID]; found {
+			images.CatalogID = newID
+		}
+		if newID, found := translator.registries[images.Registry]; found {
+			images.Registry = newID
+		}
+	}
+}
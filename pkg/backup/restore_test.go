@@ -0,0 +1,133 @@
+/*
+ *  *******************************************************************************
+ *  * Copyright (c) 2024 Datasance Teknoloji A.S.
+ *  *
+ *  * This program and the accompanying materials are made available under the
+ *  * terms of the Eclipse Public License v. 2.0 which is available at
+ *  * http://www.eclipse.org/legal/epl-2.0
+ *  *
+ *  * SPDX-License-Identifier: EPL-2.0
+ *  *******************************************************************************
+ *
+ */
+
+package backup
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/datasance/iofog-go-sdk/v3/pkg/apps"
+)
+
+func TestRestoreConflicts(t *testing.T) {
+	snap := new(Snapshot)
+	snap.add(apps.SecretKind, "existing", &apps.Secret{Type: "Opaque"})
+	snap.add(apps.ConfigMapKind, "missing", &apps.ConfigMap{})
+	snap.add(apps.AgentConfigKind, "agent", &apps.AgentConfig{})
+	snap.add(apps.AgentConfigKind, "gone", &apps.AgentConfig{})
+	onController := map[string]bool{"existing": true, "agent": true}
+
+	testCases := []struct {
+		policy   ConflictPolicy
+		applied  []string
+		skipped  []string
+		conflict bool
+	}{
+		{ConflictSkip, []string{"missing", "agent"}, []string{"existing", "gone"}, false},
+		{ConflictOverwrite, []string{"existing", "missing", "agent"}, []string{"gone"}, false},
+		{ConflictFail, nil, nil, true},
+	}
+	for _, testCase := range testCases {
+		exe := &restoreExecutor{
+			snapshot: snap,
+			policy:   testCase.policy,
+			report:   new(apps.ApplyReport),
+			exists: func(header *apps.Header) (bool, error) {
+				return onController[header.Metadata.Name], nil
+			},
+		}
+		documents, err := exe.filter()
+		if testCase.conflict {
+			if _, ok := err.(*apps.ConflictError); !ok || len(documents) > 0 {
+				t.Errorf("%s: expected a conflict error and no document, got %v and %d documents", testCase.policy, err, len(documents))
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %s", testCase.policy, err.Error())
+			continue
+		}
+		var applied, skipped []string
+		for _, header := range documents {
+			applied = append(applied, header.Metadata.Name)
+		}
+		for _, result := range exe.report.Results {
+			if result.Action == RestoreSkipped {
+				skipped = append(skipped, result.Name)
+			}
+		}
+		if !reflect.DeepEqual(applied, testCase.applied) || !reflect.DeepEqual(skipped, testCase.skipped) {
+			t.Errorf("%s: expected %v applied and %v skipped, got %v and %v", testCase.policy, testCase.applied, testCase.skipped, applied, skipped)
+		}
+	}
+
+	exe := &restoreExecutor{
+		snapshot: snap,
+		policy:   ConflictSkip,
+		report:   new(apps.ApplyReport),
+		exists: func(header *apps.Header) (bool, error) {
+			return false, errors.New("unreachable")
+		},
+	}
+	if _, err := exe.filter(); err == nil {
+		t.Error("Expected lookup errors to abort the restore")
+	}
+}
+
+func TestTranslateIDs(t *testing.T) {
+	metadata := &Metadata{
+		Registries:   map[int]string{3: "registry.local", 4: "registry.gone"},
+		CatalogItems: map[int]string{101: "sensor", 102: "gone"},
+	}
+	translator := mapIDs(metadata, map[string]int{"registry.local": 7}, map[string]int{"sensor": 12})
+
+	testCases := []struct {
+		name             string
+		images           *apps.MicroserviceImages
+		expectedCatalog  int
+		expectedRegistry string
+	}{
+		{"known IDs", &apps.MicroserviceImages{CatalogID: 101, Registry: "3"}, 12, "7"},
+		{"IDs missing on the target", &apps.MicroserviceImages{CatalogID: 102, Registry: "4"}, 102, "4"},
+		{"IDs missing from the snapshot", &apps.MicroserviceImages{CatalogID: 103, Registry: "5"}, 103, "5"},
+		{"no catalog item", &apps.MicroserviceImages{X86: "nginx:latest", Registry: "3"}, 0, "7"},
+		{"no images", nil, 0, ""},
+	}
+	for _, testCase := range testCases {
+		msvc := apps.Microservice{Name: "msvc", Images: testCase.images}
+		documents := []apps.Header{
+			{Kind: apps.ApplicationKind, Spec: &apps.Application{Name: "app", Microservices: []apps.Microservice{msvc}}},
+			{Kind: apps.ApplicationTemplateKind, Spec: &apps.ApplicationTemplate{Name: "template", Application: &apps.ApplicationTemplateInfo{Microservices: []apps.Microservice{msvc}}}},
+		}
+		for _, header := range documents {
+			var images *apps.MicroserviceImages
+			switch spec := translator.translate(header).Spec.(type) {
+			case *apps.Application:
+				images = spec.Microservices[0].Images
+			case *apps.ApplicationTemplate:
+				images = spec.Application.Microservices[0].Images
+			}
+			if images == nil {
+				if testCase.images != nil {
+					t.Errorf("%s: %s lost its images", testCase.name, header.Kind)
+				}
+				continue
+			}
+			if images.CatalogID != testCase.expectedCatalog || images.Registry != testCase.expectedRegistry {
+				t.Errorf("%s: %s translated to catalog item %d and registry %s", testCase.name, header.Kind, images.CatalogID, images.Registry)
+			}
+		}
+	}
+}
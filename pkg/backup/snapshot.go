@@ -0,0 +1,382 @@
+/*
+ *  *******************************************************************************
+ *  * Copyright (c) 2024 Datasance Teknoloji A.S.
+ *  *
+ *  * This program and the accompanying materials are made available under the
+ *  * terms of the Eclipse Public License v. 2.0 which is available at
+ *  * http://www.eclipse.org/legal/epl-2.0
+ *  *
+ *  * SPDX-License-Identifier: EPL-2.0
+ *  *******************************************************************************
+ *
+ */
+
+package backup
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/datasance/iofog-go-sdk/v3/pkg/apps"
+	"github.com/datasance/iofog-go-sdk/v3/pkg/client"
+	"gopkg.in/yaml.v2"
+)
+
+// FormatVersion is the version of the archive layout written by this package
+const FormatVersion = 1
+
+// systemCatalogCategory is the category of the catalog items provisioned by the Controller itself
+const systemCatalogCategory = "SYSTEM"
+
+// Metadata describes where and when a snapshot was taken
+type Metadata struct {
+	FormatVersion     int       `json:"formatVersion"`
+	CreatedAt         time.Time `json:"createdAt"`
+	ControllerURL     string    `json:"controllerUrl"`
+	ControllerVersion string    `json:"controllerVersion"`
+	Encrypted         bool      `json:"encrypted"`
+	// Registries maps the IDs of the source Controller registries to their URL
+	Registries map[int]string `json:"registries,omitempty"`
+	// CatalogItems maps the IDs of the source Controller catalog items to their name
+	CatalogItems map[int]string `json:"catalogItems,omitempty"`
+}
+
+// Snapshot is the configuration of a Controller, stored as documents that can be applied by pkg/apps
+type Snapshot struct {
+	Metadata  Metadata
+	Documents []apps.Header
+}
+
+// Count returns the number of documents of a kind in the snapshot
+func (snap *Snapshot) Count(kind apps.Kind) (count int) {
+	for idx := range snap.Documents {
+		if snap.Documents[idx].Kind == kind {
+			count++
+		}
+	}
+	return
+}
+
+func (snap *Snapshot) add(kind apps.Kind, name string, spec interface{}) {
+	snap.Documents = append(snap.Documents, apps.Header{
+		APIVersion: apps.APIVersion,
+		Kind:       kind,
+		Metadata: apps.HeaderMetadata{
+			Name: name,
+		},
+		Spec: spec,
+	})
+}
+
+// Take snapshots the configuration of the Controller
+// Registry passwords cannot be read from the Controller and are therefore not part of the snapshot
+// System applications, system catalog items and built-in registries are left out
+func Take(clt *client.Client) (*Snapshot, error) {
+	snap := &Snapshot{
+		Metadata: Metadata{
+			FormatVersion:     FormatVersion,
+			CreatedAt:         time.Now().UTC(),
+			ControllerURL:     clt.GetBaseURL(),
+			ControllerVersion: clt.GetVersion(),
+			Registries:        make(map[int]string),
+			CatalogItems:      make(map[int]string),
+		},
+	}
+	collectors := []func(*client.Client, *Snapshot) error{
+		takeRegistries,
+		takeCatalogItems,
+		takeSecrets,
+		takeConfigMaps,
+		takeCAs,
+		takeCertificates,
+		takeVolumeMounts,
+		takeEdgeResources,
+		takeAgentConfigs,
+		takeApplicationTemplates,
+		takeApplications,
+		takeServices,
+	}
+	for _, collect := range collectors {
+		if err := collect(clt, snap); err != nil {
+			return nil, err
+		}
+	}
+	return snap, nil
+}
+
+func isNotSupported(err error) bool {
+	_, ok := err.(*client.NotSupportedError)
+	return ok
+}
+
+func takeRegistries(clt *client.Client, snap *Snapshot) error {
+	registries, err := clt.ListRegistries()
+	if err != nil {
+		return err
+	}
+	for _, registry := range registries.Registries {
+		snap.Metadata.Registries[registry.ID] = registry.URL
+		// Built-in registries exist on every Controller
+		if _, builtin := client.RegistryTypeIDRegistryTypeDict[registry.ID]; builtin {
+			continue
+		}
+		snap.add(apps.RegistryKind, registry.URL, &apps.Registry{
+			URL:          registry.URL,
+			IsPublic:     registry.IsPublic,
+			Username:     registry.Username,
+			Email:        registry.Email,
+			RequiresCert: registry.RequiresCert,
+			Certificate:  registry.Certificate,
+		})
+	}
+	return nil
+}
+
+func takeCatalogItems(clt *client.Client, snap *Snapshot) error {
+	catalog, err := clt.GetCatalog()
+	if err != nil {
+		return err
+	}
+	for _, item := range catalog.CatalogItems {
+		snap.Metadata.CatalogItems[item.ID] = item.Name
+		if item.Category == systemCatalogCategory {
+			continue
+		}
+		spec := &apps.CatalogItem{
+			Name:        item.Name,
+			Description: item.Description,
+			Registry:    registryReference(snap, item.RegistryID),
+		}
+		for _, image := range item.Images {
+			switch client.AgentTypeIDAgentTypeDict[image.AgentTypeID] {
+			case "x86":
+				spec.X86 = image.ContainerImage
+			case "arm":
+				spec.ARM = image.ContainerImage
+			}
+		}
+		snap.add(apps.CatalogItemKind, item.Name, spec)
+	}
+	return nil
+}
+
+// registryReference returns a registry reference that resolves on any Controller the snapshot is restored to
+func registryReference(snap *Snapshot, registryID int) string {
+	if name, builtin := client.RegistryTypeIDRegistryTypeDict[registryID]; builtin {
+		return name
+	}
+	if url, found := snap.Metadata.Registries[registryID]; found {
+		return url
+	}
+	return ""
+}
+
+func takeSecrets(clt *client.Client, snap *Snapshot) error {
+	secrets, err := clt.ListSecrets()
+	if err != nil {
+		return err
+	}
+	for _, item := range secrets.Secrets {
+		secret, err := clt.GetSecret(item.Name)
+		if err != nil {
+			return err
+		}
+		snap.add(apps.SecretKind, secret.Name, &apps.Secret{
+			Type: secret.Type,
+			Data: secret.Data,
+		})
+	}
+	return nil
+}
+
+func takeConfigMaps(clt *client.Client, snap *Snapshot) error {
+	configMaps, err := clt.ListConfigMaps()
+	if err != nil {
+		return err
+	}
+	for _, item := range configMaps.ConfigMaps {
+		configMap, err := clt.GetConfigMap(item.Name)
+		if err != nil {
+			return err
+		}
+		snap.add(apps.ConfigMapKind, configMap.Name, &apps.ConfigMap{
+			Immutable: configMap.Immutable,
+			Data:      configMap.Data,
+		})
+	}
+	return nil
+}
+
+// takeCAs records the CAs as loaded from the secret holding their key pair, so the same CA is restored
+func takeCAs(clt *client.Client, snap *Snapshot) error {
+	cas, err := clt.ListCAs()
+	if err != nil {
+		return err
+	}
+	for _, ca := range cas.CAs {
+		snap.add(apps.CertificateAuthorityKind, ca.Name, &apps.CertificateAuthority{
+			Subject:    ca.Subject,
+			Type:       "direct",
+			SecretName: ca.Name,
+		})
+	}
+	return nil
+}
+
+func takeCertificates(clt *client.Client, snap *Snapshot) error {
+	certificates, err := clt.ListCertificates()
+	if err != nil {
+		return err
+	}
+	for _, cert := range certificates.Certificates {
+		// CAs are listed as certificates too
+		if cert.IsCA {
+			continue
+		}
+		spec := &apps.Certificate{
+			Subject: cert.Subject,
+			Hosts:   cert.Hosts,
+			CA:      apps.CertificateCA{Type: "self-signed"},
+		}
+		if cert.CAName != nil && *cert.CAName != "" {
+			spec.CA = apps.CertificateCA{Type: "direct", SecretName: *cert.CAName}
+		}
+		snap.add(apps.CertificateKind, cert.Name, spec)
+	}
+	return nil
+}
+
+func takeVolumeMounts(clt *client.Client, snap *Snapshot) error {
+	volumeMounts, err := clt.ListVolumeMounts()
+	if err != nil {
+		return err
+	}
+	for _, volumeMount := range volumeMounts.VolumeMounts {
+		snap.add(apps.VolumeMountKind, volumeMount.Name, &apps.VolumeMount{
+			SecretName:    volumeMount.SecretName,
+			ConfigMapName: volumeMount.ConfigMapName,
+		})
+	}
+	return nil
+}
+
+func takeServices(clt *client.Client, snap *Snapshot) error {
+	services, err := clt.ListServices()
+	if err != nil {
+		return err
+	}
+	for _, service := range services.Services {
+		snap.add(apps.ServiceKind, service.Name, &apps.Service{
+			Type:          service.Type,
+			Resource:      service.Resource,
+			TargetPort:    service.TargetPort,
+			ServicePort:   service.ServicePort,
+			K8sType:       service.K8sType,
+			DefaultBridge: service.DefaultBridge,
+			Tags:          service.Tags,
+		})
+	}
+	return nil
+}
+
+func takeEdgeResources(clt *client.Client, snap *Snapshot) error {
+	edgeResources, err := clt.ListEdgeResources()
+	if err != nil {
+		if isNotSupported(err) {
+			return nil
+		}
+		return err
+	}
+	for _, edgeResource := range edgeResources.EdgeResources {
+		spec := &apps.EdgeResource{
+			Description:       edgeResource.Description,
+			Version:           edgeResource.Version,
+			InterfaceProtocol: edgeResource.InterfaceProtocol,
+			OrchestrationTags: edgeResource.OrchestrationTags,
+			Custom:            edgeResource.Custom,
+		}
+		if edgeResource.Display != nil {
+			spec.Display = &apps.EdgeResourceDisplay{
+				Name:  edgeResource.Display.Name,
+				Icon:  edgeResource.Display.Icon,
+				Color: edgeResource.Display.Color,
+			}
+		}
+		for _, endpoint := range edgeResource.Interface.Endpoints {
+			spec.Interface.Endpoints = append(spec.Interface.Endpoints, apps.HTTPEndpoint{
+				Name:   endpoint.Name,
+				Method: endpoint.Method,
+				URL:    endpoint.URL,
+			})
+		}
+		snap.add(apps.EdgeResourceKind, edgeResource.Name, spec)
+	}
+	return nil
+}
+
+// takeAgentConfigs records the configuration of the Agents
+// Agents are not provisioned by a restore, their configuration is applied if they exist on the target Controller
+func takeAgentConfigs(clt *client.Client, snap *Snapshot) error {
+	agents, err := clt.ListAgents(client.ListAgentsRequest{})
+	if err != nil {
+		return err
+	}
+	for idx := range agents.Agents {
+		agent := &agents.Agents[idx]
+		data, err := json.Marshal(agent)
+		if err != nil {
+			return err
+		}
+		spec := new(apps.AgentConfig)
+		if err = json.Unmarshal(data, spec); err != nil {
+			return err
+		}
+		spec.IsSystem = nil
+		snap.add(apps.AgentConfigKind, agent.Name, spec)
+	}
+	return nil
+}
+
+func takeApplicationTemplates(clt *client.Client, snap *Snapshot) error {
+	templates, err := clt.ListApplicationTemplates()
+	if err != nil {
+		if isNotSupported(err) {
+			return nil
+		}
+		return err
+	}
+	for idx := range templates.ApplicationTemplates {
+		template, err := clt.GetApplicationTemplate(templates.ApplicationTemplates[idx].Name)
+		if err != nil {
+			return err
+		}
+		// Templates are returned as JSON documents, which are also valid YAML documents
+		data, err := json.Marshal(template)
+		if err != nil {
+			return err
+		}
+		spec := new(apps.ApplicationTemplate)
+		if err = yaml.Unmarshal(data, spec); err != nil {
+			return fmt.Errorf("could not read application template %s: %s", template.Name, err.Error())
+		}
+		snap.add(apps.ApplicationTemplateKind, template.Name, spec)
+	}
+	return nil
+}
+
+// takeApplications records the applications with their microservices and routes
+func takeApplications(clt *client.Client, snap *Snapshot) error {
+	applications, err := clt.GetAllApplications()
+	if err != nil {
+		return err
+	}
+	for idx := range applications.Applications {
+		app, err := apps.ExportApplicationWithClient(clt, applications.Applications[idx].Name)
+		if err != nil {
+			return err
+		}
+		snap.add(apps.ApplicationKind, app.Name, app)
+	}
+	return nil
+}
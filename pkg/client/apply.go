@@ -0,0 +1,400 @@
+/*
+ *  *******************************************************************************
+ *  * Copyright (c) 2024 Datasance Teknoloji A.S.
+ *  *
+ *  * This program and the accompanying materials are made available under the
+ *  * terms of the Eclipse Public License v. 2.0 which is available at
+ *  * http://www.eclipse.org/legal/epl-2.0
+ *  *
+ *  * SPDX-License-Identifier: EPL-2.0
+ *  *******************************************************************************
+ *
+ */
+
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ApplyAction describes the change an Apply call made on the Controller
+type ApplyAction string
+
+const (
+	ApplyCreated   ApplyAction = "Created"
+	ApplyUpdated   ApplyAction = "Updated"
+	ApplyUnchanged ApplyAction = "Unchanged"
+)
+
+func isNotFound(err error) bool {
+	_, ok := err.(*NotFoundError)
+	return ok
+}
+
+// ApplySecret creates the secret if it does not exist, or updates it if its data differs
+func (clt *Client) ApplySecret(request *SecretCreateRequest) (ApplyAction, error) {
+	current, err := clt.GetSecret(request.Name)
+	if err != nil {
+		if !isNotFound(err) {
+			return "", err
+		}
+		if err = clt.CreateSecret(request); err != nil {
+			return "", err
+		}
+		return ApplyCreated, nil
+	}
+	if request.Type != "" && current.Type != request.Type {
+		return "", NewConflictError(fmt.Sprintf("Secret %s already exists with type %s, cannot change it to %s", request.Name, current.Type, request.Type))
+	}
+	if stringMapsEqual(current.Data, request.Data) {
+		return ApplyUnchanged, nil
+	}
+	if err = clt.UpdateSecret(request.Name, &SecretUpdateRequest{Name: request.Name, Data: request.Data}); err != nil {
+		return "", err
+	}
+	return ApplyUpdated, nil
+}
+
+// ApplyConfigMap creates the config map if it does not exist, or updates it if its data differs
+func (clt *Client) ApplyConfigMap(request *ConfigMapCreateRequest) (ApplyAction, error) {
+	current, err := clt.GetConfigMap(request.Name)
+	if err != nil {
+		if !isNotFound(err) {
+			return "", err
+		}
+		if err = clt.CreateConfigMap(request); err != nil {
+			return "", err
+		}
+		return ApplyCreated, nil
+	}
+	if current.Immutable == request.Immutable && stringMapsEqual(current.Data, request.Data) {
+		return ApplyUnchanged, nil
+	}
+	if current.Immutable {
+		return "", NewConflictError(fmt.Sprintf("Config map %s is immutable", request.Name))
+	}
+	updateRequest := &ConfigMapUpdateRequest{
+		Name:      request.Name,
+		Data:      request.Data,
+		Immutable: request.Immutable,
+	}
+	if err = clt.UpdateConfigMap(request.Name, updateRequest); err != nil {
+		return "", err
+	}
+	return ApplyUpdated, nil
+}
+
+// ApplyService creates the service if it does not exist, or updates it if its definition differs
+// Optional fields left empty in the request are not compared
+func (clt *Client) ApplyService(request *ServiceCreateRequest) (ApplyAction, error) {
+	current, err := clt.GetService(request.Name)
+	if err != nil {
+		if !isNotFound(err) {
+			return "", err
+		}
+		if err = clt.CreateService(request); err != nil {
+			return "", err
+		}
+		return ApplyCreated, nil
+	}
+	unchanged := current.Type == request.Type &&
+		current.Resource == request.Resource &&
+		current.TargetPort == request.TargetPort &&
+		(request.ServicePort == 0 || current.ServicePort == request.ServicePort) &&
+		(request.K8sType == "" || current.K8sType == request.K8sType) &&
+		(request.DefaultBridge == "" || current.DefaultBridge == request.DefaultBridge) &&
+		stringSetsEqual(current.Tags, request.Tags)
+	if unchanged {
+		return ApplyUnchanged, nil
+	}
+	updateRequest := &ServiceUpdateRequest{
+		Name:          request.Name,
+		Type:          request.Type,
+		Resource:      request.Resource,
+		TargetPort:    request.TargetPort,
+		ServicePort:   request.ServicePort,
+		K8sType:       request.K8sType,
+		DefaultBridge: request.DefaultBridge,
+		Tags:          request.Tags,
+	}
+	if err = clt.UpdateService(request.Name, updateRequest); err != nil {
+		return "", err
+	}
+	return ApplyUpdated, nil
+}
+
+// ApplyVolumeMount creates the volume mount if it does not exist, or updates it if its source differs
+func (clt *Client) ApplyVolumeMount(request *VolumeMountCreateRequest) (ApplyAction, error) {
+	current, err := clt.GetVolumeMount(request.Name)
+	if err != nil {
+		if !isNotFound(err) {
+			return "", err
+		}
+		if err = clt.CreateVolumeMount(request); err != nil {
+			return "", err
+		}
+		return ApplyCreated, nil
+	}
+	if current.ConfigMapName == request.ConfigMapName && current.SecretName == request.SecretName {
+		return ApplyUnchanged, nil
+	}
+	updateRequest := &VolumeMountUpdateRequest{
+		Name:          request.Name,
+		ConfigMapName: request.ConfigMapName,
+		SecretName:    request.SecretName,
+	}
+	if err = clt.UpdateVolumeMount(request.Name, updateRequest); err != nil {
+		return "", err
+	}
+	return ApplyUpdated, nil
+}
+
+// ApplyCA creates the CA if it does not exist
+// The Controller cannot update a CA, and re-issuing one would invalidate every certificate it signed, so a CA whose
+// subject differs is reported as a conflict rather than replaced
+func (clt *Client) ApplyCA(request *CACreateRequest) (ApplyAction, error) {
+	current, err := clt.GetCA(request.Name)
+	if err != nil {
+		if !isNotFound(err) {
+			return "", err
+		}
+		if err = clt.CreateCA(request); err != nil {
+			return "", err
+		}
+		return ApplyCreated, nil
+	}
+	if request.Subject == "" || current.Subject == request.Subject {
+		return ApplyUnchanged, nil
+	}
+	return "", NewConflictError(fmt.Sprintf("CA %s already exists with subject %s, cannot change it to %s without deleting it", request.Name, current.Subject, request.Subject))
+}
+
+// ApplyCertificate creates the certificate if it does not exist
+// The Controller cannot update a certificate, so a certificate whose subject, hosts or CA differ is reported as a
+// conflict rather than deleted and created again
+func (clt *Client) ApplyCertificate(request *CertificateCreateRequest) (ApplyAction, error) {
+	current, err := clt.GetCertificate(request.Name)
+	if err != nil {
+		if !isNotFound(err) {
+			return "", err
+		}
+		if err = clt.CreateCertificate(request); err != nil {
+			return "", err
+		}
+		return ApplyCreated, nil
+	}
+	var differences []string
+	if current.Subject != request.Subject {
+		differences = append(differences, fmt.Sprintf("subject %s instead of %s", current.Subject, request.Subject))
+	}
+	if current.Hosts != request.Hosts {
+		differences = append(differences, fmt.Sprintf("hosts %s instead of %s", current.Hosts, request.Hosts))
+	}
+	if request.CA.SecretName != "" && (current.CAName == nil || *current.CAName != request.CA.SecretName) {
+		caName := ""
+		if current.CAName != nil {
+			caName = *current.CAName
+		}
+		differences = append(differences, fmt.Sprintf("CA %s instead of %s", caName, request.CA.SecretName))
+	}
+	if len(differences) == 0 {
+		return ApplyUnchanged, nil
+	}
+	return "", NewConflictError(fmt.Sprintf("Certificate %s already exists with %s, cannot change it without deleting it", request.Name, strings.Join(differences, ", ")))
+}
+
+// ApplyRegistry creates the registry if none exists for the same URL and username, or updates it if it differs
+// Registry passwords are not returned by the Controller and are therefore not compared
+func (clt *Client) ApplyRegistry(request *RegistryCreateRequest) (id int, action ApplyAction, err error) {
+	list, err := clt.ListRegistries()
+	if err != nil {
+		return -1, "", err
+	}
+	var current *RegistryInfo
+	for idx := range list.Registries {
+		if list.Registries[idx].URL == request.URL && list.Registries[idx].Username == request.Username {
+			current = &list.Registries[idx]
+			break
+		}
+	}
+	if current == nil {
+		if id, err = clt.CreateRegistry(request); err != nil {
+			return -1, "", err
+		}
+		return id, ApplyCreated, nil
+	}
+	unchanged := current.IsPublic == request.IsPublic &&
+		current.RequiresCert == request.RequiresCert &&
+		current.Certificate == request.Certificate &&
+		current.Email == request.Email
+	if unchanged {
+		return current.ID, ApplyUnchanged, nil
+	}
+	updateRequest := RegistryUpdateRequest{
+		ID:           current.ID,
+		URL:          &request.URL,
+		IsPublic:     &request.IsPublic,
+		Certificate:  &request.Certificate,
+		RequiresCert: &request.RequiresCert,
+		Username:     &request.Username,
+		Email:        &request.Email,
+	}
+	if request.Password != "" {
+		updateRequest.Password = &request.Password
+	}
+	if err = clt.UpdateRegistry(updateRequest); err != nil {
+		return -1, "", err
+	}
+	return current.ID, ApplyUpdated, nil
+}
+
+// ApplyCatalogItem creates the catalog item if none exists with the same name, or updates it if it differs
+func (clt *Client) ApplyCatalogItem(request *CatalogItemCreateRequest) (*CatalogItemInfo, ApplyAction, error) {
+	current, err := clt.GetCatalogItemByName(request.Name)
+	if err != nil {
+		if !isNotFound(err) {
+			return nil, "", err
+		}
+		item, err := clt.CreateCatalogItem(request)
+		if err != nil {
+			return nil, "", err
+		}
+		return item, ApplyCreated, nil
+	}
+	// Set registry to public docker by default, as CreateCatalogItem does
+	registryID := request.RegistryID
+	if registryID == 0 {
+		registryID = 1
+	}
+	unchanged := current.Description == request.Description &&
+		current.RegistryID == registryID &&
+		catalogImagesEqual(current.Images, request.Images)
+	if unchanged {
+		return current, ApplyUnchanged, nil
+	}
+	item, err := clt.UpdateCatalogItem(&CatalogItemUpdateRequest{
+		ID:          current.ID,
+		Name:        request.Name,
+		Description: request.Description,
+		Images:      request.Images,
+		RegistryID:  registryID,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return item, ApplyUpdated, nil
+}
+
+// ApplyRoute creates the route if it does not exist, or patches it if its endpoints differ
+func (clt *Client) ApplyRoute(route *Route) (ApplyAction, error) {
+	current, err := clt.GetRoute(route.Application, route.Name)
+	if err != nil {
+		if !isNotFound(err) {
+			return "", err
+		}
+		if err = clt.CreateRoute(route); err != nil {
+			return "", err
+		}
+		return ApplyCreated, nil
+	}
+	if current.From == route.From && current.To == route.To {
+		return ApplyUnchanged, nil
+	}
+	if err = clt.PatchRoute(route.Application, route.Name, route); err != nil {
+		return "", err
+	}
+	return ApplyUpdated, nil
+}
+
+// ApplyEdgeResource creates the Edge Resource version if it does not exist, or updates it if it differs
+func (clt *Client) ApplyEdgeResource(request *EdgeResourceMetadata) (ApplyAction, error) {
+	current, err := clt.GetHTTPEdgeResourceByName(request.Name, request.Version)
+	if err != nil {
+		if !isNotFound(err) {
+			return "", err
+		}
+		if err = clt.CreateHTTPEdgeResource(request); err != nil {
+			return "", err
+		}
+		return ApplyCreated, nil
+	}
+	unchanged, err := jsonEqual(&current, request)
+	if err != nil {
+		return "", err
+	}
+	if unchanged {
+		return ApplyUnchanged, nil
+	}
+	if err = clt.UpdateHTTPEdgeResource(request.Name, request); err != nil {
+		return "", err
+	}
+	return ApplyUpdated, nil
+}
+
+func stringMapsEqual(lhs, rhs map[string]string) bool {
+	if len(lhs) != len(rhs) {
+		return false
+	}
+	for key, val := range lhs {
+		if other, found := rhs[key]; !found || other != val {
+			return false
+		}
+	}
+	return true
+}
+
+func stringSetsEqual(lhs, rhs []string) bool {
+	if len(lhs) != len(rhs) {
+		return false
+	}
+	lhsSorted := append([]string{}, lhs...)
+	rhsSorted := append([]string{}, rhs...)
+	sort.Strings(lhsSorted)
+	sort.Strings(rhsSorted)
+	for idx := range lhsSorted {
+		if lhsSorted[idx] != rhsSorted[idx] {
+			return false
+		}
+	}
+	return true
+}
+
+func catalogImagesEqual(lhs, rhs []CatalogImage) bool {
+	if len(lhs) != len(rhs) {
+		return false
+	}
+	images := make(map[int]string)
+	for _, image := range lhs {
+		images[image.AgentTypeID] = image.ContainerImage
+	}
+	for _, image := range rhs {
+		if other, found := images[image.AgentTypeID]; !found || other != image.ContainerImage {
+			return false
+		}
+	}
+	return true
+}
+
+// jsonEqual compares two values by their JSON representation, which ignores number types and map ordering
+func jsonEqual(lhs, rhs interface{}) (bool, error) {
+	lhsBytes, err := json.Marshal(lhs)
+	if err != nil {
+		return false, err
+	}
+	rhsBytes, err := json.Marshal(rhs)
+	if err != nil {
+		return false, err
+	}
+	var lhsValue, rhsValue interface{}
+	if err = json.Unmarshal(lhsBytes, &lhsValue); err != nil {
+		return false, err
+	}
+	if err = json.Unmarshal(rhsBytes, &rhsValue); err != nil {
+		return false, err
+	}
+	return reflect.DeepEqual(lhsValue, rhsValue), nil
+}
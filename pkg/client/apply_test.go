@@ -0,0 +1,278 @@
+/*
+ *  *******************************************************************************
+ *  * Copyright (c) 2024 Datasance Teknoloji A.S.
+ *  *
+ *  * This program and the accompanying materials are made available under the
+ *  * terms of the Eclipse Public License v. 2.0 which is available at
+ *  * http://www.eclipse.org/legal/epl-2.0
+ *  *
+ *  * SPDX-License-Identifier: EPL-2.0
+ *  *******************************************************************************
+ *
+ */
+
+package client
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeController answers GET requests with its resources, keyed by path, and records every other request
+// Unknown resources are not found, other requests answer the resource keyed by "<METHOD> <path>" or an empty object
+type fakeController struct {
+	resources map[string]interface{}
+	mutex     sync.Mutex
+	requests  []string
+}
+
+func (ctrl *fakeController) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	path := strings.TrimPrefix(request.URL.Path, "/api/v3")
+	key := path
+	if request.Method != http.MethodGet {
+		key = request.Method + " " + path
+		if request.Method != http.MethodHead {
+			ctrl.mutex.Lock()
+			ctrl.requests = append(ctrl.requests, key)
+			ctrl.mutex.Unlock()
+		}
+	}
+	resource, found := ctrl.resources[key]
+	switch {
+	case found:
+		_ = json.NewEncoder(writer).Encode(resource)
+	case request.Method == http.MethodGet:
+		writer.WriteHeader(http.StatusNotFound)
+	default:
+		_, _ = writer.Write([]byte("{}"))
+	}
+}
+
+func newFakeController(t *testing.T, resources map[string]interface{}) (*Client, *fakeController) {
+	ctrl := &fakeController{resources: resources}
+	server := httptest.NewServer(ctrl)
+	t.Cleanup(server.Close)
+	baseURL, err := url.Parse(server.URL + "/api/v3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clt := New(Options{BaseURL: baseURL})
+	clt.SetAccessToken("token")
+	return clt, ctrl
+}
+
+func TestApply(t *testing.T) {
+	caName := "root"
+	secret := &SecretInfo{Name: "creds", Type: "Opaque", Data: map[string]string{"password": "secret"}}
+	configMap := &ConfigMapInfo{Name: "settings", Data: map[string]string{"level": "debug"}}
+	service := &ServiceInfo{Name: "web", Type: "microservice", Resource: "app/web", TargetPort: 80, Tags: []string{"b", "a"}}
+	volumeMount := &VolumeMountInfo{Name: "data", SecretName: "creds"}
+	ca := &CAInfo{Name: "root", Subject: "root-ca"}
+	certificate := &CertificateInfo{Name: "tls", Subject: "web", Hosts: "web.local", CAName: &caName}
+	registries := RegistryListResponse{Registries: []RegistryInfo{{ID: 3, URL: "registry.local", Username: "user", Email: "user@local"}}}
+	catalogItem := CatalogItemInfo{ID: 7, Name: "sensor", RegistryID: 1, Images: []CatalogImage{{ContainerImage: "sensor:x86", AgentTypeID: 1}}}
+	catalog := &CatalogListResponse{CatalogItems: []CatalogItemInfo{catalogItem}}
+	route := &Route{Name: "route", Application: "app", From: "a", To: "b"}
+	edgeResource := &EdgeResourceMetadata{Name: "sensor", Version: "1.0.0", InterfaceProtocol: "http", OrchestrationTags: []string{"sensor"}}
+
+	applySecret := func(data map[string]string) func(*Client) (ApplyAction, error) {
+		return func(clt *Client) (ApplyAction, error) {
+			return clt.ApplySecret(&SecretCreateRequest{Name: "creds", Type: "Opaque", Data: data})
+		}
+	}
+	applyCA := func(subject string) func(*Client) (ApplyAction, error) {
+		return func(clt *Client) (ApplyAction, error) {
+			return clt.ApplyCA(&CACreateRequest{Name: "root", Subject: subject, Type: "self-signed"})
+		}
+	}
+	applyCertificate := func(hosts string) func(*Client) (ApplyAction, error) {
+		return func(clt *Client) (ApplyAction, error) {
+			return clt.ApplyCertificate(&CertificateCreateRequest{Name: "tls", Subject: "web", Hosts: hosts, CA: CertificateCreateCA{Type: "direct", SecretName: "root"}})
+		}
+	}
+	applyRegistry := func(email string) func(*Client) (ApplyAction, error) {
+		return func(clt *Client) (ApplyAction, error) {
+			_, action, err := clt.ApplyRegistry(&RegistryCreateRequest{URL: "registry.local", Username: "user", Email: email})
+			return action, err
+		}
+	}
+	applyCatalogItem := func(name, image string) func(*Client) (ApplyAction, error) {
+		return func(clt *Client) (ApplyAction, error) {
+			_, action, err := clt.ApplyCatalogItem(&CatalogItemCreateRequest{Name: name, Images: []CatalogImage{{ContainerImage: image, AgentTypeID: 1}}})
+			return action, err
+		}
+	}
+	applyRoute := func(to string) func(*Client) (ApplyAction, error) {
+		return func(clt *Client) (ApplyAction, error) {
+			return clt.ApplyRoute(&Route{Name: "route", Application: "app", From: "a", To: to})
+		}
+	}
+	applyEdgeResource := func(tags ...string) func(*Client) (ApplyAction, error) {
+		return func(clt *Client) (ApplyAction, error) {
+			return clt.ApplyEdgeResource(&EdgeResourceMetadata{Name: "sensor", Version: "1.0.0", InterfaceProtocol: "http", OrchestrationTags: tags})
+		}
+	}
+
+	testCases := []struct {
+		name      string
+		resources map[string]interface{}
+		apply     func(*Client) (ApplyAction, error)
+		action    ApplyAction
+		conflict  bool
+		requests  []string
+	}{
+		{"secret created", nil, applySecret(secret.Data), ApplyCreated, false, []string{"POST /secrets"}},
+		{"secret unchanged", map[string]interface{}{"/secrets/creds": secret}, applySecret(map[string]string{"password": "secret"}), ApplyUnchanged, false, nil},
+		{"secret updated", map[string]interface{}{"/secrets/creds": secret}, applySecret(map[string]string{"password": "rotated"}), ApplyUpdated, false, []string{"PATCH /secrets/creds"}},
+
+		{"config map created", nil, func(clt *Client) (ApplyAction, error) {
+			return clt.ApplyConfigMap(&ConfigMapCreateRequest{Name: "settings", Data: configMap.Data})
+		}, ApplyCreated, false, []string{"POST /configmaps"}},
+		{"config map unchanged", map[string]interface{}{"/configmaps/settings": configMap}, func(clt *Client) (ApplyAction, error) {
+			return clt.ApplyConfigMap(&ConfigMapCreateRequest{Name: "settings", Data: map[string]string{"level": "debug"}})
+		}, ApplyUnchanged, false, nil},
+		{"config map updated", map[string]interface{}{"/configmaps/settings": configMap}, func(clt *Client) (ApplyAction, error) {
+			return clt.ApplyConfigMap(&ConfigMapCreateRequest{Name: "settings", Data: map[string]string{"level": "info"}})
+		}, ApplyUpdated, false, []string{"PATCH /configmaps/settings"}},
+
+		{"service created", nil, func(clt *Client) (ApplyAction, error) {
+			return clt.ApplyService(&ServiceCreateRequest{Name: "web", Type: "microservice", Resource: "app/web", TargetPort: 80})
+		}, ApplyCreated, false, []string{"POST /services"}},
+		{"service unchanged", map[string]interface{}{"/services/web": service}, func(clt *Client) (ApplyAction, error) {
+			return clt.ApplyService(&ServiceCreateRequest{Name: "web", Type: "microservice", Resource: "app/web", TargetPort: 80, Tags: []string{"a", "b"}})
+		}, ApplyUnchanged, false, nil},
+		{"service updated", map[string]interface{}{"/services/web": service}, func(clt *Client) (ApplyAction, error) {
+			return clt.ApplyService(&ServiceCreateRequest{Name: "web", Type: "microservice", Resource: "app/web", TargetPort: 8080, Tags: []string{"a", "b"}})
+		}, ApplyUpdated, false, []string{"PATCH /services/web"}},
+
+		{"volume mount created", nil, func(clt *Client) (ApplyAction, error) {
+			return clt.ApplyVolumeMount(&VolumeMountCreateRequest{Name: "data", SecretName: "creds"})
+		}, ApplyCreated, false, []string{"POST /volumeMounts"}},
+		{"volume mount unchanged", map[string]interface{}{"/volumeMounts/data": volumeMount}, func(clt *Client) (ApplyAction, error) {
+			return clt.ApplyVolumeMount(&VolumeMountCreateRequest{Name: "data", SecretName: "creds"})
+		}, ApplyUnchanged, false, nil},
+		{"volume mount updated", map[string]interface{}{"/volumeMounts/data": volumeMount}, func(clt *Client) (ApplyAction, error) {
+			return clt.ApplyVolumeMount(&VolumeMountCreateRequest{Name: "data", ConfigMapName: "settings"})
+		}, ApplyUpdated, false, []string{"PATCH /volumeMounts/data"}},
+
+		{"CA created", nil, applyCA("root-ca"), ApplyCreated, false, []string{"POST /certificates/ca"}},
+		{"CA unchanged", map[string]interface{}{"/certificates/ca/root": ca}, applyCA("root-ca"), ApplyUnchanged, false, nil},
+		{"CA subject changed", map[string]interface{}{"/certificates/ca/root": ca}, applyCA("other-ca"), "", true, nil},
+
+		{"certificate created", nil, applyCertificate("web.local"), ApplyCreated, false, []string{"POST /certificates"}},
+		{"certificate unchanged", map[string]interface{}{"/certificates/tls": certificate}, applyCertificate("web.local"), ApplyUnchanged, false, nil},
+		{"certificate hosts changed", map[string]interface{}{"/certificates/tls": certificate}, applyCertificate("web.other"), "", true, nil},
+
+		{"registry created", map[string]interface{}{"/registries": RegistryListResponse{}, "POST /registries": RegistryCreateResponse{ID: 4}}, applyRegistry("user@local"), ApplyCreated, false, []string{"POST /registries"}},
+		{"registry unchanged", map[string]interface{}{"/registries": registries}, applyRegistry("user@local"), ApplyUnchanged, false, nil},
+		{"registry updated", map[string]interface{}{"/registries": registries}, applyRegistry("user@other"), ApplyUpdated, false, []string{"PATCH /registries/3"}},
+
+		{"catalog item created", map[string]interface{}{"/catalog/microservices": &CatalogListResponse{}, "POST /catalog/microservices": CatalogItemCreateResponse{ID: 7}, "/catalog/microservices/7": catalogItem}, applyCatalogItem("sensor", "sensor:x86"), ApplyCreated, false, []string{"POST /catalog/microservices"}},
+		{"catalog item unchanged", map[string]interface{}{"/catalog/microservices": catalog}, applyCatalogItem("sensor", "sensor:x86"), ApplyUnchanged, false, nil},
+		{"catalog item updated", map[string]interface{}{"/catalog/microservices": catalog, "/catalog/microservices/7": catalogItem}, applyCatalogItem("sensor", "sensor:v2"), ApplyUpdated, false, []string{"PATCH /catalog/microservices/7"}},
+
+		{"route created", nil, applyRoute("b"), ApplyCreated, false, []string{"POST /routes"}},
+		{"route unchanged", map[string]interface{}{"/routes/app/route": route}, applyRoute("b"), ApplyUnchanged, false, nil},
+		{"route patched", map[string]interface{}{"/routes/app/route": route}, applyRoute("c"), ApplyUpdated, false, []string{"PATCH /routes/app/route"}},
+
+		{"edge resource created", nil, applyEdgeResource("sensor"), ApplyCreated, false, []string{"POST /edgeResource"}},
+		{"edge resource unchanged", map[string]interface{}{"/edgeResource/sensor/1.0.0": edgeResource}, applyEdgeResource("sensor"), ApplyUnchanged, false, nil},
+		{"edge resource updated", map[string]interface{}{"/edgeResource/sensor/1.0.0": edgeResource}, applyEdgeResource("sensor", "camera"), ApplyUpdated, false, []string{"PUT /edgeResource/sensor/1.0.0"}},
+	}
+	for _, testCase := range testCases {
+		clt, ctrl := newFakeController(t, testCase.resources)
+		action, err := testCase.apply(clt)
+		if testCase.conflict {
+			if _, ok := err.(*ConflictError); !ok {
+				t.Errorf("%s: expected a conflict error, got %v", testCase.name, err)
+			}
+		} else if err != nil {
+			t.Errorf("%s: %s", testCase.name, err.Error())
+			continue
+		}
+		if action != testCase.action {
+			t.Errorf("%s: expected %q, got %q", testCase.name, testCase.action, action)
+		}
+		if !reflect.DeepEqual(ctrl.requests, testCase.requests) {
+			t.Errorf("%s: expected requests %v, got %v", testCase.name, testCase.requests, ctrl.requests)
+		}
+	}
+}
+
+func TestApplyHelpers(t *testing.T) {
+	mapCases := []struct {
+		lhs, rhs map[string]string
+		equal    bool
+	}{
+		{nil, map[string]string{}, true},
+		{map[string]string{"a": "1"}, map[string]string{"a": "1"}, true},
+		{map[string]string{"a": "1"}, map[string]string{"a": "2"}, false},
+		{map[string]string{"a": "1"}, map[string]string{"b": "1"}, false},
+		{map[string]string{"a": "1"}, map[string]string{"a": "1", "b": "2"}, false},
+	}
+	for _, testCase := range mapCases {
+		if stringMapsEqual(testCase.lhs, testCase.rhs) != testCase.equal {
+			t.Errorf("stringMapsEqual(%v, %v) should be %v", testCase.lhs, testCase.rhs, testCase.equal)
+		}
+	}
+
+	setCases := []struct {
+		lhs, rhs []string
+		equal    bool
+	}{
+		{nil, []string{}, true},
+		{[]string{"a", "b"}, []string{"b", "a"}, true},
+		{[]string{"a", "a"}, []string{"a", "b"}, false},
+		{[]string{"a"}, []string{"a", "b"}, false},
+	}
+	for _, testCase := range setCases {
+		lhs := append([]string{}, testCase.lhs...)
+		if stringSetsEqual(testCase.lhs, testCase.rhs) != testCase.equal {
+			t.Errorf("stringSetsEqual(%v, %v) should be %v", testCase.lhs, testCase.rhs, testCase.equal)
+		}
+		if !reflect.DeepEqual(lhs, append([]string{}, testCase.lhs...)) {
+			t.Errorf("stringSetsEqual sorted its argument %v", testCase.lhs)
+		}
+	}
+
+	x86 := CatalogImage{ContainerImage: "sensor:x86", AgentTypeID: 1}
+	arm := CatalogImage{ContainerImage: "sensor:arm", AgentTypeID: 2}
+	imageCases := []struct {
+		lhs, rhs []CatalogImage
+		equal    bool
+	}{
+		{[]CatalogImage{x86, arm}, []CatalogImage{arm, x86}, true},
+		{[]CatalogImage{x86}, []CatalogImage{{ContainerImage: "sensor:v2", AgentTypeID: 1}}, false},
+		{[]CatalogImage{x86}, []CatalogImage{{ContainerImage: "sensor:x86", AgentTypeID: 2}}, false},
+		{[]CatalogImage{x86}, []CatalogImage{x86, arm}, false},
+	}
+	for _, testCase := range imageCases {
+		if catalogImagesEqual(testCase.lhs, testCase.rhs) != testCase.equal {
+			t.Errorf("catalogImagesEqual(%v, %v) should be %v", testCase.lhs, testCase.rhs, testCase.equal)
+		}
+	}
+
+	jsonCases := []struct {
+		lhs, rhs interface{}
+		equal    bool
+	}{
+		{map[string]interface{}{"port": 80}, map[string]interface{}{"port": 80.0}, true},
+		{&Route{Name: "route", From: "a"}, map[string]string{"name": "route", "application": "", "from": "a", "to": ""}, true},
+		{&Route{Name: "route", From: "a"}, &Route{Name: "route", From: "b"}, false},
+	}
+	for _, testCase := range jsonCases {
+		equal, err := jsonEqual(testCase.lhs, testCase.rhs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if equal != testCase.equal {
+			t.Errorf("jsonEqual(%v, %v) should be %v", testCase.lhs, testCase.rhs, testCase.equal)
+		}
+	}
+}
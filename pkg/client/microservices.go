@@ -307,12 +307,26 @@ func (clt *Client) UpdateSystemMicroserviceFromYAML(uuid string, file io.Reader)
 	return clt.GetSystemMicroserviceByID(uuid)
 }
 
+// UpdateMicroservice patches the fields set in the request on a microservice using Controller REST API
+func (clt *Client) UpdateMicroservice(uuid string, request *MicroserviceUpdateRequest) (*MicroserviceInfo, error) {
+	if _, err := clt.doRequest("PATCH", fmt.Sprintf("/microservices/%s", uuid), request); err != nil {
+		return nil, err
+	}
+	return clt.GetMicroserviceByID(uuid)
+}
+
 // DeleteMicroservice deletes a microservice using Controller REST API
 func (clt *Client) DeleteMicroservice(uuid string) (err error) {
 	_, err = clt.doRequest("DELETE", fmt.Sprintf("/microservices/%s", uuid), nil)
 	return
 }
 
+// DeleteMicroserviceWithCleanup deletes a microservice and makes its Agent remove its data using Controller REST API
+func (clt *Client) DeleteMicroserviceWithCleanup(uuid string) (err error) {
+	_, err = clt.doRequest("DELETE", fmt.Sprintf("/microservices/%s", uuid), MicroserviceDeleteRequest{WithCleanup: true})
+	return
+}
+
 // RebuildsMicroservice rebuilds a microservice using Controller REST API
 func (clt *Client) RebuildsMicroservice(uuid string) (err error) {
 	_, err = clt.doRequest("PATCH", fmt.Sprintf("/microservices/%s/rebuild", uuid), nil)
@@ -0,0 +1,114 @@
+/*
+ *  *******************************************************************************
+ *  * Copyright (c) 2024 Datasance Teknoloji A.S.
+ *  *
+ *  * This program and the accompanying materials are made available under the
+ *  * terms of the Eclipse Public License v. 2.0 which is available at
+ *  * http://www.eclipse.org/legal/epl-2.0
+ *  *
+ *  * SPDX-License-Identifier: EPL-2.0
+ *  *******************************************************************************
+ *
+ */
+
+package client
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Precondition is the server state an update expects, set from the resource the caller last read
+// The update fails with a *ConflictError when the resource changed since
+// The Controller has no conditional update, the state is checked right before the update is sent
+type Precondition struct {
+	// UpdatedAt is the updatedAt value of the resource, the Controller must report it
+	UpdatedAt string
+	// Hash is the content hash of the resource, see ApplicationHash and MicroserviceHash
+	Hash string
+}
+
+// check returns a *ConflictError when the current state of the resource does not match the precondition
+func (precondition Precondition) check(kind, name, updatedAt, hash string) error {
+	if precondition.UpdatedAt != "" {
+		if updatedAt == "" {
+			return NewNotSupportedError(fmt.Sprintf("updatedAt precondition on %s %s, use a content hash instead", kind, name))
+		}
+		if updatedAt != precondition.UpdatedAt {
+			return NewConflictError(fmt.Sprintf("%s %s was updated at %s, expected %s", kind, name, updatedAt, precondition.UpdatedAt))
+		}
+	}
+	if precondition.Hash != "" && hash != precondition.Hash {
+		return NewConflictError(fmt.Sprintf("%s %s has changed, its content hash is %s, expected %s", kind, name, hash, precondition.Hash))
+	}
+	return nil
+}
+
+// ApplicationHash returns the content hash of an application, ignoring the status of its microservices
+func ApplicationHash(application *ApplicationInfo) (string, error) {
+	app := *application
+	app.UpdatedAt = ""
+	app.Microservices = make([]MicroserviceInfo, len(application.Microservices))
+	for idx := range application.Microservices {
+		app.Microservices[idx] = microserviceSpec(&application.Microservices[idx])
+	}
+	return contentHash(app)
+}
+
+// MicroserviceHash returns the content hash of a microservice, ignoring its status
+func MicroserviceHash(microservice *MicroserviceInfo) (string, error) {
+	return contentHash(microserviceSpec(microservice))
+}
+
+// microserviceSpec returns a copy of the microservice without the fields changed by the Agents
+func microserviceSpec(microservice *MicroserviceInfo) MicroserviceInfo {
+	msvc := *microservice
+	msvc.Status = MicroserviceStatusInfo{}
+	msvc.ExecStatus = MicroserviceExecStatusInfo{}
+	msvc.UpdatedAt = ""
+	return msvc
+}
+
+func contentHash(in interface{}) (string, error) {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// UpdateApplicationFromYAMLWithPrecondition updates an application if it still matches the precondition
+func (clt *Client) UpdateApplicationFromYAMLWithPrecondition(name string, file io.Reader, precondition Precondition) (*ApplicationInfo, error) {
+	current, err := clt.GetApplicationByName(name)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := ApplicationHash(current)
+	if err != nil {
+		return nil, err
+	}
+	if err := precondition.check("Application", name, current.UpdatedAt, hash); err != nil {
+		return nil, err
+	}
+	return clt.UpdateApplicationFromYAML(name, file)
+}
+
+// UpdateMicroserviceFromYAMLWithPrecondition updates a microservice if it still matches the precondition
+func (clt *Client) UpdateMicroserviceFromYAMLWithPrecondition(uuid string, file io.Reader, precondition Precondition) (*MicroserviceInfo, error) {
+	current, err := clt.GetMicroserviceByID(uuid)
+	if err != nil {
+		return nil, err
+	}
+	hash, err := MicroserviceHash(current)
+	if err != nil {
+		return nil, err
+	}
+	if err := precondition.check("Microservice", uuid, current.UpdatedAt, hash); err != nil {
+		return nil, err
+	}
+	return clt.UpdateMicroserviceFromYAML(uuid, file)
+}
@@ -57,6 +57,7 @@ type ApplicationInfo struct {
 	ID            int                `json:"id"`
 	Microservices []MicroserviceInfo `json:"microservices"`
 	Routes        []Route            `json:"routes"`
+	UpdatedAt     string             `json:"updatedAt,omitempty"`
 }
 
 type ApplicationCreateResponse struct {
@@ -233,6 +234,10 @@ type MicroserviceExecStatusInfo struct {
 	ExecSessionID string `json:"execSessionId"`
 }
 
+type MicroserviceDeleteRequest struct {
+	WithCleanup bool `json:"withCleanup"`
+}
+
 type MicroserviceInfo struct {
 	UUID              string                          `json:"uuid"`
 	Config            string                          `json:"config"`
@@ -271,6 +276,7 @@ type MicroserviceInfo struct {
 	CpuSetCpus        string                          `json:"cpuSetCpus,omitempty"`
 	MemoryLimit       int64                           `json:"memoryLimit,omitempty"`
 	HealthCheck       MicroserviceHealthCheck         `json:"healthCheck,omitempty"`
+	UpdatedAt         string                          `json:"updatedAt,omitempty"`
 }
 
 type MicroserviceHealthCheck struct {
@@ -288,6 +294,15 @@ type MicroserviceExtraHost struct {
 	Value   string `json:"value,omitempty"`
 }
 
+// MicroserviceUpdateRequest is a partial update of a microservice, nil lists are left unchanged
+type MicroserviceUpdateRequest struct {
+	Env        *[]MicroserviceEnvironmentInfo   `json:"env,omitempty"`
+	Volumes    *[]MicroserviceVolumeMappingInfo `json:"volumeMappings,omitempty"`
+	ExtraHosts *[]MicroserviceExtraHost         `json:"extraHosts,omitempty"`
+	// Rebuild recreates the container even if the update does not require it
+	Rebuild bool `json:"rebuild,omitempty"`
+}
+
 type MicroserviceCreateResponse struct {
 	UUID string `json:"uuid"`
 }
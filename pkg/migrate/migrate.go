@@ -0,0 +1,204 @@
+/*
+ *  *******************************************************************************
+ *  * Copyright (c) 2024 Datasance Teknoloji A.S.
+ *  *
+ *  * This program and the accompanying materials are made available under the
+ *  * terms of the Eclipse Public License v. 2.0 which is available at
+ *  * http://www.eclipse.org/legal/epl-2.0
+ *  *
+ *  * SPDX-License-Identifier: EPL-2.0
+ *  *******************************************************************************
+ *
+ */
+
+package migrate
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/datasance/iofog-go-sdk/v3/pkg/apps"
+	"github.com/datasance/iofog-go-sdk/v3/pkg/backup"
+	"github.com/datasance/iofog-go-sdk/v3/pkg/client"
+)
+
+// Options controls a migration
+type Options struct {
+	// AgentNames maps the Agent names of the source Controller to the Agent names of the target Controller
+	// Agents that are not mapped keep their name
+	AgentNames map[string]string
+	// Conflict decides what happens to resources that already exist on the target Controller, defaults to backup.ConflictFail
+	Conflict backup.ConflictPolicy
+	// DryRun only computes what would be migrated
+	DryRun bool
+}
+
+// Issue is a resource, or part of a resource, that cannot be migrated
+// Blocking issues exclude the resource from the migration
+type Issue struct {
+	Kind     apps.Kind
+	Name     string
+	Reason   string
+	Blocking bool
+}
+
+// String returns a one line description of the issue
+func (issue Issue) String() string {
+	if issue.Blocking {
+		return fmt.Sprintf("%s/%s: not migrated: %s", issue.Kind, issue.Name, issue.Reason)
+	}
+	return fmt.Sprintf("%s/%s: %s", issue.Kind, issue.Name, issue.Reason)
+}
+
+// Report contains the outcome of a migration
+type Report struct {
+	// Documents are the resources written, or to be written on a dry run, to the target Controller
+	Documents []apps.Header
+	Issues    []Issue
+	// Applied is nil on a dry run
+	Applied *apps.ApplyReport
+}
+
+// String returns the issues followed by the outcome of every migrated resource
+func (report *Report) String() string {
+	var builder strings.Builder
+	for _, issue := range report.Issues {
+		fmt.Fprintln(&builder, issue.String())
+	}
+	if report.Applied == nil {
+		for idx := range report.Documents {
+			fmt.Fprintf(&builder, "%s/%s: Pending\n", report.Documents[idx].Kind, report.Documents[idx].Metadata.Name)
+		}
+		return builder.String()
+	}
+	builder.WriteString(report.Applied.String())
+	return builder.String()
+}
+
+// Migrate copies the configuration of the source Controller to the target Controller
+// Agent assignments are rewritten through the agent name mapping, registry and catalog item IDs are translated on restore
+func Migrate(source, target *client.Client, opt Options) (*Report, error) {
+	snap, err := backup.Take(source)
+	if err != nil {
+		return nil, err
+	}
+	targetAgents, err := agentNames(target)
+	if err != nil {
+		return nil, err
+	}
+
+	exe := &migrateExecutor{
+		agentNames:   opt.AgentNames,
+		targetAgents: targetAgents,
+		report:       new(Report),
+	}
+	snap.Documents = exe.rewrite(snap.Documents)
+	exe.report.Documents = snap.Documents
+	if opt.DryRun {
+		return exe.report, nil
+	}
+
+	exe.report.Applied, err = backup.Restore(target, snap, backup.RestoreOptions{Conflict: opt.Conflict})
+	return exe.report, err
+}
+
+func agentNames(clt *client.Client) (map[string]bool, error) {
+	agents, err := clt.ListAgents(client.ListAgentsRequest{})
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]bool)
+	for _, agent := range agents.Agents {
+		names[agent.Name] = true
+	}
+	return names, nil
+}
+
+type migrateExecutor struct {
+	agentNames   map[string]string
+	targetAgents map[string]bool
+	report       *Report
+}
+
+func (exe *migrateExecutor) issue(header *apps.Header, blocking bool, format string, args ...interface{}) {
+	exe.report.Issues = append(exe.report.Issues, Issue{
+		Kind:     header.Kind,
+		Name:     header.Metadata.Name,
+		Reason:   fmt.Sprintf(format, args...),
+		Blocking: blocking,
+	})
+}
+
+// mapAgent returns the name of the Agent on the target Controller
+func (exe *migrateExecutor) mapAgent(name string) string {
+	if mapped, found := exe.agentNames[name]; found {
+		return mapped
+	}
+	return name
+}
+
+// rewrite returns the documents to migrate, with their Agent names mapped to the target Controller
+func (exe *migrateExecutor) rewrite(documents []apps.Header) (migrated []apps.Header) {
+	for idx := range documents {
+		header := documents[idx]
+		switch spec := header.Spec.(type) {
+		case *apps.Registry:
+			if spec.Username != "" {
+				exe.issue(&header, false, "password cannot be read from the source Controller and must be set again")
+			}
+		case *apps.AgentConfig:
+			name := exe.mapAgent(header.Metadata.Name)
+			if !exe.targetAgents[name] {
+				exe.issue(&header, true, "Agent %s does not exist on the target Controller", name)
+				continue
+			}
+			header.Metadata.Name = name
+		case *apps.Application:
+			app := spec.DeepCopy()
+			if !exe.rewriteMicroservices(&header, app.Microservices) {
+				continue
+			}
+			header.Spec = app
+		case *apps.ApplicationTemplate:
+			template := spec.DeepCopy()
+			if template.Application != nil {
+				exe.rewriteTemplateMicroservices(template.Application.Microservices)
+			}
+			header.Spec = template
+		}
+		migrated = append(migrated, header)
+	}
+	return migrated
+}
+
+// rewriteMicroservices maps the Agents of the microservices and reports state that stays on the source Agents
+// It returns false if the application cannot be deployed on the target Controller
+func (exe *migrateExecutor) rewriteMicroservices(header *apps.Header, msvcs []apps.Microservice) bool {
+	deployable := true
+	for idx := range msvcs {
+		msvc := &msvcs[idx]
+		msvc.Agent.Name = exe.mapAgent(msvc.Agent.Name)
+		if !exe.targetAgents[msvc.Agent.Name] {
+			exe.issue(header, true, "microservice %s is scheduled on Agent %s which does not exist on the target Controller", msvc.Name, msvc.Agent.Name)
+			deployable = false
+		}
+		if msvc.Container.Volumes == nil {
+			continue
+		}
+		for _, volume := range *msvc.Container.Volumes {
+			// Volume mounts are backed by secrets and config maps, which are migrated
+			if volume.Type == "volumeMount" {
+				continue
+			}
+			exe.issue(header, false, "content of %s on the Agent of microservice %s is not migrated", volume.HostDestination, msvc.Name)
+		}
+	}
+	return deployable
+}
+
+// rewriteTemplateMicroservices maps the Agents of template microservices, templates are not deployed so missing Agents are not an issue
+func (exe *migrateExecutor) rewriteTemplateMicroservices(msvcs []apps.Microservice) {
+	for idx := range msvcs {
+		msvcs[idx].Agent.Name = exe.mapAgent(msvcs[idx].Agent.Name)
+	}
+}
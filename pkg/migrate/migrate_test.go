@@ -0,0 +1,75 @@
+/*
+ *  *******************************************************************************
+ *  * Copyright (c) 2024 Datasance Teknoloji A.S.
+ *  *
+ *  * This program and the accompanying materials are made available under the
+ *  * terms of the Eclipse Public License v. 2.0 which is available at
+ *  * http://www.eclipse.org/legal/epl-2.0
+ *  *
+ *  * SPDX-License-Identifier: EPL-2.0
+ *  *******************************************************************************
+ *
+ */
+
+package migrate
+
+import (
+	"testing"
+
+	"github.com/datasance/iofog-go-sdk/v3/pkg/apps"
+)
+
+func document(kind apps.Kind, name string, spec interface{}) apps.Header {
+	return apps.Header{APIVersion: apps.APIVersion, Kind: kind, Metadata: apps.HeaderMetadata{Name: name}, Spec: spec}
+}
+
+func TestRewrite(t *testing.T) {
+	volumes := []apps.MicroserviceVolumeMapping{{HostDestination: "/data", ContainerDestination: "/data", Type: "bind"}}
+	documents := []apps.Header{
+		document(apps.AgentConfigKind, "staging-1", &apps.AgentConfig{}),
+		document(apps.AgentConfigKind, "staging-2", &apps.AgentConfig{}),
+		document(apps.ApplicationKind, "web", &apps.Application{
+			Name: "web",
+			Microservices: []apps.Microservice{{
+				Name:      "server",
+				Agent:     apps.MicroserviceAgent{Name: "staging-1"},
+				Container: apps.MicroserviceContainer{Volumes: &volumes},
+			}},
+		}),
+		document(apps.ApplicationKind, "batch", &apps.Application{
+			Name:          "batch",
+			Microservices: []apps.Microservice{{Name: "job", Agent: apps.MicroserviceAgent{Name: "staging-2"}}},
+		}),
+	}
+	exe := &migrateExecutor{
+		agentNames:   map[string]string{"staging-1": "prod-1"},
+		targetAgents: map[string]bool{"prod-1": true},
+		report:       new(Report),
+	}
+	migrated := exe.rewrite(documents)
+
+	if len(migrated) != 2 {
+		t.Fatalf("Expected 2 documents to be migrated, got %d", len(migrated))
+	}
+	if migrated[0].Metadata.Name != "prod-1" {
+		t.Errorf("Expected agent config to be renamed, got %s", migrated[0].Metadata.Name)
+	}
+	app := migrated[1].Spec.(*apps.Application)
+	if app.Name != "web" || app.Microservices[0].Agent.Name != "prod-1" {
+		t.Errorf("Unexpected migrated application: %+v", app)
+	}
+	if documents[2].Spec.(*apps.Application).Microservices[0].Agent.Name != "staging-1" {
+		t.Error("Rewrite modified the source documents")
+	}
+
+	blocking := 0
+	for _, issue := range exe.report.Issues {
+		if issue.Blocking {
+			blocking++
+		}
+	}
+	// staging-2 agent config and batch application are blocked, the bind volume of web is reported
+	if blocking != 2 || len(exe.report.Issues) != 3 {
+		t.Errorf("Unexpected issues: %v", exe.report.Issues)
+	}
+}